@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/export"
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/store"
+)
+
+// statsSource loads the statistics served by the JSON API, from statistics files saved with --save-stats
+// or from a SQLite database written with --sqlite, and loads them again when the files change.
+type statsSource struct {
+	// fileNames are the statistics files, or the SQLite database.
+	fileNames []string
+	// sqlite is set if fileNames holds a SQLite database.
+	sqlite bool
+
+	mu sync.Mutex
+	// stats are the statistics last loaded, or nil before the first request.
+	stats *logstats.LogStats
+	// modTime is the latest modification time of the files when stats were loaded.
+	modTime time.Time
+}
+
+// get returns the statistics, loading them if they were not loaded yet or the files changed since.
+func (src *statsSource) get() (*logstats.LogStats, error) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	var latest time.Time
+	for _, name := range src.fileNames {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	if src.stats != nil && !latest.After(src.modTime) {
+		return src.stats, nil
+	}
+
+	stats, err := src.load()
+	if err != nil {
+		return nil, err
+	}
+	src.stats, src.modTime = stats, latest
+	return stats, nil
+}
+
+// load loads the statistics from the files.
+func (src *statsSource) load() (*logstats.LogStats, error) {
+	if !src.sqlite {
+		return loadStats(src.fileNames)
+	}
+	db, err := store.OpenSQLite(src.fileNames[0])
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.Load()
+}
+
+// handleAPI adds the endpoints of the JSON API to mux:
+//
+//	GET /api/summary                  the grand totals
+//	GET /api/monthly                  the totals per month, oldest first
+//	GET /api/daily                    the totals per day, oldest first
+//	GET /api/top/{list}?limit=N       the top-N list, such as urls, referrers, or browsers, most counted first
+//	GET /api/countries?limit=N        the countries, with their ISO 3166 codes, most counted first
+//
+// The periods and entries have the schema of the JSON export. Without a limit, the lists are complete.
+func handleAPI(mux *http.ServeMux, src *statsSource) {
+	mux.HandleFunc("GET /api/summary", apiHandler(src, func(stats *logstats.LogStats, r *http.Request) (any, error) {
+		return export.NewSummary(stats), nil
+	}))
+	mux.HandleFunc("GET /api/monthly", apiHandler(src, func(stats *logstats.LogStats, r *http.Request) (any, error) {
+		return export.Monthly(stats), nil
+	}))
+	mux.HandleFunc("GET /api/daily", apiHandler(src, func(stats *logstats.LogStats, r *http.Request) (any, error) {
+		return export.Daily(stats), nil
+	}))
+	mux.HandleFunc("GET /api/top/{list}", apiHandler(src, func(stats *logstats.LogStats, r *http.Request) (any, error) {
+		return topList(stats, r.PathValue("list"), r)
+	}))
+	mux.HandleFunc("GET /api/countries", apiHandler(src, func(stats *logstats.LogStats, r *http.Request) (any, error) {
+		return topList(stats, "countries", r)
+	}))
+}
+
+// apiError is an error of the JSON API, with the HTTP status it is served with.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+// topList returns the top-N list name, cut to the limit query parameter of r.
+func topList(stats *logstats.LogStats, name string, r *http.Request) ([]export.Entry, error) {
+	limit := 0
+	if text := r.URL.Query().Get("limit"); text != "" {
+		var err error
+		if limit, err = strconv.Atoi(text); err != nil || limit < 0 {
+			return nil, &apiError{http.StatusBadRequest, fmt.Errorf("invalid limit %q, expected a number of 0 or more", text)}
+		}
+	}
+	list, ok := export.TopList(stats, name, limit, export.Options{})
+	if !ok {
+		return nil, &apiError{http.StatusNotFound, fmt.Errorf("unknown list %q", name)}
+	}
+	return list, nil
+}
+
+// apiHandler returns the handler of an endpoint of the JSON API, serving the result of fn on the
+// statistics as JSON, or its error as {"error": "..."}.
+func apiHandler(src *statsSource, fn func(stats *logstats.LogStats, r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var result any
+		stats, err := src.get()
+		if err == nil {
+			result, err = fn(stats, r)
+		}
+		status := http.StatusOK
+		if err != nil {
+			status = http.StatusInternalServerError
+			if apiErr, ok := err.(*apiError); ok {
+				status = apiErr.status
+			}
+			result = map[string]string{"error": err.Error()}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(status)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+	}
+}
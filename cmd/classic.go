@@ -0,0 +1,141 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rbscholtus/go-webalizer/internal/charts"
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/report"
+)
+
+// Report layouts.
+const (
+	// layoutSingle is a single page holding all charts and tables.
+	layoutSingle = "single"
+	// layoutClassic is the multi-page layout of classic Webalizer.
+	layoutClassic = "classic"
+)
+
+// writeReport writes the report on stats to index.html in dir, in the layout selected by the options.
+// dir is created if needed.
+func writeReport(stats *logstats.LogStats, opt options, dir string, title string, open bool) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if opt.layout == layoutClassic {
+		return writeClassic(stats, opt, dir, title, open)
+	}
+
+	page := newPage(title, opt)
+	addReport(page, stats, opt)
+	if err := writeListings(page, stats, opt, dir, "", "index.html"); err != nil {
+		return err
+	}
+	if err := writeFeed(page, stats, opt, dir, title); err != nil {
+		return err
+	}
+	return writePage(page, filepath.Join(dir, "index.html"), opt, open)
+}
+
+// writeClassic writes the report on stats in the classic Webalizer layout: an index.html with the summary
+// of the last 12 months, linking to a usage_YYYYMM.html page with the details of each parsed month.
+func writeClassic(stats *logstats.LogStats, opt options, dir string, title string, open bool) error {
+	months := stats.AggregatesByMonth()
+	keys := slices.Sorted(maps.Keys(months))
+	if len(keys) > 12 {
+		keys = keys[len(keys)-12:]
+	}
+	year := make(map[string]*logstats.HFPBVSData, len(keys))
+	for _, key := range keys {
+		year[key] = months[key]
+	}
+
+	links := make(map[string]string)
+	for _, month := range stats.Months() {
+		if _, ok := year[month]; !ok {
+			continue
+		}
+		suffix := "_" + strings.ReplaceAll(month, "-", "")
+		fileName := "usage" + suffix + ".html"
+		page := newPage(title+" for "+year[month].Category+" "+month[:4], opt)
+		page.AddLinks(report.Link{Text: "Back to Summary", URL: "index.html"})
+		monthStats := stats.MonthStats(month)
+		addMonthReport(page, monthStats, month, opt)
+		if err := writeListings(page, monthStats, opt, dir, suffix, fileName); err != nil {
+			return err
+		}
+		if err := writePage(page, filepath.Join(dir, fileName), opt, false); err != nil {
+			return err
+		}
+		links[month] = fileName
+	}
+
+	page := newPage(title, opt)
+	hfpBar, bBar, vsBar := charts.MonthlyBarCharts(year)
+	charts.LinkMonthlyBars(year, links, hfpBar, bBar, vsBar)
+	page.AddCharts(opt.usageCharts(hfpBar, bBar, vsBar)...)
+	page.AddTables(report.MonthlySummaryTable(year, links))
+	if err := writeFeed(page, stats, opt, dir, title); err != nil {
+		return err
+	}
+	return writePage(page, filepath.Join(dir, "index.html"), opt, open)
+}
+
+// addMonthReport adds the daily and hourly statistics and the top-N tables of a month, in the format "YYYY-MM",
+// to page, where stats only holds the days of the month.
+func addMonthReport(page *report.Page, stats *logstats.LogStats, month string, opt options) {
+	// Aggregates
+	days := stats.RecentAggregates()
+	hours := stats.HourlyAggregates()
+	hourlyAverages := stats.HourlyAverages()
+	urls := newURLAggregates(stats, opt)
+	sites := opt.groups.Sites.Apply(opt.hide.Sites.Hide(stats.SiteAggregates()))
+	referrerDomains := opt.groups.Referrers.Apply(opt.hide.Referrers.Hide(stats.ReferrerDomainAggregates()))
+	browsers, browserVersions := stats.BrowserAggregates()
+	oses, _ := stats.OSDeviceAggregates()
+	methods, responses := stats.MethRespAggregates()
+	userAgents := opt.hide.Agents.Hide(stats.UserAgentAggregates())
+
+	// Render charts and tables
+	if opt.excludeRobots {
+		page.AddNotes(robotsNote)
+	} else {
+		page.AddNotes(humansNote)
+	}
+	if opt.location != nil {
+		page.AddNotes(timeZoneNote(opt.location))
+	}
+	summary := stats.Summary()
+	page.AddCards(report.SummaryCards(summary)...)
+	page.AddTables(report.SummaryTable(summary, stats.TotalHumansRobots(opt.excludeRobots)))
+	page.AddCharts(opt.usageCharts(charts.DailyBarCharts(month, stats.MonthDayAggregates(month)))...)
+	page.AddTables(report.DailyTable(days, stats.RecentVisitMetrics(), stats.RecentNotModified(), stats.DailyHumansRobots(opt.excludeRobots)))
+	page.AddCharts(charts.HourlyBarChart(hours, hourlyAverages))
+	page.AddTables(report.HourlyTable(hours, hourlyAverages))
+	page.AddCharts(charts.TopURLsBarChart(urls.bytes, topURLsChart))
+	referrersTable := addReferrersChart(page, referrerDomains, opt, "_"+strings.ReplaceAll(month, "-", ""), "Top Referrers")
+	page.AddTables(
+		report.TopTable("Top URLs", "URL", "Hits", urls.hits, opt.top.URLs),
+		report.URLBytesTable(urls.bytes, 10),
+		report.TopTable("Top Entry Pages", "URL", "Visits", urls.entries, opt.top.EntryPages),
+		report.TopTable("Top Exit Pages", "URL", "Visits", urls.exits, opt.top.ExitPages),
+		report.TopTable("Top Sites", "Site", "Hits", sites, opt.top.Sites),
+		referrersTable,
+		report.TopTable("Top Search Strings", "Search String", "Hits", stats.SearchStringAggregates(), opt.top.SearchStrings),
+		report.TopTable("Top Browser Versions", "Browser", "Visits", browserVersions, opt.top.Agents),
+		report.TopTable("Top User Agents", "User Agent", "Visits", userAgents, opt.top.Agents),
+		report.TopTable("Top Countries", "Country", "Visits", stats.CountryAggregates(), opt.top.Countries),
+		report.HumansRobotsTable(stats.HumansRobotsAggregates(opt.excludeRobots)),
+		report.TopTable("Top Robots", "Robot", "Hits", stats.RobotAggregates(), opt.top.Agents),
+	)
+	page.AddCharts(
+		charts.MethodPieChart(methods),
+		charts.ResponsesPieChart(responses),
+		charts.BrowserPieChart(browsers),
+		charts.OSPieChart(oses),
+	)
+}
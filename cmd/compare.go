@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/report"
+)
+
+// compareCommand returns the command that writes a report of the differences between two saved
+// statistics files, or two date ranges of one.
+func compareCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "compare",
+		Usage:     "Report the URLs that gained or lost the most traffic, new referrers, and new 404s between two --save-stats files, or two date ranges of one",
+		ArgsUsage: "BEFORE [AFTER]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "before",
+				Usage: "compare the days from FIRST to LAST of the first file, as the `FIRST..LAST` dates in the format YYYY-MM-DD",
+			},
+			&cli.StringFlag{
+				Name:  "after",
+				Usage: "compare the days from FIRST to LAST of the last file, as the `FIRST..LAST` dates in the format YYYY-MM-DD",
+			},
+			&cli.IntFlag{
+				Name:  "top",
+				Value: 30,
+				Usage: "show `N` rows in each table",
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Value: "compare.html",
+				Usage: "write the report to `FILE`",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "overwrite an existing file that is not a generated report",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			if len(args) < 1 || len(args) > 2 {
+				return fmt.Errorf("expected one or two statistics files")
+			}
+			if len(args) == 1 && (cmd.String("before") == "" || cmd.String("after") == "") {
+				return fmt.Errorf("comparing a single statistics file requires --before and --after")
+			}
+
+			before, beforeName, err := loadPeriod(args[0], cmd.String("before"))
+			if err != nil {
+				return err
+			}
+			after, afterName, err := loadPeriod(args[len(args)-1], cmd.String("after"))
+			if err != nil {
+				return err
+			}
+
+			diff := logstats.Compare(before, after, beforeName, afterName)
+			n := cmd.Int("top")
+			page := report.NewPage(fmt.Sprintf("Comparison of %s and %s", beforeName, afterName))
+			page.AddTables(
+				report.ComparisonTable("Totals", &diff.Totals),
+				report.ChangeTable("URLs Gaining the Most Hits", "URL", diff.URLs, n, true),
+				report.ChangeTable("URLs Losing the Most Hits", "URL", diff.URLs, n, false),
+				report.TopTable("New Referrers", "Site", "Hits", diff.NewReferrers, n),
+			)
+			if notFound := report.NotFoundTable(diff.NewNotFound, n); len(notFound.Rows) > 0 {
+				notFound.Title = "New Broken Links (404 Not Found)"
+				page.AddTables(notFound)
+			}
+			return writePage(page, cmd.String("output"), options{force: cmd.Bool("force")}, false)
+		},
+	}
+}
+
+// loadPeriod loads a statistics file saved with --save-stats, restricted to the days of the period
+// "FIRST..LAST" if not empty, and returns it with the name of the file or period.
+func loadPeriod(fileName string, period string) (*logstats.LogStats, string, error) {
+	stats, err := logstats.LoadFile(fileName)
+	if err != nil {
+		return nil, "", err
+	}
+	if period == "" {
+		return stats, fileName, nil
+	}
+
+	first, last, ok := strings.Cut(period, "..")
+	for _, date := range []string{first, last} {
+		if _, err := time.Parse(time.DateOnly, date); !ok || err != nil {
+			return nil, "", fmt.Errorf("invalid period %q, expected FIRST..LAST in the format YYYY-MM-DD", period)
+		}
+	}
+	return stats.RangeStats(first, last), first + " - " + last, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/schedule"
+)
+
+// runScheduled calls run now and then at each time of the schedule, until ctx is canceled. The runs never
+// overlap: times that pass while a run is busy are skipped. A failed run is logged, and the next run is
+// still scheduled.
+func runScheduled(ctx context.Context, sched schedule.Schedule, run func() error) error {
+	for {
+		if err := run(); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			slog.Error("scheduled run failed", "error", err)
+		}
+
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("the schedule never runs again")
+		}
+		slog.Info("waiting for the next run", "at", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+	}
+}
+
+// lockFile creates the lock file name holding the process ID, so that runs writing the same report or
+// database, such as a scheduled run and a run started by cron, never overlap. A lock file left by a process
+// that is gone, such as one that was killed, is stale and taken over. It returns the function that removes
+// the lock file again, or an error naming the process holding the lock.
+func lockFile(name string) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		content, _ := os.ReadFile(name)
+		holder := string(bytes.TrimSpace(content))
+		if pid, perr := strconv.Atoi(holder); perr == nil && pid != os.Getpid() && !processAlive(pid) {
+			slog.Warn("removing a stale lock file", "path", name, "pid", pid)
+			if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		}
+	}
+	if errors.Is(err, fs.ErrExist) {
+		content, _ := os.ReadFile(name)
+		return nil, fmt.Errorf("another run is busy: %s is held by process %s, remove it if that process is gone",
+			name, cmp.Or(string(bytes.TrimSpace(content)), "unknown"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+	return func() { os.Remove(name) }, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLockFile(t *testing.T) {
+	// A test binary that runs no tests exits right away, leaving the ID of a process that is gone
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		holder  string
+		wantErr bool
+	}{
+		{"no lock", "", false},
+		{"running process", strconv.Itoa(os.Getppid()), true},
+		{"stale lock", strconv.Itoa(exited.Process.Pid), false},
+		{"unknown holder", "busy", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "webalizer.lock")
+			if tt.holder != "" {
+				if err := os.WriteFile(name, []byte(tt.holder+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			unlock, err := lockFile(name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lockFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := lockFile(name); err == nil {
+				t.Error("lockFile() of a held lock succeeded, want an error")
+			}
+			unlock()
+			if _, err := os.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("the lock file is left after unlock: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/export"
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/report"
+)
+
+// feedFile is the name of the Atom feed written alongside the report.
+const feedFile = "feed.xml"
+
+// writeFeed writes the Atom feed of the daily summaries of stats to dir, linking to its index.html,
+// and announces it in the head of page, if enabled by the options.
+func writeFeed(page *report.Page, stats *logstats.LogStats, opt options, dir string, title string) error {
+	if !opt.feed {
+		return nil
+	}
+
+	f, err := os.Create(filepath.Join(dir, feedFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := export.WriteAtom(f, stats, title, "index.html", time.Now()); err != nil {
+		return err
+	}
+
+	page.AddFeeds(report.Link{Text: title, URL: feedFile})
+	opt.summary.addOutput(f.Name())
+	return f.Close()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/rbscholtus/go-webalizer/internal/charts"
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/report"
+)
+
+// listingPageSize is the number of rows on each page of a full listing.
+const listingPageSize = 500
+
+// referrersID is the HTML id of the table of top referring sites, which the referrers chart links to.
+const referrersID = "referrers"
+
+// topReferrersChart is the number of referring sites in the referrers chart.
+const topReferrersChart = 15
+
+// addReferrersChart adds the chart of the top referring sites in referrerDomains to page. Its bars link
+// to the listing of all referrers of the page with the suffix if it is written, or else to the table
+// of top referring sites returned, to be added to the page by the caller.
+func addReferrersChart(page *report.Page, referrerDomains map[string]uint64, opt options, suffix string, title string) *report.Table {
+	table := report.TopTable(title, "Site", "Hits", referrerDomains, opt.top.Referrers)
+	link := ""
+	switch {
+	case opt.all.Referrers:
+		link = "all_referrers" + suffix + ".html"
+	case table != nil:
+		table.ID = referrersID
+		link = "#" + referrersID
+	}
+	if len(referrerDomains) > 0 {
+		page.AddCharts(charts.TopReferrersBarChart(referrerDomains, topReferrersChart, link))
+	}
+	return table
+}
+
+// writeListings writes the full listings of the URL paths, sites, and referrers of stats selected by
+// the options to dir, and links them from page. A listing is split into pages named all_urls<suffix>.html,
+// all_urls<suffix>_2.html, and so on, which link back to the report page in the file named back.
+func writeListings(page *report.Page, stats *logstats.LogStats, opt options, dir string, suffix string, back string) error {
+	listings := []struct {
+		enabled   bool
+		name      string
+		title     string
+		keyHeader string
+		aggr      func() map[string]*logstats.HitsBytes
+	}{
+		{opt.all.URLs, "all_urls", "All URLs", "URL", func() map[string]*logstats.HitsBytes {
+			aggr := stats.URLAggregates()
+			for urlPath := range aggr {
+				if opt.hide.URLs.Match(urlPath) {
+					delete(aggr, urlPath)
+				}
+			}
+			return aggr
+		}},
+		{opt.all.Sites, "all_sites", "All Sites", "Site", func() map[string]*logstats.HitsBytes {
+			aggr := make(map[string]*logstats.HitsBytes)
+			for ip, hbv := range stats.IPAggregates() {
+				if !opt.hide.Sites.Match(ip) {
+					aggr[ip] = &logstats.HitsBytes{Hits: hbv.Hits, Bytes: hbv.Bytes}
+				}
+			}
+			return aggr
+		}},
+		{opt.all.Referrers, "all_referrers", "All Referrers", "Referrer", func() map[string]*logstats.HitsBytes {
+			aggr := stats.ReferrerAggregates()
+			for referrer := range aggr {
+				if opt.hide.Referrers.Match(referrer) {
+					delete(aggr, referrer)
+				}
+			}
+			return aggr
+		}},
+	}
+
+	for _, listing := range listings {
+		if !listing.enabled {
+			continue
+		}
+		tables := report.ListingTables(listing.title, listing.keyHeader, listing.aggr(), listingPageSize)
+		fileNames := make([]string, len(tables))
+		nav := []report.Link{{Text: "Back to Report", URL: back}}
+		for i := range tables {
+			fileNames[i] = listing.name + suffix + ".html"
+			if i > 0 {
+				fileNames[i] = fmt.Sprintf("%s%s_%d.html", listing.name, suffix, i+1)
+			}
+			if len(tables) > 1 {
+				nav = append(nav, report.Link{Text: fmt.Sprintf("Page %d", i+1), URL: fileNames[i]})
+			}
+		}
+		for i, table := range tables {
+			listingPage := newPage(page.Title+": "+listing.title, opt)
+			listingPage.AddLinks(nav...)
+			listingPage.AddTables(table)
+			if err := writePage(listingPage, filepath.Join(dir, fileNames[i]), opt, false); err != nil {
+				return err
+			}
+		}
+		page.AddLinks(report.Link{Text: listing.title, URL: fileNames[0]})
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Log formats of the messages written to stderr.
+const (
+	// logText writes messages as key=value pairs.
+	logText = "text"
+	// logJSON writes messages as JSON objects, one per line.
+	logJSON = "json"
+)
+
+// loggingFlags are the flags that select the verbosity and format of the messages.
+var loggingFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:    "quiet",
+		Aliases: []string{"q"},
+		Usage:   "only log errors, silencing the warnings about invalid log lines",
+	},
+	&cli.BoolFlag{
+		Name:    "verbose",
+		Aliases: []string{"v"},
+		Usage:   "also log debug messages, such as the files written",
+	},
+	&cli.StringFlag{
+		Name:  "log-format",
+		Value: logText,
+		Usage: "log messages to stderr as `FORMAT`: text or json",
+	},
+}
+
+// setupLogging sets the default logger to write to stderr at the verbosity and in the format selected by the flags.
+func setupLogging(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	switch {
+	case cmd.Bool("quiet") && cmd.Bool("verbose"):
+		return ctx, fmt.Errorf("--quiet and --verbose cannot be combined")
+	case cmd.Bool("quiet"):
+		opts.Level = slog.LevelError
+	case cmd.Bool("verbose"):
+		opts.Level = slog.LevelDebug
+	}
+
+	var handler slog.Handler
+	switch format := cmd.String("log-format"); format {
+	case logText:
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case logJSON:
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return ctx, fmt.Errorf("unsupported log format %q", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return ctx, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/rbscholtus/go-webalizer/internal/group"
+)
+
+func TestAddExcludeFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		ip        string
+		urlPath   string
+		userAgent string
+		want      bool
+	}{
+		{"URL regexp", []string{"--exclude-url", "^/healthz"}, "192.0.2.1", "/healthz", "curl", true},
+		{"URL regexp anchored", []string{"--exclude-url", "^/healthz"}, "192.0.2.1", "/api/healthz", "curl", false},
+		{"IP subnet", []string{"--exclude-ip", "10.0.0.0/8"}, "10.1.2.3", "/", "curl", true},
+		{"IP outside subnet", []string{"--exclude-ip", "10.0.0.0/8"}, "11.1.2.3", "/", "curl", false},
+		{"single IP", []string{"--exclude-ip", "192.0.2.1"}, "192.0.2.1", "/", "curl", true},
+		{"single IP only", []string{"--exclude-ip", "192.0.2.1"}, "192.0.2.10", "/", "curl", false},
+		{"agent regexp", []string{"--exclude-agent", "Uptime(Robot|Kuma)"}, "192.0.2.1", "/", "UptimeKuma/1.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filters group.Filters
+			cmd := analyzeCommand()
+			cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+				return addExcludeFlags(&filters, cmd)
+			}
+			if err := cmd.Run(context.Background(), append([]string{"analyze"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if got := filters.Match(tt.ip, tt.urlPath, "-", tt.userAgent); got != tt.want {
+				t.Errorf("Match(%q, %q, %q) = %v, want %v", tt.ip, tt.urlPath, tt.userAgent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddExcludeFlagsInvalid(t *testing.T) {
+	tests := [][]string{
+		{"--exclude-ip", "example.com"},
+		{"--exclude-url", "("},
+	}
+	for _, args := range tests {
+		t.Run(args[0], func(t *testing.T) {
+			cmd := analyzeCommand()
+			cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+				return addExcludeFlags(&group.Filters{}, cmd)
+			}
+			if err := cmd.Run(context.Background(), append([]string{"analyze"}, args...)); err == nil {
+				t.Errorf("addExcludeFlags(%q) succeeded, want an error", args)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/browser"
+	"github.com/rbscholtus/go-webalizer/internal/buildinfo"
+	"github.com/urfave/cli/v3"
+)
+
+// serveCommand returns the command that serves the reports in a directory over HTTP.
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve the reports written by analyze, and optionally their statistics as JSON, over HTTP, so they can be viewed without a separate web server",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Value: "localhost:8080",
+				Usage: "listen on the TCP network address `ADDR`",
+			},
+			&cli.StringFlag{
+				Name:  "dir",
+				Value: ".",
+				Usage: "serve the reports in `DIR`, the --output-dir of analyze",
+			},
+			&cli.StringFlag{
+				Name:    "basic-auth",
+				Usage:   "require HTTP basic authentication with `USER:PASSWORD`",
+				Sources: cli.EnvVars("WEBALIZER_BASIC_AUTH"),
+			},
+			&cli.BoolFlag{
+				Name:  "no-refresh",
+				Usage: "do not reload the reports in the browser when they are written again",
+			},
+			&cli.BoolFlag{
+				Name:  "open",
+				Usage: "open the reports in the default browser once the server listens",
+			},
+			&cli.StringSliceFlag{
+				Name:  "stats",
+				Usage: "serve the statistics `FILE`s saved with --save-stats, combined, on the JSON API under /api/",
+			},
+			&cli.StringFlag{
+				Name:  "sqlite",
+				Usage: "serve the statistics in the SQLite database `FILE` written with --sqlite on the JSON API under /api/",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			s := &reportServer{
+				dir:     cmd.String("dir"),
+				refresh: !cmd.Bool("no-refresh"),
+				changed: make(chan struct{}),
+			}
+			if auth := cmd.String("basic-auth"); auth != "" {
+				var ok bool
+				if s.user, s.password, ok = strings.Cut(auth, ":"); !ok || s.user == "" {
+					return fmt.Errorf("invalid basic authentication %q, expected USER:PASSWORD", auth)
+				}
+			}
+			switch statsFiles, sqlitePath := cmd.StringSlice("stats"), cmd.String("sqlite"); {
+			case len(statsFiles) > 0 && sqlitePath != "":
+				return fmt.Errorf("please serve either --stats or --sqlite on the API, not both")
+			case len(statsFiles) > 0:
+				s.api = &statsSource{fileNames: statsFiles}
+			case sqlitePath != "":
+				s.api = &statsSource{fileNames: []string{sqlitePath}, sqlite: true}
+			}
+			if s.refresh {
+				go s.watch(ctx, 2*time.Second)
+			}
+
+			server := &http.Server{
+				Addr:              cmd.String("addr"),
+				Handler:           s.handler(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				server.Shutdown(shutdownCtx)
+			}()
+
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			url := listenURL(ln.Addr())
+			slog.Info("serving reports", "dir", s.dir, "url", url)
+			if cmd.Bool("open") {
+				if err := browser.OpenURL(url); err != nil {
+					slog.Warn("could not open reports in browser", "error", err)
+				}
+			}
+			if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// listenURL returns the URL of the reports served on addr. An unspecified address, such as that of ":8080",
+// is served on the loopback address too, so the URL uses localhost.
+func listenURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String() + "/"
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/"
+}
+
+// reportsPath is the URL path of the index of the reports, and of the paths serve adds itself. Report
+// directories never start with an underscore followed by a letter, so it does not hide a report.
+const reportsPath = "/_reports/"
+
+// reportServer serves the reports in a directory, with an index of the sites and months, optional basic
+// authentication, a script that reloads a report when it is written again, and optionally a JSON API.
+type reportServer struct {
+	// dir is the directory holding the reports.
+	dir string
+	// user and password are the credentials of the basic authentication, if user is set.
+	user, password string
+	// refresh is set if the reports reload when they are written again.
+	refresh bool
+	// api holds the statistics of the JSON API, if it is served.
+	api *statsSource
+
+	mu sync.Mutex
+	// changed is closed, and replaced, when a report is written.
+	changed chan struct{}
+}
+
+// handler returns the HTTP handler of the reports.
+func (s *reportServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	files := http.FileServer(http.Dir(s.dir))
+	mux.HandleFunc(reportsPath, s.serveIndex)
+	mux.HandleFunc(reportsPath+"events", s.serveEvents)
+	mux.Handle("/version", buildinfo.Handler())
+	if s.api != nil {
+		handleAPI(mux, s.api)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" && !fileExists(filepath.Join(s.dir, "index.html")) {
+			http.Redirect(w, r, reportsPath, http.StatusFound)
+			return
+		}
+		if s.refresh && s.servePage(w, r) {
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+	if s.user == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(s.user)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="webalizer", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// refreshScript reloads the page when the server sends an event that a report was written.
+const refreshScript = `<script>new EventSource("` + reportsPath + `events").onmessage = () => location.reload();</script>`
+
+// servePage serves the HTML page of the request with refreshScript added, and reports whether it did. It
+// leaves other files, and missing pages, to the file server.
+func (s *reportServer) servePage(w http.ResponseWriter, r *http.Request) bool {
+	name := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	} else if path.Ext(name) != ".html" || path.Base(name) == "index.html" {
+		// The file server redirects index.html to its directory
+		return false
+	}
+	f, err := http.Dir(s.dir).Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	page, err := io.ReadAll(f)
+	if err != nil {
+		return false
+	}
+
+	if i := bytes.LastIndex(page, []byte("</body>")); i >= 0 {
+		page = slices.Concat(page[:i], []byte(refreshScript), page[i:])
+	} else {
+		page = append(page, refreshScript...)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(page)
+	return true
+}
+
+// serveEvents sends a server-sent event each time a report is written, until the client goes away.
+func (s *reportServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if !s.refresh {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	http.NewResponseController(w).Flush()
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+			fmt.Fprint(w, "data: reload\n\n")
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// watch checks the reports for changes every interval until ctx is canceled, and signals the clients of
+// serveEvents when one was written.
+func (s *reportServer) watch(ctx context.Context, interval time.Duration) {
+	last := latestModTime(s.dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if latest := latestModTime(s.dir); latest.After(last) {
+			last = latest
+			slog.Debug("reports changed", "time", latest)
+			s.mu.Lock()
+			close(s.changed)
+			s.changed = make(chan struct{})
+			s.mu.Unlock()
+		}
+	}
+}
+
+// latestModTime returns the latest modification time of the HTML pages in dir and its subdirectories.
+func latestModTime(dir string) time.Time {
+	var latest time.Time
+	filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(name) != ".html" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest
+}
+
+// reportSite is a site in the index of the reports: a directory holding an index.html page.
+type reportSite struct {
+	Name   string
+	URL    string
+	Months []reportMonth
+}
+
+// reportMonth is a month with a page of a site in the index of the reports.
+type reportMonth struct {
+	Name string
+	URL  string
+}
+
+// indexTemplate is the index of the reports.
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Reports</title>
+<style>body{font-family:system-ui,sans-serif;margin:2em}li{margin:.25em 0}footer{margin-top:2em;color:#666}</style>
+</head>
+<body>
+<h1>Reports</h1>
+{{range .Sites}}<h2><a href="{{.URL}}">{{.Name}}</a></h2>
+{{with .Months}}<ul>
+{{range .}}<li><a href="{{.URL}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{end}}{{else}}<p>There are no reports yet.</p>
+{{end}}<footer><a href="/version">Version</a>{{if .API}} · <a href="/api/summary">JSON API</a>{{end}}</footer>
+</body>
+</html>
+`))
+
+// serveIndex serves the index of the reports, listing each site with the pages of its months, and linking
+// the build information and the JSON API, if it is served.
+func (s *reportServer) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != reportsPath {
+		http.NotFound(w, r)
+		return
+	}
+	var page bytes.Buffer
+	data := struct {
+		Sites []reportSite
+		API   bool
+	}{reportSites(s.dir), s.api != nil}
+	if err := indexTemplate.Execute(&page, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s.refresh {
+		page.Truncate(page.Len() - len("</body>\n</html>\n"))
+		page.WriteString(refreshScript + "</body>\n</html>\n")
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(page.Bytes())
+}
+
+// reportSites returns the sites with reports in dir, such as the directories of the virtual hosts, with
+// the months of the classic layout, the latest first.
+func reportSites(dir string) []reportSite {
+	var sites []reportSite
+	filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if !fileExists(filepath.Join(name, "index.html")) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return nil
+		}
+		site := reportSite{Name: filepath.ToSlash(rel), URL: "/"}
+		if rel == "." {
+			site.Name = "Main report"
+		} else {
+			site.URL = "/" + filepath.ToSlash(rel) + "/"
+		}
+		pages, _ := filepath.Glob(filepath.Join(name, "usage_*.html"))
+		slices.Sort(pages)
+		for _, page := range slices.Backward(pages) {
+			base := filepath.Base(page)
+			month, err := time.Parse("200601", strings.TrimSuffix(strings.TrimPrefix(base, "usage_"), ".html"))
+			if err != nil {
+				continue
+			}
+			site.Months = append(site.Months, reportMonth{Name: month.Format("January 2006"), URL: site.URL + base})
+		}
+		sites = append(sites, site)
+		return nil
+	})
+	return sites
+}
+
+// fileExists reports whether name is an existing regular file.
+func fileExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
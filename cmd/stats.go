@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/rbscholtus/go-webalizer/internal/config"
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// loadStats loads the statistics files saved with --save-stats and merges them.
+func loadStats(fileNames []string) (*logstats.LogStats, error) {
+	if len(fileNames) == 0 {
+		return nil, fmt.Errorf("please provide the statistics files")
+	}
+	stats, err := logstats.LoadFile(fileNames[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := mergeStats(stats, options{mergeFiles: fileNames[1:]}); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// mergeCommand returns the command that combines saved statistics files into one.
+func mergeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "merge",
+		Usage:     "Combine statistics files saved with --save-stats, such as of several servers or months, into one",
+		ArgsUsage: "FILE...",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Required: true,
+				Usage:    "save the combined statistics to `FILE` (gob if it ends in .gob, JSON otherwise)",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			stats, err := loadStats(cmd.Args().Slice())
+			if err != nil {
+				return err
+			}
+			return stats.SaveFile(cmd.String("output"))
+		},
+	}
+}
+
+// exportCommand returns the command that exports saved statistics files in a machine-readable format,
+// or into a SQL database.
+func exportCommand() *cli.Command {
+	formats := slices.DeleteFunc(slices.Clone(outputFormats), func(format string) bool { return format == outputHTML })
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "Export statistics files saved with --save-stats, combined, as JSON, XML, CSV, or text, or into a SQL database",
+		ArgsUsage: "FILE...",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "output-format",
+				Value: outputJSON,
+				Usage: "export as `FORMAT`: " + strings.Join(formats, ", "),
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "write the export to `FILE` instead of stdout",
+			},
+			&cli.StringFlag{
+				Name:  "sql",
+				Usage: "upsert the daily totals and top-N lists into the PostgreSQL or MySQL database at `URL` instead, keyed by --site-name",
+			},
+			&cli.StringFlag{
+				Name:  "site-name",
+				Usage: "key the rows in the SQL database by `NAME`",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			format := cmd.String("output-format")
+			if !slices.Contains(formats, format) {
+				return fmt.Errorf("unsupported output format %q", format)
+			}
+			stats, err := loadStats(cmd.Args().Slice())
+			if err != nil {
+				return err
+			}
+			if url := cmd.String("sql"); url != "" {
+				return writeSQL(stats, url, cmd.String("site-name"))
+			}
+
+			var w io.Writer = os.Stdout
+			if path := cmd.String("output"); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			return writeExport(stats, options{outputFormat: format, top: config.DefaultTopN}, w)
+		},
+	}
+}
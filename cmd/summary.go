@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/parser"
+)
+
+// Statuses of a run in the run summary.
+const (
+	// statusOK is a run that parsed every line.
+	statusOK = "ok"
+	// statusParseErrors is a run that completed, but could not parse some lines.
+	statusParseErrors = "ok_with_parse_errors"
+	// statusFailed is a run that failed.
+	statusFailed = "failed"
+)
+
+// exitParseErrors is the exit code of a run that completed, but could not parse some lines.
+// Failed runs exit with 1.
+const exitParseErrors = 3
+
+// runSummary is the machine-readable summary of a run, for cron wrappers and other scripts.
+type runSummary struct {
+	// Status is statusOK, statusParseErrors, or statusFailed.
+	Status string `json:"status"`
+	// Error is the error of a failed run.
+	Error string `json:"error,omitempty"`
+	// File is the log file, or the first of the log files.
+	File string `json:"file"`
+	// Files are the log files, if there are several.
+	Files []string `json:"files,omitempty"`
+	// Counts are the numbers of lines by how they were processed.
+	parser.Counts
+	// ParseSeconds is the time spent parsing the log file.
+	ParseSeconds float64 `json:"parse_seconds"`
+	// FirstDate is the first day in the statistics, in the format "YYYY-MM-DD".
+	FirstDate string `json:"first_date,omitempty"`
+	// LastDate is the last day in the statistics, in the format "YYYY-MM-DD".
+	LastDate string `json:"last_date,omitempty"`
+	// LookupFailures is the number of visitors whose country lookup failed, counted as unresolved.
+	LookupFailures int `json:"lookup_failures"`
+	// Outputs are the files written.
+	Outputs []string `json:"outputs"`
+}
+
+// addLookupFailures records failed country lookups. It does nothing on a nil summary.
+func (s *runSummary) addLookupFailures(n int) {
+	if s != nil {
+		s.LookupFailures += n
+	}
+}
+
+// addOutput records a written file. It does nothing on a nil summary.
+func (s *runSummary) addOutput(fileName string) {
+	if s != nil {
+		s.Outputs = append(s.Outputs, fileName)
+	}
+}
+
+// setDates records the date range of stats.
+func (s *runSummary) setDates(stats *logstats.LogStats) {
+	summary := stats.Summary()
+	s.FirstDate, s.LastDate = summary.First, summary.Last
+}
+
+// finish sets the status of the run from its error, if any, and the parse errors.
+func (s *runSummary) finish(err error) {
+	switch {
+	case err != nil:
+		s.Status, s.Error = statusFailed, err.Error()
+	case s.Invalid > 0:
+		s.Status = statusParseErrors
+	default:
+		s.Status = statusOK
+	}
+}
+
+// write writes the summary as JSON to fileName, or to stderr if fileName is "-".
+func (s *runSummary) write(fileName string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if fileName == "-" {
+		_, err = os.Stderr.Write(data)
+		return err
+	}
+	return os.WriteFile(fileName, data, 0o644)
+}
@@ -0,0 +1,67 @@
+// Package anonymize replaces the IP addresses of visitors before they are counted, so the statistics and reports
+// hold no personal data, such as required by the GDPR.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Modes of anonymization.
+const (
+	// Truncate zeroes the host part of addresses, keeping the /24 network of IPv4 and the /48 network of IPv6
+	// addresses, and drops the first label of hostnames. The networks can still be placed in a country.
+	Truncate = "truncate"
+	// Hash replaces addresses and hostnames by a keyed hash with a random salt that is only kept for the run,
+	// so visitors are told apart within a run but not across runs. Hashed visitors cannot be placed in a country.
+	Hash = "hash"
+)
+
+// Modes are the supported modes of anonymization.
+var Modes = []string{Truncate, Hash}
+
+// New returns the function that anonymizes an IP address or hostname in the mode.
+func New(mode string) (func(string) string, error) {
+	switch mode {
+	case Truncate:
+		return truncate, nil
+	case Hash:
+		salt := make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		return func(visitor string) string {
+			return hash(salt, visitor)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported anonymization mode %q", mode)
+	}
+}
+
+// truncate returns the /24 network of an IPv4 address or the /48 network of an IPv6 address, or the hostname
+// without its first label.
+func truncate(visitor string) string {
+	ip := net.ParseIP(visitor)
+	if ip == nil {
+		if _, domain, ok := strings.Cut(visitor, "."); ok {
+			return domain
+		}
+		return visitor
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// hash returns the first 16 hex digits of the HMAC-SHA256 of visitor keyed with salt.
+func hash(salt []byte, visitor string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(visitor))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
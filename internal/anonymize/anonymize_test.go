@@ -0,0 +1,58 @@
+package anonymize
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		visitor string
+		want    string
+	}{
+		{"192.0.2.123", "192.0.2.0"},
+		{"::ffff:192.0.2.123", "192.0.2.0"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234::"},
+		{"host.example.com", "example.com"},
+		{"localhost", "localhost"},
+	}
+	anonymize, err := New(Truncate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.visitor, func(t *testing.T) {
+			if got := anonymize(tt.visitor); got != tt.want {
+				t.Errorf("truncate(%q) = %q, want %q", tt.visitor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	anonymize, err := New(Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := anonymize("192.0.2.1"), anonymize("192.0.2.2")
+	if len(a) != 16 {
+		t.Errorf("hash %q has %d digits, want 16", a, len(a))
+	}
+	if a != anonymize("192.0.2.1") {
+		t.Errorf("hash of the same visitor differs within a run")
+	}
+	if a == b {
+		t.Errorf("hashes of different visitors are equal")
+	}
+	if a == other("192.0.2.1") {
+		t.Errorf("hashes of the same visitor are equal across runs")
+	}
+}
+
+func TestNewUnsupported(t *testing.T) {
+	if _, err := New("scramble"); err == nil {
+		t.Error("New(\"scramble\") succeeded, want an error")
+	}
+}
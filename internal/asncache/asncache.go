@@ -0,0 +1,61 @@
+// Package asncache provides a cached autonomous system lookup service using the MaxMind GeoLite2-ASN database.
+package asncache
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// ASNLookup represents an autonomous system lookup service.
+type ASNLookup struct {
+	// db is the underlying GeoLite2-ASN database reader.
+	db *geoip2.Reader
+	// systems is a map of autonomous systems, where the key is the IP address and the value is the
+	// autonomous system, or an empty string if the IP address is not in the database.
+	systems map[string]string
+}
+
+// NewASNLookup returns a new ASNLookup instance.
+// dbPath is the path to the GeoLite2-ASN database file.
+func NewASNLookup(dbPath string) (*ASNLookup, error) {
+	db, err := geoip2.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ASNLookup{
+		db:      db,
+		systems: make(map[string]string),
+	}, nil
+}
+
+// Close closes the underlying GeoLite2-ASN database.
+func (al *ASNLookup) Close() error {
+	return al.db.Close()
+}
+
+// Lookup returns the autonomous system of an IP address in the form "AS15169 Google LLC",
+// and false if the IP address is invalid or not in the database.
+func (al *ASNLookup) Lookup(ip string) (string, bool) {
+	system, ok := al.systems[ip]
+	if !ok {
+		system = al.lookupASN(ip)
+		al.systems[ip] = system
+	}
+	return system, system != ""
+}
+
+// lookupASN looks up the autonomous system of an IP address in the database.
+func (al *ASNLookup) lookupASN(ip string) string {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return ""
+	}
+	record, err := al.db.ASN(parsedIP)
+	if err != nil || record.AutonomousSystemNumber == 0 {
+		return ""
+	}
+	return fmt.Sprintf("AS%d %s", record.AutonomousSystemNumber, record.AutonomousSystemOrganization)
+}
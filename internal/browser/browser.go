@@ -0,0 +1,42 @@
+// Package browser opens files and URLs in the user's default web browser.
+package browser
+
+import (
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// command returns the platform command that opens target in the default browser.
+func command(target string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", target)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		return exec.Command("xdg-open", target)
+	}
+}
+
+// OpenURL opens rawURL in the default browser without waiting for the browser to exit.
+func OpenURL(rawURL string) error {
+	cmd := command(rawURL)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the launcher process in the background.
+	go cmd.Wait()
+	return nil
+}
+
+// OpenFile opens a local file in the default browser.
+func OpenFile(fileName string) error {
+	path, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return OpenURL(u.String())
+}
@@ -0,0 +1,94 @@
+// Package buildinfo reports the version, commit, and capabilities of the running binary.
+package buildinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Version is the release version, set at build time with -ldflags "-X .../buildinfo.Version=v1.2.3".
+var Version = "dev"
+
+// Commit is the VCS revision, set at build time or taken from the embedded Go build info.
+var Commit = ""
+
+// Formats lists the log formats supported by the parser.
+var Formats = []string{"clf", "combined", "vhost_combined"}
+
+// Features lists the optional features compiled into the binary.
+var Features = []string{"geoip-country", "history-import", "sqlite-store", "stats-files", "topk-limits", "ua-classification"}
+
+// Info describes the running binary.
+type Info struct {
+	// Version is the release version.
+	Version string `json:"version"`
+	// Commit is the VCS revision the binary was built from.
+	Commit string `json:"commit"`
+	// GoVersion is the version of the Go toolchain used for the build.
+	GoVersion string `json:"go_version"`
+	// Platform is the operating system and architecture.
+	Platform string `json:"platform"`
+	// Formats lists the supported log formats.
+	Formats []string `json:"formats"`
+	// Features lists the enabled features.
+	Features []string `json:"features"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Formats:   Formats,
+		Features:  Features,
+	}
+
+	// Fall back to the revision stamped by the go command.
+	if info.Commit == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	return info
+}
+
+// String returns a human-readable, multi-line description.
+func (info Info) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "go-webalizer %s\n", info.Version)
+	fmt.Fprintf(&sb, "commit:   %s\n", info.Commit)
+	fmt.Fprintf(&sb, "go:       %s %s\n", info.GoVersion, info.Platform)
+	fmt.Fprintf(&sb, "formats:  %s\n", strings.Join(info.Formats, ", "))
+	fmt.Fprintf(&sb, "features: %s\n", strings.Join(info.Features, ", "))
+	return sb.String()
+}
+
+// Handler returns an HTTP handler that serves the build information as JSON.
+// Nothing is sent anywhere; the information is only returned to the caller.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(Get())
+	})
+}
@@ -2,12 +2,20 @@
 package charts
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
+	"html"
 	"maps"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/event"
 	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
+	"github.com/rbscholtus/go-webalizer/internal/countrycache"
 	"github.com/rbscholtus/go-webalizer/internal/http"
 	"github.com/rbscholtus/go-webalizer/internal/logstats"
 )
@@ -75,8 +83,14 @@ func MonthlyBarCharts(aggr map[string]*logstats.HFPBVSData) (*charts.Bar, *chart
 	bBar.SetGlobalOptions(
 		charts.WithTitleOpts(opts.Title{Title: "Usage summary"}),
 		charts.WithColorsOpts(opts.Colors{"#ff0000"}),
+		charts.WithTooltipOpts(opts.Tooltip{ValueFormatter: string(opts.FuncOpts(formatBytesJS))}),
 		charts.WithXAxisOpts(xAxisOpts),
-		charts.WithYAxisOpts(yAxisOpts),
+		charts.WithYAxisOpts(opts.YAxis{
+			AxisLabel: bytesAxisLabel(),
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
 	)
 	bBar.SetXAxis(months).
 		AddSeries("Bytes", bytes)
@@ -94,9 +108,694 @@ func MonthlyBarCharts(aggr map[string]*logstats.HFPBVSData) (*charts.Bar, *chart
 		AddSeries("Sites", sites)
 	vsBar.SetSeriesOptions(gapOpt, styleOpt)
 
+	for _, bar := range []*charts.Bar{hfpBar, bBar, vsBar} {
+		bar.SetGlobalOptions(timeSeriesOpts()...)
+	}
+	return hfpBar, bBar, vsBar
+}
+
+// formatBytesJS is a JavaScript function formatting a number of bytes in binary units, such as 1.5 MiB.
+const formatBytesJS = `function (value) {
+	var units = ['B', 'KiB', 'MiB', 'GiB', 'TiB', 'PiB'];
+	var i = 0;
+	value = Number(value);
+	while (Math.abs(value) >= 1024 && i < units.length - 1) {
+		value /= 1024;
+		i++;
+	}
+	return (i === 0 ? value : value.toFixed(1)) + ' ' + units[i];
+}`
+
+// bytesAxisLabel returns the labels of a value axis of bytes, formatted in binary units.
+func bytesAxisLabel() *opts.AxisLabel {
+	return &opts.AxisLabel{Formatter: opts.FuncOpts(formatBytesJS)}
+}
+
+// SetLogScale draws the values of bars on a logarithmic scale, so series spanning many orders of magnitude
+// are all visible. Days or months without any value are left blank.
+func SetLogScale(bars ...*charts.Bar) {
+	for _, bar := range bars {
+		bar.YAxisList[0].Type = "log"
+	}
+}
+
+// timeSeriesOpts returns the options of charts over time: a slider to zoom into a range of days or months,
+// and a toolbox to restore the zoom and save the chart as an image.
+func timeSeriesOpts() []charts.GlobalOpts {
+	return []charts.GlobalOpts{
+		charts.WithDataZoomOpts(opts.DataZoom{
+			Type: "slider",
+			End:  100,
+		}),
+		charts.WithToolboxOpts(opts.Toolbox{
+			Show: opts.Bool(true),
+			Feature: &opts.ToolBoxFeature{
+				Restore: &opts.ToolBoxFeatureRestore{
+					Show:  opts.Bool(true),
+					Title: "Reset zoom",
+				},
+				SaveAsImage: &opts.ToolBoxFeatureSaveAsImage{
+					Show:  opts.Bool(true),
+					Type:  "png",
+					Title: "Save as image",
+				},
+			},
+		}),
+	}
+}
+
+// DailyBarCharts generates the bar charts of MonthlyBarCharts for the days of a month, as returned by
+// logstats.MonthDayAggregates, titled with the month in the format "YYYY-MM".
+func DailyBarCharts(month string, aggr map[string]*logstats.HFPBVSData) (*charts.Bar, *charts.Bar, *charts.Bar) {
+	title := "Daily usage"
+	if t, err := time.Parse("2006-01", month); err == nil {
+		title += " for " + t.Format("January 2006")
+	}
+
+	hfpBar, bBar, vsBar := MonthlyBarCharts(aggr)
+	for _, bar := range []*charts.Bar{hfpBar, bBar, vsBar} {
+		bar.SetGlobalOptions(
+			charts.WithTitleOpts(opts.Title{Title: title}),
+			charts.WithXAxisOpts(opts.XAxis{
+				Name: "Day",
+				SplitLine: &opts.SplitLine{
+					Show: opts.Bool(true),
+				},
+			}),
+		)
+	}
 	return hfpBar, bBar, vsBar
 }
 
+// AddMovingAverages overlays the charts generated by MonthlyBarCharts for recent days with lines of the hits
+// and visits averaged over the window of days, as returned by logstats.RollingAggregates(days).
+func AddMovingAverages(rolling map[string]*logstats.RollingAverages, days int, hfpBar *charts.Bar, vsBar *charts.Bar) {
+	keys := slices.Sorted(maps.Keys(rolling))
+	dates := make([]string, 0, len(keys))
+	hits := make([]opts.LineData, 0, len(keys))
+	visits := make([]opts.LineData, 0, len(keys))
+	for _, key := range keys {
+		date, _ := time.Parse("2006-01-02", key)
+		dates = append(dates, date.Format("Jan 2"))
+		hits = append(hits, opts.LineData{Value: fmt.Sprintf("%.1f", rolling[key].Hits)})
+		visits = append(visits, opts.LineData{Value: fmt.Sprintf("%.1f", rolling[key].Visits)})
+	}
+
+	lineOpts := []charts.SeriesOpts{
+		charts.WithLineChartOpts(opts.LineChart{Smooth: opts.Bool(true), ShowSymbol: opts.Bool(false)}),
+		charts.WithItemStyleOpts(opts.ItemStyle{Color: "#000000"}),
+	}
+	hitsLine := charts.NewLine()
+	hitsLine.SetXAxis(dates).
+		AddSeries(fmt.Sprintf("Hits (%d-day avg)", days), hits, lineOpts...)
+	hfpBar.Overlap(hitsLine)
+
+	visitsLine := charts.NewLine()
+	visitsLine.SetXAxis(dates).
+		AddSeries(fmt.Sprintf("Visits (%d-day avg)", days), visits, lineOpts...)
+	vsBar.Overlap(visitsLine)
+}
+
+// LinkMonthlyBars makes the bars of charts generated by MonthlyBarCharts(aggr) link to the pages in links,
+// keyed like aggr. Clicking the bars of a month without a link does nothing.
+func LinkMonthlyBars(aggr map[string]*logstats.HFPBVSData, links map[string]string, bars ...*charts.Bar) {
+	urls := make([]string, 0, len(aggr))
+	for _, key := range slices.Sorted(maps.Keys(aggr)) {
+		urls = append(urls, links[key])
+	}
+	js, _ := json.Marshal(urls)
+	handler := fmt.Sprintf("function (params) { var urls = %s; if (urls[params.dataIndex]) { window.location.href = urls[params.dataIndex]; } }", js)
+
+	for _, bar := range bars {
+		bar.SetGlobalOptions(charts.WithEventListeners(event.Listener{
+			EventName: "click",
+			Handler:   opts.FuncOpts(handler),
+		}))
+	}
+}
+
+// HourlyBarChart generates a bar chart for the total hits, files, and pages by hour of day,
+// with their averages per day as lines on a second axis.
+func HourlyBarChart(aggr map[string]*logstats.HFPBVSData, averages map[string]*logstats.HourlyAverages) *charts.Bar {
+	hours := make([]string, 0, len(aggr))
+	hits := make([]opts.BarData, 0, len(aggr))
+	files := make([]opts.BarData, 0, len(aggr))
+	pages := make([]opts.BarData, 0, len(aggr))
+	avgHits := make([]opts.LineData, 0, len(aggr))
+	avgFiles := make([]opts.LineData, 0, len(aggr))
+	avgPages := make([]opts.LineData, 0, len(aggr))
+
+	// Get the sorted keys of the aggregate map.
+	keys := slices.Sorted(maps.Keys(aggr))
+	for _, key := range keys {
+		data := aggr[key]
+		hours = append(hours, data.Category)
+		hits = append(hits, opts.BarData{Value: data.Hits})
+		files = append(files, opts.BarData{Value: data.Files})
+		pages = append(pages, opts.BarData{Value: data.Pages})
+		avg := averages[key]
+		if avg == nil {
+			avg = &logstats.HourlyAverages{}
+		}
+		avgHits = append(avgHits, opts.LineData{Value: fmt.Sprintf("%.1f", avg.Hits)})
+		avgFiles = append(avgFiles, opts.LineData{Value: fmt.Sprintf("%.1f", avg.Files)})
+		avgPages = append(avgPages, opts.LineData{Value: fmt.Sprintf("%.1f", avg.Pages)})
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Hourly Usage"}),
+		charts.WithColorsOpts(opts.Colors{"#00805c", "#0040ff", "#00e0ff"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "bottom"}),
+		charts.WithXAxisOpts(opts.XAxis{Name: "Hour"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Total",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.ExtendYAxis(opts.YAxis{
+		Name: "Avg per day",
+	})
+	bar.SetXAxis(hours).
+		AddSeries("Hits", hits).
+		AddSeries("Files", files).
+		AddSeries("Pages", pages)
+	bar.SetSeriesOptions(
+		charts.WithBarChartOpts(opts.BarChart{
+			BarGap: "-75%",
+		}),
+		charts.WithItemStyleOpts(opts.ItemStyle{
+			BorderWidth: 1,
+			BorderColor: "black",
+		}),
+	)
+
+	line := charts.NewLine()
+	line.SetXAxis(hours).
+		AddSeries("Avg Hits", avgHits, charts.WithLineChartOpts(opts.LineChart{YAxisIndex: 1})).
+		AddSeries("Avg Files", avgFiles, charts.WithLineChartOpts(opts.LineChart{YAxisIndex: 1})).
+		AddSeries("Avg Pages", avgPages, charts.WithLineChartOpts(opts.LineChart{YAxisIndex: 1}))
+	bar.Overlap(line)
+
+	return bar
+}
+
+// WeekdayBarChart generates a bar chart for average daily hits, files, pages, and visits by day of week.
+func WeekdayBarChart(aggr map[string]*logstats.HFPBVSData) *charts.Bar {
+	days := make([]string, 0, len(aggr))
+	hits := make([]opts.BarData, 0, len(aggr))
+	files := make([]opts.BarData, 0, len(aggr))
+	pages := make([]opts.BarData, 0, len(aggr))
+	visits := make([]opts.BarData, 0, len(aggr))
+
+	// Get the sorted keys of the aggregate map.
+	keys := slices.Sorted(maps.Keys(aggr))
+	for _, key := range keys {
+		data := aggr[key]
+		days = append(days, data.Category)
+		hits = append(hits, opts.BarData{Value: data.Hits})
+		files = append(files, opts.BarData{Value: data.Files})
+		pages = append(pages, opts.BarData{Value: data.Pages})
+		visits = append(visits, opts.BarData{Value: data.Visits})
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Usage by Day of Week",
+			Subtitle: "Average per day",
+		}),
+		charts.WithColorsOpts(opts.Colors{"#00805c", "#0040ff", "#00e0ff", "#ffff00"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.SetXAxis(days).
+		AddSeries("Hits", hits).
+		AddSeries("Files", files).
+		AddSeries("Pages", pages).
+		AddSeries("Visits", visits)
+	bar.SetSeriesOptions(
+		charts.WithItemStyleOpts(opts.ItemStyle{
+			BorderWidth: 1,
+			BorderColor: "black",
+		}),
+	)
+
+	return bar
+}
+
+// VisitQualityChart generates a line chart for the average visit duration and bounce rate per day.
+func VisitQualityChart(visits map[string]*logstats.VisitMetrics) *charts.Line {
+	days := make([]string, 0, len(visits))
+	durations := make([]opts.LineData, 0, len(visits))
+	bounceRates := make([]opts.LineData, 0, len(visits))
+
+	// Get the sorted keys of the visits map.
+	keys := slices.Sorted(maps.Keys(visits))
+	for _, key := range keys {
+		vm := visits[key]
+		date, _ := time.Parse("2006-01-02", key)
+		days = append(days, date.Format("Jan 2"))
+		durations = append(durations, opts.LineData{Value: fmt.Sprintf("%.1f", vm.AvgDuration().Minutes())})
+		bounceRates = append(bounceRates, opts.LineData{Value: fmt.Sprintf("%.1f", vm.BounceRate())})
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Visit Duration and Bounce Rate"}),
+		charts.WithColorsOpts(opts.Colors{"#0040ff", "#ff0000"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Minutes",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	line.ExtendYAxis(opts.YAxis{
+		Name: "Bounce %",
+		Min:  0,
+		Max:  100,
+	})
+	line.SetXAxis(days).
+		AddSeries("Avg visit duration", durations).
+		AddSeries("Bounce rate", bounceRates, charts.WithLineChartOpts(opts.LineChart{YAxisIndex: 1}))
+
+	return line
+}
+
+// ContentBandwidthChart generates a stacked bar chart of the daily bytes transferred per content category.
+func ContentBandwidthChart(aggr map[string]map[string]uint64) *charts.Bar {
+	keys := slices.Sorted(maps.Keys(aggr))
+	days := make([]string, 0, len(keys))
+	for _, key := range keys {
+		date, _ := time.Parse("2006-01-02", key)
+		days = append(days, date.Format("Jan 2"))
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Bandwidth by Content Type"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis", ValueFormatter: string(opts.FuncOpts(formatBytesJS))}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "bottom"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name:      "Bytes",
+			AxisLabel: bytesAxisLabel(),
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.SetXAxis(days)
+	for _, category := range http.ContentCategories {
+		items := make([]opts.BarData, 0, len(keys))
+		total := uint64(0)
+		for _, key := range keys {
+			bytes := aggr[key][category]
+			items = append(items, opts.BarData{Value: bytes})
+			total += bytes
+		}
+		// Leave out categories that were not requested at all.
+		if total > 0 {
+			bar.AddSeries(category, items, charts.WithBarChartOpts(opts.BarChart{Stack: "bytes"}))
+		}
+	}
+
+	return bar
+}
+
+// SchemeChart generates a chart of the daily HTTPS and HTTP hits, stacked, with the share of HTTPS hits as a line.
+func SchemeChart(aggr map[string]map[string]uint64) *charts.Bar {
+	keys := slices.Sorted(maps.Keys(aggr))
+	days := make([]string, 0, len(keys))
+	secureHits := make([]opts.BarData, 0, len(keys))
+	plainHits := make([]opts.BarData, 0, len(keys))
+	shares := make([]opts.LineData, 0, len(keys))
+	for _, key := range keys {
+		date, _ := time.Parse("2006-01-02", key)
+		days = append(days, date.Format("Jan 2"))
+		secure, plain := aggr[key]["https"], aggr[key]["http"]
+		secureHits = append(secureHits, opts.BarData{Value: secure})
+		plainHits = append(plainHits, opts.BarData{Value: plain})
+		share := 0.0
+		if secure+plain > 0 {
+			share = float64(secure) * 100 / float64(secure+plain)
+		}
+		shares = append(shares, opts.LineData{Value: fmt.Sprintf("%.1f", share)})
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "HTTPS and HTTP Hits"}),
+		charts.WithColorsOpts(opts.Colors{"#00805c", "#ff8000", "#0040ff"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "bottom"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Hits",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.ExtendYAxis(opts.YAxis{
+		Name: "HTTPS %",
+		Min:  0,
+		Max:  100,
+	})
+	bar.SetXAxis(days).
+		AddSeries("HTTPS", secureHits, charts.WithBarChartOpts(opts.BarChart{Stack: "hits"})).
+		AddSeries("HTTP", plainHits, charts.WithBarChartOpts(opts.BarChart{Stack: "hits"}))
+
+	line := charts.NewLine()
+	line.SetXAxis(days).
+		AddSeries("HTTPS share", shares, charts.WithLineChartOpts(opts.LineChart{YAxisIndex: 1}))
+	bar.Overlap(line)
+
+	return bar
+}
+
+// VisitorTypeChart generates a stacked bar chart of the daily new and returning visitors.
+func VisitorTypeChart(aggr map[string]map[string]uint64) *charts.Bar {
+	keys := slices.Sorted(maps.Keys(aggr))
+	days := make([]string, 0, len(keys))
+	for _, key := range keys {
+		date, _ := time.Parse("2006-01-02", key)
+		days = append(days, date.Format("Jan 2"))
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Daily New and Returning Visitors"}),
+		charts.WithColorsOpts(opts.Colors{"#00e0ff", "#0040ff"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "bottom"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Visitors",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.SetXAxis(days)
+	for _, visitorType := range logstats.VisitorTypes {
+		items := make([]opts.BarData, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, opts.BarData{Value: aggr[key][visitorType]})
+		}
+		bar.AddSeries(visitorType, items, charts.WithBarChartOpts(opts.BarChart{Stack: "visitors"}))
+	}
+
+	return bar
+}
+
+// StatusClassChart generates a stacked bar chart of the daily hits per response code class.
+func StatusClassChart(aggr map[string]map[string]uint64) *charts.Bar {
+	keys := slices.Sorted(maps.Keys(aggr))
+	days := make([]string, 0, len(keys))
+	for _, key := range keys {
+		date, _ := time.Parse("2006-01-02", key)
+		days = append(days, date.Format("Jan 2"))
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Daily Hits by Response Class"}),
+		charts.WithColorsOpts(opts.Colors{"#a0a0a0", "#00805c", "#0040ff", "#ff8000", "#ff0000"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "bottom"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Hits",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.SetXAxis(days)
+	for _, class := range logstats.StatusClasses {
+		items := make([]opts.BarData, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, opts.BarData{Value: aggr[key][class]})
+		}
+		bar.AddSeries(class, items, charts.WithBarChartOpts(opts.BarChart{Stack: "hits"}))
+	}
+
+	return bar
+}
+
+// SizePercentilesChart generates a line chart of the daily response size percentiles in bytes,
+// on a logarithmic scale so small and large responses are both visible.
+func SizePercentilesChart(aggr map[string]*logstats.SizePercentiles) *charts.Line {
+	days := make([]string, 0, len(aggr))
+	p50 := make([]opts.LineData, 0, len(aggr))
+	p90 := make([]opts.LineData, 0, len(aggr))
+	p99 := make([]opts.LineData, 0, len(aggr))
+
+	keys := slices.Sorted(maps.Keys(aggr))
+	for _, key := range keys {
+		sp := aggr[key]
+		date, _ := time.Parse("2006-01-02", key)
+		days = append(days, date.Format("Jan 2"))
+		p50 = append(p50, opts.LineData{Value: sp.P50})
+		p90 = append(p90, opts.LineData{Value: sp.P90})
+		p99 = append(p99, opts.LineData{Value: sp.P99})
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Response Size Percentiles"}),
+		charts.WithColorsOpts(opts.Colors{"#00805c", "#ff8000", "#ff0000"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis", ValueFormatter: string(opts.FuncOpts(formatBytesJS))}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name:      "Size",
+			Type:      "log",
+			AxisLabel: bytesAxisLabel(),
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	line.SetXAxis(days).
+		AddSeries("p50", p50).
+		AddSeries("p90", p90).
+		AddSeries("p99", p99)
+
+	return line
+}
+
+// SizeBucketBarChart generates a bar chart for the response size distribution.
+func SizeBucketBarChart(aggr map[string]uint64) *charts.Bar {
+	items := make([]opts.BarData, 0, len(logstats.SizeBuckets))
+	for _, bucket := range logstats.SizeBuckets {
+		items = append(items, opts.BarData{Value: aggr[bucket]})
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Response Sizes"}),
+		charts.WithColorsOpts(opts.Colors{"#ff0000"}),
+		charts.WithXAxisOpts(opts.XAxis{Name: "Size"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Hits",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.SetXAxis(logstats.SizeBuckets).
+		AddSeries("Hits", items)
+	bar.SetSeriesOptions(
+		charts.WithItemStyleOpts(opts.ItemStyle{
+			BorderWidth: 1,
+			BorderColor: "black",
+		}),
+	)
+
+	return bar
+}
+
+// ErrorTrendChart generates a line chart of the daily error hits of the n URL paths with the most errors.
+func ErrorTrendChart(aggr map[string]map[string]uint64, n int) *charts.Line {
+	// Find the URL paths with the most errors over all days.
+	totals := make(map[string]uint64)
+	for _, urlPaths := range aggr {
+		for urlPath, hits := range urlPaths {
+			totals[urlPath] += hits
+		}
+	}
+	urlPaths := slices.SortedFunc(maps.Keys(totals), func(a, b string) int {
+		if c := cmp.Compare(totals[b], totals[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(urlPaths) > n {
+		urlPaths = urlPaths[:n]
+	}
+
+	keys := slices.Sorted(maps.Keys(aggr))
+	days := make([]string, 0, len(keys))
+	for _, key := range keys {
+		date, _ := time.Parse("2006-01-02", key)
+		days = append(days, date.Format("Jan 2"))
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Daily Errors by URL"}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "bottom"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Errors",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	line.SetXAxis(days)
+	for _, urlPath := range urlPaths {
+		items := make([]opts.LineData, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, opts.LineData{Value: aggr[key][urlPath]})
+		}
+		line.AddSeries(urlPath, items)
+	}
+
+	return line
+}
+
+// maxLabelLength is the maximum length in runes of the category labels of horizontal bar charts.
+const maxLabelLength = 40
+
+// TopURLsBarChart generates a horizontal bar chart of the n URL paths with the most hits, the top one
+// at the top, showing their kilobytes in the tooltip.
+func TopURLsBarChart(aggr map[string]*logstats.HitsBytes, n int) *charts.Bar {
+	urlPaths := slices.SortedFunc(maps.Keys(aggr), func(a, b string) int {
+		if c := cmp.Compare(aggr[b].Hits, aggr[a].Hits); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(urlPaths) > n {
+		urlPaths = urlPaths[:n]
+	}
+
+	labels := make([]string, 0, len(urlPaths))
+	hits := make([]opts.BarData, 0, len(urlPaths))
+	for _, urlPath := range urlPaths {
+		hb := aggr[urlPath]
+		labels = append(labels, shorten(urlPath, maxLabelLength))
+		hits = append(hits, opts.BarData{
+			Value: hb.Hits,
+			Tooltip: &opts.Tooltip{
+				Formatter: types.FuncStr(fmt.Sprintf("%s<br>%d hits, %d KBytes",
+					html.EscapeString(urlPath), hb.Hits, hb.Bytes/1024)),
+			},
+		})
+	}
+
+	return horizontalBarChart(fmt.Sprintf("Top %d URLs", n), "#00805c", labels, hits)
+}
+
+// TopReferrersBarChart generates a horizontal bar chart of the n referring domains with the most hits,
+// the top one at the top. If link is not empty, clicking a bar opens it, such as the full referrer table.
+func TopReferrersBarChart(aggr map[string]uint64, n int, link string) *charts.Bar {
+	domains := slices.SortedFunc(maps.Keys(aggr), func(a, b string) int {
+		if c := cmp.Compare(aggr[b], aggr[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(domains) > n {
+		domains = domains[:n]
+	}
+
+	labels := make([]string, 0, len(domains))
+	hits := make([]opts.BarData, 0, len(domains))
+	for _, domain := range domains {
+		labels = append(labels, shorten(domain, maxLabelLength))
+		hits = append(hits, opts.BarData{Value: aggr[domain]})
+	}
+
+	bar := horizontalBarChart(fmt.Sprintf("Top %d Referring Sites", n), "#ff8000", labels, hits)
+	if link != "" {
+		js, _ := json.Marshal(link)
+		bar.SetGlobalOptions(charts.WithEventListeners(event.Listener{
+			EventName: "click",
+			Handler:   opts.FuncOpts(fmt.Sprintf("function () { window.location.href = %s; }", js)),
+		}))
+	}
+	return bar
+}
+
+// horizontalBarChart generates a horizontal bar chart of hits by category, the first category at the top.
+func horizontalBarChart(title string, color string, labels []string, hits []opts.BarData) *charts.Bar {
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: title}),
+		charts.WithColorsOpts(opts.Colors{color}),
+		charts.WithTooltipOpts(opts.Tooltip{Trigger: "item"}),
+		charts.WithGridOpts(opts.Grid{Left: "3%", Right: "4%", ContainLabel: opts.Bool(true)}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Name: "Hits",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{Inverse: opts.Bool(true)}),
+	)
+	bar.SetXAxis(labels).
+		AddSeries("Hits", hits)
+	bar.XYReversal()
+
+	return bar
+}
+
+// shorten returns s cut to at most n runes, marking a cut with an ellipsis.
+func shorten(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// PagesPerVisitBarChart generates a bar chart for the distribution of page views per visit.
+func PagesPerVisitBarChart(aggr map[string]uint64) *charts.Bar {
+	items := make([]opts.BarData, 0, len(logstats.PagesBuckets))
+	for _, bucket := range logstats.PagesBuckets {
+		items = append(items, opts.BarData{Value: aggr[bucket]})
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Pages per Visit"}),
+		charts.WithColorsOpts(opts.Colors{"#ffff00"}),
+		charts.WithXAxisOpts(opts.XAxis{Name: "Pages"}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Visits",
+			SplitLine: &opts.SplitLine{
+				Show: opts.Bool(true),
+			},
+		}),
+	)
+	bar.SetXAxis(logstats.PagesBuckets).
+		AddSeries("Visits", items)
+	bar.SetSeriesOptions(
+		charts.WithItemStyleOpts(opts.ItemStyle{
+			BorderWidth: 1,
+			BorderColor: "black",
+		}),
+	)
+
+	return bar
+}
+
 // MethodPieChart generates a pie chart for HTTP method distribution.
 func MethodPieChart(aggr map[string]uint64) *charts.Pie {
 	pie := charts.NewPie()
@@ -161,32 +860,392 @@ func ResponsesPieChart(aggr map[uint16]uint64) *charts.Pie {
 	return pie
 }
 
-// WorldMap generates a world map chart for country distribution.
-func WorldMap(countries map[string]uint64) *charts.Map {
-	// Calculate series data for the chart.
-	items := make([]opts.MapData, 0, len(countries))
-	maxVisits := uint64(0)
-	for k, v := range countries {
-		items = append(items, opts.MapData{Name: k, Value: v})
-		if v > maxVisits {
-			maxVisits = v
+// BrowserPieChart generates a pie chart for browser family distribution.
+func BrowserPieChart(aggr map[string]uint64) *charts.Pie {
+	return visitsPieChart("Visits by Browser", "Browser", aggr)
+}
+
+// OSPieChart generates a pie chart for operating system distribution.
+func OSPieChart(aggr map[string]uint64) *charts.Pie {
+	return visitsPieChart("Visits by Operating System", "Operating System", aggr)
+}
+
+// DevicePieChart generates a pie chart for device type distribution.
+func DevicePieChart(aggr map[string]uint64) *charts.Pie {
+	return visitsPieChart("Visits by Device", "Device", aggr)
+}
+
+// visitsPieChart generates a pie chart for the distribution of visits over the keys of aggr.
+func visitsPieChart(title string, name string, aggr map[string]uint64) *charts.Pie {
+	pie := charts.NewPie()
+
+	pie.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: title,
+		}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(false)}),
+	)
+
+	// Calculate series data for the chart, with the largest slices first.
+	keys := slices.SortedFunc(maps.Keys(aggr), func(a, b string) int {
+		if c := cmp.Compare(aggr[b], aggr[a]); c != 0 {
+			return c
 		}
+		return strings.Compare(a, b)
+	})
+	items := make([]opts.PieData, 0, len(aggr))
+	for _, key := range keys {
+		items = append(items, opts.PieData{Name: key, Value: aggr[key]})
+	}
+
+	pie.AddSeries(name, items).
+		SetSeriesOptions(
+			charts.WithLabelOpts(opts.Label{
+				Show:      opts.Bool(true),
+				Formatter: "{b} ({d}%)",
+			}),
+			charts.WithPieChartOpts(opts.PieChart{
+				Radius: []string{"30%", "75%"},
+			}),
+		)
+
+	return pie
+}
+
+// worldMapNames holds the names of the regions of the echarts world map by ISO 3166 code, for the countries
+// whose name in the map differs from the English names in the country databases.
+var worldMapNames = map[string]string{
+	"BA": "Bosnia and Herz.",
+	"BN": "Brunei",
+	"BO": "Bolivia",
+	"BS": "Bahamas",
+	"CD": "Dem. Rep. Congo",
+	"CF": "Central African Rep.",
+	"CG": "Congo",
+	"CI": "Côte d'Ivoire",
+	"CZ": "Czech Rep.",
+	"DO": "Dominican Rep.",
+	"EH": "W. Sahara",
+	"FK": "Falkland Is.",
+	"GB": "United Kingdom",
+	"GM": "Gambia",
+	"GQ": "Eq. Guinea",
+	"IR": "Iran",
+	"KP": "Dem. Rep. Korea",
+	"KR": "Korea",
+	"LA": "Lao PDR",
+	"MD": "Moldova",
+	"MK": "Macedonia",
+	"MM": "Myanmar",
+	"NL": "Netherlands",
+	"PS": "Palestine",
+	"RU": "Russia",
+	"SB": "Solomon Is.",
+	"SS": "S. Sudan",
+	"SY": "Syria",
+	"SZ": "Swaziland",
+	"TF": "Fr. S. Antarctic Lands",
+	"TL": "Timor-Leste",
+	"TR": "Turkey",
+	"TW": "Taiwan",
+	"TZ": "Tanzania",
+	"US": "United States",
+	"VE": "Venezuela",
+	"VN": "Vietnam",
+}
+
+// WorldMap generates a world map chart for the distribution of visits, hits, and kilobytes over countries.
+// The legend toggles between the metrics, rescaling the color scale to the selected one. codes holds the
+// ISO 3166 codes of the countries, used to match countries whose name differs from the name in the map.
+func WorldMap(countries map[string]*logstats.HitsBytesVisits, codes map[string]string) *charts.Map {
+	metrics := []struct {
+		name  string
+		value func(hbv *logstats.HitsBytesVisits) uint64
+	}{
+		{"Visits", func(hbv *logstats.HitsBytesVisits) uint64 { return hbv.Visits }},
+		{"Hits", func(hbv *logstats.HitsBytesVisits) uint64 { return hbv.Hits }},
+		{"KBytes", func(hbv *logstats.HitsBytesVisits) uint64 { return hbv.Bytes / 1024 }},
 	}
 
 	mc := charts.NewMap()
 	mc.RegisterMapType("world")
+
+	// Calculate series data for the chart.
+	maxValues := make(map[string]uint64, len(metrics))
+	selected := make(map[string]bool, len(metrics))
+	for i, metric := range metrics {
+		// Databases may name several countries like a single region of the map.
+		regions := make(map[string]uint64, len(countries))
+		for country, hbv := range countries {
+			// Visitors that cannot be placed on the map would only skew its scale.
+			if country == countrycache.Unknown || country == countrycache.Unresolved {
+				continue
+			}
+			region := cmp.Or(worldMapNames[codes[country]], country)
+			regions[region] += metric.value(hbv)
+		}
+		items := make([]opts.MapData, 0, len(regions))
+		for _, region := range slices.Sorted(maps.Keys(regions)) {
+			v := regions[region]
+			items = append(items, opts.MapData{Name: region, Value: v})
+			maxValues[metric.name] = max(maxValues[metric.name], v)
+		}
+		mc.AddSeries(metric.name, items)
+		selected[metric.name] = i == 0
+	}
+
+	js, _ := json.Marshal(maxValues)
+	handler := fmt.Sprintf("function (params) { var max = %s; %%MY_ECHARTS%%.setOption({visualMap: {max: max[params.name]}}); }", js)
 	mc.SetGlobalOptions(
 		charts.WithTitleOpts(opts.Title{
-			Title: "Visits by Country",
+			Title: "Traffic by Country",
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show:         opts.Bool(true),
+			Top:          "bottom",
+			SelectedMode: "single",
+			Selected:     selected,
 		}),
 		charts.WithVisualMapOpts(opts.VisualMap{
 			Calculable: opts.Bool(true),
 			Min:        0,
-			Max:        float32(maxVisits),
+			Max:        float32(maxValues[metrics[0].name]),
+		}),
+		charts.WithEventListeners(event.Listener{
+			EventName: "legendselectchanged",
+			Handler:   opts.FuncOpts(handler),
 		}),
 	)
 
-	mc.AddSeries("Visits", items)
-
 	return mc
 }
+
+// CityScatterChart generates a world map with a bubble for each of the n cities with the most visits,
+// sized by their visits. Cities without a known location are left out.
+func CityScatterChart(cities map[string]uint64, locations map[string]*logstats.GeoPoint, n int) *charts.Geo {
+	names := slices.SortedFunc(maps.Keys(cities), func(a, b string) int {
+		if c := cmp.Compare(cities[b], cities[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	items := make([]opts.GeoData, 0, n)
+	maxVisits := uint64(1)
+	for _, city := range names {
+		location, ok := locations[city]
+		if !ok {
+			continue
+		}
+		if len(items) == n {
+			break
+		}
+		items = append(items, opts.GeoData{Name: city, Value: []float64{location.Lon, location.Lat, float64(cities[city])}})
+		maxVisits = max(maxVisits, cities[city])
+	}
+
+	geo := charts.NewGeo()
+	geo.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Visits by City"}),
+		charts.WithGeoComponentOpts(opts.GeoComponent{Map: "world", ItemStyle: &opts.ItemStyle{Color: "#e0e0e0"}}),
+	)
+	// Bubble areas are proportional to the visits.
+	symbolSize := fmt.Sprintf("function (value) { return 4 + 26 * Math.sqrt(value[2] / %d); }", maxVisits)
+	geo.AddSeries("Visits", types.ChartEffectScatter, items,
+		charts.WithEffectScatterChartOpts(opts.EffectScatterChart{
+			CoordSystem: types.ChartGeo,
+			SymbolSize:  opts.FuncOpts(symbolSize),
+		}),
+		charts.WithItemStyleOpts(opts.ItemStyle{Color: "#ff8000"}),
+	)
+
+	return geo
+}
+
+// URLTreemap generates a treemap of the URL paths in aggr grouped by their path segments, such as /blog/ and
+// /static/, up to depth directories deep. The legend toggles between sizing the paths by hits and by kilobytes.
+// Only the maxPaths URL paths with the most hits are included.
+func URLTreemap(aggr map[string]*logstats.HitsBytes, depth int, maxPaths int) *charts.TreeMap {
+	urlPaths := slices.SortedFunc(maps.Keys(aggr), func(a, b string) int {
+		if c := cmp.Compare(aggr[b].Hits, aggr[a].Hits); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(urlPaths) > maxPaths {
+		urlPaths = urlPaths[:maxPaths]
+	}
+
+	root := &urlNode{}
+	for _, urlPath := range urlPaths {
+		segments := strings.Split(strings.TrimPrefix(urlPath, "/"), "/")
+		dirs, leaf := segments[:len(segments)-1], segments[len(segments)-1]
+		if len(dirs) > depth {
+			leaf = strings.Join(append(dirs[depth:], leaf), "/")
+			dirs = dirs[:depth]
+		}
+		node := root
+		for _, dir := range dirs {
+			node = node.child(dir + "/")
+		}
+		if leaf == "" {
+			leaf = "/"
+		}
+		node = node.child(leaf)
+		node.hits += aggr[urlPath].Hits
+		node.bytes += aggr[urlPath].Bytes
+	}
+
+	tm := charts.NewTreeMap()
+	tm.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "URLs by Section"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+		charts.WithLegendOpts(opts.Legend{
+			Show:         opts.Bool(true),
+			Top:          "bottom",
+			SelectedMode: "single",
+			Selected:     map[string]bool{"Hits": true, "KBytes": false},
+		}),
+	)
+	seriesOpts := charts.WithTreeMapOpts(opts.TreeMapChart{
+		Roam:   opts.Bool(false),
+		Top:    "40",
+		Bottom: "60",
+		UpperLabel: &opts.UpperLabel{
+			Show: opts.Bool(true),
+		},
+		Levels: &[]opts.TreeMapLevel{
+			{ItemStyle: &opts.ItemStyle{BorderColor: "#555", BorderWidth: 4, GapWidth: 4}},
+			{ItemStyle: &opts.ItemStyle{BorderColor: "#ddd", BorderWidth: 2, GapWidth: 2}, ColorSaturation: []float32{0.35, 0.5}},
+			{ItemStyle: &opts.ItemStyle{BorderWidth: 1, GapWidth: 1}, ColorSaturation: []float32{0.35, 0.5}},
+		},
+	})
+	tm.AddSeries("Hits", root.nodes(func(node *urlNode) uint64 { return node.hits }), seriesOpts)
+	tm.AddSeries("KBytes", root.nodes(func(node *urlNode) uint64 { return node.bytes / 1024 }), seriesOpts)
+
+	return tm
+}
+
+// urlNode is a path segment in the tree of URL paths of URLTreemap.
+type urlNode struct {
+	// children are the path segments below this one, keyed by name.
+	children map[string]*urlNode
+	// hits is the number of hits of the URL path ending in this segment, which is not a directory.
+	hits uint64
+	// bytes is the number of bytes transferred for the URL path ending in this segment.
+	bytes uint64
+}
+
+// child returns the child segment of node with the name, adding it if needed.
+func (node *urlNode) child(name string) *urlNode {
+	if node.children == nil {
+		node.children = make(map[string]*urlNode)
+	}
+	child, ok := node.children[name]
+	if !ok {
+		child = &urlNode{}
+		node.children[name] = child
+	}
+	return child
+}
+
+// nodes returns the treemap nodes of the children of node, valued by value. Directories are named with
+// a trailing slash, so a page and a directory of the same name, such as /blog and /blog/, are separate nodes.
+func (node *urlNode) nodes(value func(node *urlNode) uint64) []opts.TreeMapNode {
+	names := slices.Sorted(maps.Keys(node.children))
+	nodes := make([]opts.TreeMapNode, 0, len(names))
+	for _, name := range names {
+		child := node.children[name]
+		if child.children == nil {
+			nodes = append(nodes, opts.TreeMapNode{Name: name, Value: int(value(child))})
+			continue
+		}
+		nodes = append(nodes, opts.TreeMapNode{Name: name, Children: child.nodes(value)})
+	}
+	return nodes
+}
+
+// AcquisitionSankey generates a Sankey diagram of the visits flowing from the n top referring domains to
+// the n top entry pages, and on to the n top exit pages if exits is set, from acquisitions keyed by
+// logstats.AcquisitionKey. The other referrers and pages are combined.
+func AcquisitionSankey(aggr map[string]uint64, n int, exits bool) *charts.Sankey {
+	const otherSites, otherPages = "Other sites", "Other pages"
+	// exitName distinguishes the exit pages from the entry pages, as nodes must have unique names.
+	exitName := func(page string) string { return page + " (exit)" }
+
+	referrerVisits := make(map[string]uint64)
+	entryVisits := make(map[string]uint64)
+	exitVisits := make(map[string]uint64)
+	for key, visits := range aggr {
+		referrer, entry, exit := logstats.AcquisitionPages(key)
+		referrerVisits[referrer] += visits
+		entryVisits[entry] += visits
+		exitVisits[exit] += visits
+	}
+	topReferrers := topSet(referrerVisits, n)
+	topEntries := topSet(entryVisits, n)
+	topExits := topSet(exitVisits, n)
+
+	links := make(map[[2]string]uint64)
+	for key, visits := range aggr {
+		referrer, entry, exit := logstats.AcquisitionPages(key)
+		if !topReferrers[referrer] {
+			referrer = otherSites
+		}
+		if !topEntries[entry] {
+			entry = otherPages
+		}
+		if !topExits[exit] {
+			exit = otherPages
+		}
+		links[[2]string{referrer, entry}] += visits
+		if exits {
+			links[[2]string{entry, exitName(exit)}] += visits
+		}
+	}
+
+	seen := make(map[string]bool)
+	var nodes []opts.SankeyNode
+	var sankeyLinks []opts.SankeyLink
+	for _, link := range slices.SortedFunc(maps.Keys(links), func(a, b [2]string) int {
+		if c := cmp.Compare(links[b], links[a]); c != 0 {
+			return c
+		}
+		return cmp.Or(strings.Compare(a[0], b[0]), strings.Compare(a[1], b[1]))
+	}) {
+		for _, name := range link {
+			if !seen[name] {
+				seen[name] = true
+				nodes = append(nodes, opts.SankeyNode{Name: name})
+			}
+		}
+		sankeyLinks = append(sankeyLinks, opts.SankeyLink{Source: link[0], Target: link[1], Value: float32(links[link])})
+	}
+
+	sankey := charts.NewSankey()
+	sankey.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Visits from Referrers to Entry Pages"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "item"}),
+	)
+	sankey.AddSeries("Visits", nodes, sankeyLinks,
+		charts.WithLabelOpts(opts.Label{Show: opts.Bool(true)}),
+		charts.WithLineStyleOpts(opts.LineStyle{Color: "source", Curveness: 0.5, Opacity: opts.Float(0.4)}),
+	)
+
+	return sankey
+}
+
+// topSet returns the set of the n keys with the highest counts.
+func topSet(counts map[string]uint64, n int) map[string]bool {
+	keys := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	set := make(map[string]bool, n)
+	for _, key := range keys[:min(n, len(keys))] {
+		set[key] = true
+	}
+	return set
+}
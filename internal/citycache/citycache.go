@@ -0,0 +1,91 @@
+// Package citycache provides a cached city lookup service using the MaxMind GeoLite2-City database.
+package citycache
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// CityLookup represents a city lookup service.
+type CityLookup struct {
+	// db is the underlying GeoLite2-City database reader.
+	db *geoip2.Reader
+	// cities is a map of cities, where the key is the IP address and the value is the city,
+	// or an empty string if the IP address is not in the database.
+	cities map[string]string
+	// locations is a map of the latitude and longitude of the cities looked up, where the key is the city.
+	locations map[string][2]float64
+}
+
+// NewCityLookup returns a new CityLookup instance.
+// dbPath is the path to the GeoLite2-City database file.
+func NewCityLookup(dbPath string) (*CityLookup, error) {
+	db, err := geoip2.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CityLookup{
+		db:        db,
+		cities:    make(map[string]string),
+		locations: make(map[string][2]float64),
+	}, nil
+}
+
+// Close closes the underlying GeoLite2-City database.
+func (cl *CityLookup) Close() error {
+	return cl.db.Close()
+}
+
+// Lookup returns the city of an IP address in the form "City, Region, Country", leaving out the parts that
+// are unknown. It returns false if the IP address is invalid or neither its city nor its region is known.
+func (cl *CityLookup) Lookup(ip string) (string, bool) {
+	city, ok := cl.cities[ip]
+	if !ok {
+		city = cl.lookupCity(ip)
+		cl.cities[ip] = city
+	}
+	return city, city != ""
+}
+
+// lookupCity looks up the city of an IP address in the database.
+func (cl *CityLookup) lookupCity(ip string) string {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return ""
+	}
+	record, err := cl.db.City(parsedIP)
+	if err != nil {
+		return ""
+	}
+
+	parts := make([]string, 0, 3)
+	if name := record.City.Names["en"]; name != "" {
+		parts = append(parts, name)
+	}
+	if len(record.Subdivisions) > 0 {
+		if name := record.Subdivisions[0].Names["en"]; name != "" {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	if name := record.Country.Names["en"]; name != "" {
+		parts = append(parts, name)
+	}
+	city := strings.Join(parts, ", ")
+	if _, ok := cl.locations[city]; !ok && (record.Location.Latitude != 0 || record.Location.Longitude != 0) {
+		cl.locations[city] = [2]float64{record.Location.Latitude, record.Location.Longitude}
+	}
+	return city
+}
+
+// Location returns the latitude and longitude of a city returned by Lookup.
+// It returns false if the location of the city is not in the database.
+func (cl *CityLookup) Location(city string) (float64, float64, bool) {
+	location, ok := cl.locations[city]
+	return location[0], location[1], ok
+}
@@ -0,0 +1,543 @@
+// Package config reads configuration files holding Webalizer-style directives, or settings in YAML or TOML.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rbscholtus/go-webalizer/internal/group"
+	"github.com/rbscholtus/go-webalizer/internal/iprange"
+	"github.com/rbscholtus/go-webalizer/internal/pages"
+)
+
+// Config holds the settings read from a configuration file.
+type Config struct {
+	// Input holds the log files to process, if set.
+	Input []string
+	// Format is the log format, such as parser.FormatCombined, if set.
+	Format string
+	// OutputDir is the directory the report is written to, if set.
+	OutputDir string
+	// HostName is the host name of the site, shown in the report titles and telling internal referrers, if set.
+	HostName string
+	// Title is the title of the report, if set.
+	Title string
+	// VisitTimeout is the time without hits after which a visitor starts a new visit, or 0 for the default.
+	VisitTimeout time.Duration
+	// Schedule is the cron-style schedule the logs are processed on in a long-running process, if set.
+	Schedule string
+	// TimeZone is the name of the time zone the timestamps are converted to, such as Europe/Amsterdam, if set.
+	TimeZone string
+	// Groups holds the grouping rules.
+	Groups group.Groups
+	// Ignore holds the rules of log lines that are left out of all statistics.
+	Ignore group.Filters
+	// Hide holds the rules of rows that are left out of the top-N tables, but still counted in the totals.
+	Hide group.Filters
+	// Pages holds the rules deciding which URL paths are pages.
+	Pages pages.Rules
+	// ExcludeMethods are the request methods left out of all statistics but the methods breakdown.
+	ExcludeMethods []string
+	// IgnoreRobots leaves robots out of all statistics but the robots breakdown.
+	IgnoreRobots bool
+	// Anonymize is the mode the IP addresses of visitors are anonymized in, such as anonymize.Truncate, if set.
+	Anonymize string
+	// Top holds the number of rows of the top-N tables.
+	Top TopN
+	// All selects the full listings written next to the report.
+	All Listings
+	// Theme is the name of the report theme, if set.
+	Theme string
+	// Palette holds the colors of the chart series, or the name of a theme palette, such as pastel, if set.
+	Palette []string
+	// SeriesColors holds the colors of chart series by series name.
+	SeriesColors map[string]string
+	// ChartColumns is the number of charts placed side by side, or 0 to stack them.
+	ChartColumns int
+	// HiddenCharts holds the titles of the charts left out of the report.
+	HiddenCharts []string
+	// ChartSpans holds the number of columns a chart spans by title.
+	ChartSpans map[string]int
+	// Webhook is the webhook the summary of the last day is posted to after each run.
+	Webhook Webhook
+	// MaxMindLicenseKey is the MaxMind license key the GeoLite2 databases are downloaded with, if set.
+	MaxMindLicenseKey string
+	// MaxMindAccountID is the MaxMind account ID the countries of IP addresses that are not in the country
+	// database are looked up in the web service with, together with MaxMindLicenseKey, if set.
+	MaxMindAccountID string
+	// CountryDB is the GeoLite2-Country, DB-IP, or IPinfo database countries are looked up in, if set.
+	CountryDB string
+	// GeoWorkers is the number of country lookups run in parallel, or 0 for the default.
+	GeoWorkers int
+	// GeoCache is the file the countries looked up are kept in between runs, if set.
+	GeoCache string
+	// GeoCacheTTL is the age after which the countries in the cache file are looked up again, or 0 for the default.
+	GeoCacheTTL time.Duration
+	// GeoTimeout is the time a single country lookup may take before it fails, or 0 for the default.
+	GeoTimeout time.Duration
+	// DatacenterRanges holds the lists of the IP ranges of cloud and datacenter providers whose traffic is tagged.
+	Datacenters []iprange.List
+	// Blocklists holds the lists of IP ranges whose traffic is flagged, such as abuse lists.
+	Blocklists []iprange.List
+	// Offline skips the lookups and downloads that use the network, such as resolving hostnames.
+	Offline bool
+}
+
+// Webhook is a webhook, such as a Slack or Discord incoming webhook.
+type Webhook struct {
+	// URL is the address of the webhook, or empty for none.
+	URL string
+	// Format is the format of the payload, such as notify.Slack, or empty to detect it from the URL.
+	Format string
+}
+
+// Listings selects the full listings of every URL, site, and referrer, written to separate pages
+// linked from the report.
+type Listings struct {
+	// URLs lists all URL paths.
+	URLs bool
+	// Sites lists all visitor IP addresses.
+	Sites bool
+	// Referrers lists all referrers.
+	Referrers bool
+}
+
+// TopN holds the number of rows of each top-N table. A table with 0 rows is left out of the report.
+type TopN struct {
+	// URLs is the number of URL paths.
+	URLs int
+	// EntryPages is the number of entry pages.
+	EntryPages int
+	// ExitPages is the number of exit pages.
+	ExitPages int
+	// Sites is the number of sites.
+	Sites int
+	// Referrers is the number of referring sites.
+	Referrers int
+	// SearchStrings is the number of search strings.
+	SearchStrings int
+	// Agents is the number of user agents.
+	Agents int
+	// Countries is the number of countries.
+	Countries int
+}
+
+// DefaultTopN holds the table sizes of classic Webalizer.
+var DefaultTopN = TopN{
+	URLs:          30,
+	EntryPages:    10,
+	ExitPages:     10,
+	Sites:         30,
+	Referrers:     30,
+	SearchStrings: 20,
+	Agents:        15,
+	Countries:     30,
+}
+
+// LoadFile reads a configuration file: YAML if its name ends in .yaml or .yml, TOML if it ends in .toml,
+// and directives otherwise.
+func LoadFile(fileName string) (*Config, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return LoadYAML(file)
+	case ".toml":
+		return LoadTOML(file)
+	default:
+		return Load(file)
+	}
+}
+
+// Load reads configuration directives, one per line, in the form "Directive value [name]".
+// Empty lines and lines starting with "#" are skipped. Supported directives are:
+//
+//	LogFile        path            processes the log file at path, if no log files are given on the command line
+//	LogType        format          reads the logs in the format: clf or combined, or vhost_combined
+//	OutputDir      path            writes the report to the directory at path
+//	HostName       name            shows the host name of the site in the report titles, and counts referrers on it as internal
+//	ReportTitle    text            titles the report with the text followed by the host name, such as Statistics for
+//	VisitTimeout   seconds         starts a new visit when a visitor returns after the seconds, or a duration such as 30m
+//	Schedule       spec            processes the logs on the cron-style schedule, such as @hourly or 0 * * * *, until stopped
+//	TimeZone       name            counts the hits by day and hour in the time zone, such as Europe/Amsterdam or UTC
+//	GMTTime        yes|no          counts the hits by day and hour in UTC, like TimeZone UTC
+//	GroupURL       pattern [name]  groups URL paths
+//	GroupSite      pattern [name]  groups visitor IP addresses; pattern may be a subnet in CIDR notation
+//	GroupReferrer  pattern [name]  groups referrers
+//	IgnoreURL      pattern         leaves matching URL paths out of all statistics
+//	IgnoreSite     pattern         leaves matching visitor IP addresses out of all statistics
+//	IgnoreReferrer pattern         leaves matching referrers out of all statistics
+//	IgnoreAgent    pattern         leaves matching user agents out of all statistics
+//	IgnoreRobots   yes|no          leaves robots out of all statistics but the robots breakdown
+//	Anonymize      mode            anonymizes the IP addresses of visitors: truncate to their network, or hash them
+//	HideURL        pattern         leaves matching URL paths out of the top-N tables
+//	HideSite       pattern         leaves matching visitor IP addresses out of the top-N tables
+//	HideReferrer   pattern         leaves matching referrers out of the top-N tables
+//	HideAgent      pattern         leaves matching user agents out of the Top User Agents table
+//	PageType       ext             counts URL paths with the extension as pages, replacing the default extensions
+//	PageDirIndex   yes|no          counts URL paths ending in "/" as pages
+//	PageNoExt      yes|no          counts URL paths without an extension as pages
+//	PageInclude    regexp          counts matching URL paths as pages
+//	PageExclude    regexp          never counts matching URL paths as pages
+//	ExcludeMethod  method          counts requests with the method only in the methods breakdown
+//	TopURLs        n               shows n rows in the top URLs table, or leaves it out if 0
+//	TopEntry       n               shows n rows in the top entry pages table
+//	TopExit        n               shows n rows in the top exit pages table
+//	TopSites       n               shows n rows in the top sites table
+//	TopReferrers   n               shows n rows in the top referrers table
+//	TopSearch      n               shows n rows in the top search strings table
+//	TopAgents      n               shows n rows in the top user agents table
+//	TopCountries   n               shows n rows in the top countries table
+//	AllURLs        yes|no          lists all URL paths on separate pages
+//	AllSites       yes|no          lists all visitor IP addresses on separate pages
+//	AllReferrers   yes|no          lists all referrers on separate pages
+//	Theme          name            renders the report with the theme, such as dark
+//	Palette        colors...       colors the chart series with the colors, or with a named palette, such as pastel
+//	SeriesColor    color name      colors the chart series with the name, such as Hits, with the color
+//	ChartColumns   n               places n charts side by side on wide screens, instead of stacking them
+//	HideChart      title           leaves out the charts with the title, or whose title starts with it, such as Daily usage
+//	ChartSpan      n title         lets the charts with the title span n columns
+//	WebhookURL     url             posts a summary of the last day to the webhook after each run
+//	WebhookFormat  format          formats the summary for slack, discord, or as json, instead of detecting it from the URL
+//	CountryDB      path            looks up countries in the GeoLite2-Country, DB-IP, or IPinfo database at path
+//	GeoWorkers     n               runs n country lookups in parallel
+//	DNSChildren    n               runs n country lookups in parallel, like GeoWorkers
+//	GeoCache       path            keeps the countries looked up in the cache file at path between runs
+//	GeoCacheTTL    duration        looks up the countries in the cache file again after the duration, such as 168h
+//	GeoTimeout     duration        fails a country lookup that takes longer than the duration, such as 2s
+//	Datacenters    list [name]     tags the traffic from the IP ranges of a provider, such as aws, or in a file or at a URL
+//	Blocklist      list [name]     flags the traffic from the IP ranges of a blocklist, such as spamhaus-drop, or in a file or at a URL
+//	Offline        yes|no          skips the lookups and downloads that use the network, such as resolving hostnames
+//	MaxMindKey     key             downloads the GeoLite2 databases with the MaxMind license key if missing or outdated
+//	MaxMindAccount id              looks up the countries of IP addresses missing from the database in the MaxMind
+//	                               web service with the account ID and the license key
+//
+// The name is the rest of the line and may contain spaces. See group.NewRule for the pattern syntax, which is
+// that of Webalizer. A PageType ending in "*", such as htm*, matches the extensions starting with it.
+//
+// The directives of classic Webalizer that have no equivalent, such as Incremental or HTMLHead, are skipped
+// with a warning, so an existing webalizer.conf can be read as is.
+func Load(r io.Reader) (*Config, error) {
+	cfg := &Config{Top: DefaultTopN}
+
+	var reportTitle string
+	lineNr := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		directive, value := cutField(line)
+		pattern, name := cutField(value)
+		if unsupportedDirectives[strings.ToLower(directive)] {
+			slog.Warn("skipping unsupported Webalizer directive", "line", lineNr, "directive", directive)
+			continue
+		}
+		if pattern == "" {
+			return nil, fmt.Errorf("line %d: missing value for %s", lineNr, directive)
+		}
+
+		var err error
+		switch strings.ToLower(directive) {
+		case "logfile":
+			cfg.Input = append(cfg.Input, value)
+		case "logtype":
+			cfg.Format = strings.ToLower(pattern)
+			if cfg.Format == "clf" {
+				// The combined log format extends the common log format, and is read as such by Webalizer.
+				cfg.Format = "combined"
+			}
+		case "outputdir":
+			cfg.OutputDir = value
+		case "hostname":
+			cfg.HostName = value
+		case "reporttitle":
+			reportTitle = value
+		case "visittimeout":
+			cfg.VisitTimeout, err = parseSeconds(pattern)
+		case "schedule":
+			cfg.Schedule = value
+		case "timezone":
+			cfg.TimeZone = pattern
+		case "gmttime":
+			var utc bool
+			if utc, err = parseBool(pattern); utc {
+				cfg.TimeZone = "UTC"
+			}
+		case "groupurl":
+			err = addRule(&cfg.Groups.URLs, group.NewRule, pattern, name)
+		case "groupsite":
+			err = addRule(&cfg.Groups.Sites, group.NewSiteRule, pattern, name)
+		case "groupreferrer":
+			err = addRule(&cfg.Groups.Referrers, group.NewRule, pattern, name)
+		case "ignoreurl":
+			err = addRule(&cfg.Ignore.URLs, group.NewRule, pattern, name)
+		case "ignoresite":
+			err = addRule(&cfg.Ignore.Sites, group.NewSiteRule, pattern, name)
+		case "ignorereferrer":
+			err = addRule(&cfg.Ignore.Referrers, group.NewRule, pattern, name)
+		case "ignoreagent":
+			err = addRule(&cfg.Ignore.Agents, group.NewRule, pattern, name)
+		case "ignorerobots":
+			cfg.IgnoreRobots, err = parseBool(pattern)
+		case "anonymize":
+			cfg.Anonymize = strings.ToLower(pattern)
+		case "hideurl":
+			err = addRule(&cfg.Hide.URLs, group.NewRule, pattern, name)
+		case "hidesite":
+			err = addRule(&cfg.Hide.Sites, group.NewSiteRule, pattern, name)
+		case "hidereferrer":
+			err = addRule(&cfg.Hide.Referrers, group.NewRule, pattern, name)
+		case "hideagent":
+			err = addRule(&cfg.Hide.Agents, group.NewRule, pattern, name)
+		case "pagetype":
+			err = addPageType(&cfg.Pages, pattern)
+		case "pagedirindex":
+			cfg.Pages.DirectoryIndex, err = parseBool(pattern)
+		case "pagenoext":
+			cfg.Pages.Extensionless, err = parseBool(pattern)
+		case "pageinclude":
+			err = cfg.Pages.AddInclude(value)
+		case "pageexclude":
+			err = cfg.Pages.AddExclude(value)
+		case "excludemethod":
+			cfg.ExcludeMethods = append(cfg.ExcludeMethods, strings.ToUpper(pattern))
+		case "topurls":
+			cfg.Top.URLs, err = parseCount(pattern)
+		case "topentry":
+			cfg.Top.EntryPages, err = parseCount(pattern)
+		case "topexit":
+			cfg.Top.ExitPages, err = parseCount(pattern)
+		case "topsites":
+			cfg.Top.Sites, err = parseCount(pattern)
+		case "topreferrers":
+			cfg.Top.Referrers, err = parseCount(pattern)
+		case "topsearch":
+			cfg.Top.SearchStrings, err = parseCount(pattern)
+		case "topagents":
+			cfg.Top.Agents, err = parseCount(pattern)
+		case "topcountries":
+			cfg.Top.Countries, err = parseCount(pattern)
+		case "allurls":
+			cfg.All.URLs, err = parseBool(pattern)
+		case "allsites":
+			cfg.All.Sites, err = parseBool(pattern)
+		case "allreferrers":
+			cfg.All.Referrers, err = parseBool(pattern)
+		case "theme":
+			cfg.Theme = strings.ToLower(pattern)
+		case "palette":
+			cfg.Palette, err = parsePalette(value)
+		case "seriescolor":
+			err = addSeriesColor(cfg, pattern, name)
+		case "chartcolumns":
+			cfg.ChartColumns, err = parseCount(pattern)
+		case "hidechart":
+			cfg.HiddenCharts = append(cfg.HiddenCharts, value)
+		case "chartspan":
+			err = addChartSpan(cfg, pattern, name)
+		case "webhookurl":
+			cfg.Webhook.URL = pattern
+		case "webhookformat":
+			cfg.Webhook.Format = strings.ToLower(pattern)
+		case "countrydb":
+			cfg.CountryDB = value
+		case "geoworkers", "dnschildren":
+			cfg.GeoWorkers, err = parseCount(pattern)
+			if err == nil && cfg.GeoWorkers == 0 {
+				err = fmt.Errorf("invalid value %q, expected a number of 1 or more", pattern)
+			}
+		case "geocache":
+			cfg.GeoCache = value
+		case "geocachettl":
+			cfg.GeoCacheTTL, err = time.ParseDuration(pattern)
+			if err == nil && cfg.GeoCacheTTL <= 0 {
+				err = fmt.Errorf("invalid duration %q, expected a positive duration", pattern)
+			}
+		case "geotimeout":
+			cfg.GeoTimeout, err = time.ParseDuration(pattern)
+			if err == nil && cfg.GeoTimeout <= 0 {
+				err = fmt.Errorf("invalid duration %q, expected a positive duration", pattern)
+			}
+		case "datacenters":
+			cfg.Datacenters = append(cfg.Datacenters, parseList(pattern, name, iprange.Providers))
+		case "blocklist":
+			cfg.Blocklists = append(cfg.Blocklists, parseList(pattern, name, iprange.Blocklists))
+		case "offline":
+			cfg.Offline, err = parseBool(pattern)
+		case "maxmindaccount":
+			cfg.MaxMindAccountID = pattern
+		case "maxmindkey":
+			cfg.MaxMindLicenseKey = pattern
+		default:
+			err = fmt.Errorf("unknown directive %q", directive)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNr, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if reportTitle != "" {
+		// Like in Webalizer, the host name follows the title, wherever it is set.
+		cfg.Title = strings.TrimSpace(reportTitle + " " + cfg.HostName)
+	}
+
+	return cfg, nil
+}
+
+// addRule creates a rule with newRule and appends it to rules.
+func addRule(rules *group.Rules, newRule func(pattern, name string) (group.Rule, error), pattern, name string) error {
+	rule, err := newRule(pattern, name)
+	if err != nil {
+		return err
+	}
+	*rules = append(*rules, rule)
+	return nil
+}
+
+// parseList parses the list of IP ranges of a directive, named name if set. See iprange.ParseList.
+func parseList(value string, name string, known map[string]iprange.KnownList) iprange.List {
+	if name != "" {
+		return iprange.List{Name: name, Source: value}
+	}
+	return iprange.ParseList(value, known)
+}
+
+// unsupportedDirectives holds the directives of classic Webalizer, in lower case, that have no equivalent
+// and are skipped.
+var unsupportedDirectives = make(map[string]bool)
+
+func init() {
+	for _, directive := range strings.Fields(`
+		HistoryName Incremental IncrementalName HTMLExtension UseHTTPS DNSCache CacheIPs CacheTTL
+		Quiet ReallyQuiet TimeMe Debug FoldSeqErr IgnoreHist IgnoreState
+		CountryGraph CountryFlags FlagDir DailyGraph DailyStats HourlyGraph HourlyStats GraphLegend GraphLines
+		GraphMonths IndexMonths YearHeaders YearTotals
+		TopKSites TopKURLs TopUsers AllAgents AllSearchStr AllUsers IndexAlias DefaultIndex
+		HideUser HideAllSites GroupDomains GroupShading GroupHighlight GroupAgent GroupUser IgnoreUser
+		IncludeSite IncludeURL IncludeReferrer IncludeAgent IncludeUser MangleAgents SearchEngine SearchCaseI
+		DumpPath DumpHeader DumpExtension DumpSites DumpURLs DumpReferrers DumpAgents DumpUsers DumpSearchStr
+		HTMLHead HTMLBody HTMLPre HTMLPost HTMLTail HTMLEnd LinkReferrer
+		ColorHit ColorFile ColorSite ColorKbyte ColorPage ColorVisit ColorMisc PieColor1 PieColor2 PieColor3 PieColor4
+		GeoDB GeoDBDatabase GeoIP GeoIPDatabase StripCGI TrimSquidURL OmitPage`) {
+		unsupportedDirectives[strings.ToLower(directive)] = true
+	}
+}
+
+// parseSeconds parses a positive number of seconds, as used by Webalizer, or a duration, such as 30m.
+func parseSeconds(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if n, nerr := strconv.Atoi(value); nerr == nil {
+		d, err = time.Duration(n)*time.Second, nil
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid value %q, expected a positive number of seconds or duration", value)
+	}
+	return d, nil
+}
+
+// addPageType adds a page extension to rules. Like in Webalizer, an extension ending in "*" matches the
+// extensions starting with it.
+func addPageType(rules *pages.Rules, ext string) error {
+	prefix, ok := strings.CutSuffix(strings.TrimPrefix(ext, "."), "*")
+	if !ok {
+		rules.AddExtensions(ext)
+		return nil
+	}
+	// Adding no extensions still replaces the default extensions.
+	rules.AddExtensions()
+	return rules.AddInclude(`\.` + regexp.QuoteMeta(prefix) + `[^./]*$`)
+}
+
+// parseBool parses a yes/no directive value.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "true", "on":
+		return true, nil
+	case "no", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid value %q, expected yes or no", value)
+}
+
+// parseCount parses a non-negative number directive value.
+func parseCount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value %q, expected a number of 0 or more", value)
+	}
+	return n, nil
+}
+
+// colorPattern matches CSS colors in hex notation, such as #1f77b4, and color names, such as steelblue.
+var colorPattern = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|[a-zA-Z]+)$`)
+
+// parsePalette parses a list of colors, separated by spaces or commas. A single name may also select a
+// theme palette, which is resolved by the caller.
+func parsePalette(value string) ([]string, error) {
+	colors := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	for _, color := range colors {
+		if !colorPattern.MatchString(color) {
+			return nil, fmt.Errorf("invalid color %q", color)
+		}
+	}
+	return colors, nil
+}
+
+// addSeriesColor sets the color of the chart series with the name.
+func addSeriesColor(cfg *Config, color, name string) error {
+	if name == "" {
+		return fmt.Errorf("missing series name for color %q", color)
+	}
+	if !colorPattern.MatchString(color) {
+		return fmt.Errorf("invalid color %q", color)
+	}
+	if cfg.SeriesColors == nil {
+		cfg.SeriesColors = make(map[string]string)
+	}
+	cfg.SeriesColors[name] = color
+	return nil
+}
+
+// addChartSpan sets the number of columns the chart with the title spans.
+func addChartSpan(cfg *Config, span, title string) error {
+	if title == "" {
+		return fmt.Errorf("missing chart title for span %q", span)
+	}
+	n, err := strconv.Atoi(span)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid span %q, expected a number of 1 or more", span)
+	}
+	if cfg.ChartSpans == nil {
+		cfg.ChartSpans = make(map[string]int)
+	}
+	cfg.ChartSpans[title] = n
+	return nil
+}
+
+// cutField returns the first whitespace-separated field of s and the rest of s with surrounding whitespace removed.
+func cutField(s string) (string, string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	conf := `# webalizer.conf
+LogFile     /var/log/apache2/access.log
+LogType     clf
+OutputDir   /var/www/stats
+HostName    www.example.com
+ReportTitle Usage statistics for
+VisitTimeout 1800
+GMTTime     yes
+GroupURL    /blog/*   Blog
+GroupSite   10.0.0.0/8
+HideAgent   *Googlebot*
+IgnoreURL   re:^/healthz$
+ExcludeMethod options
+PageType    htm*
+TopURLs     50
+TopAgents   0
+AllURLs     yes
+Schedule    @hourly
+Quiet       yes
+`
+	cfg, err := Load(strings.NewReader(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Input", slices.Equal(cfg.Input, []string{"/var/log/apache2/access.log"}), true},
+		{"Format", cfg.Format, "combined"},
+		{"OutputDir", cfg.OutputDir, "/var/www/stats"},
+		{"Title", cfg.Title, "Usage statistics for www.example.com"},
+		{"VisitTimeout", cfg.VisitTimeout, 30 * time.Minute},
+		{"TimeZone", cfg.TimeZone, "UTC"},
+		{"GroupURL", cfg.Groups.URLs.Apply(map[string]uint64{"/blog/a": 1, "/blog/b": 2})["Blog"], uint64(3)},
+		{"GroupSite", len(cfg.Groups.Sites), 1},
+		{"HideAgent", cfg.Hide.Agents.Match("Mozilla/5.0 (compatible; Googlebot/2.1)"), true},
+		{"IgnoreURL", cfg.Ignore.URLs.Match("/healthz"), true},
+		{"ExcludeMethod", slices.Equal(cfg.ExcludeMethods, []string{"OPTIONS"}), true},
+		{"TopURLs", cfg.Top.URLs, 50},
+		{"TopAgents", cfg.Top.Agents, 0},
+		{"TopSites default", cfg.Top.Sites, DefaultTopN.Sites},
+		{"AllURLs", cfg.All.URLs, true},
+		{"Schedule", cfg.Schedule, "@hourly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		conf string
+	}{
+		{"unknown directive", "NoSuchDirective yes\n"},
+		{"missing value", "HostName\n"},
+		{"invalid boolean", "AllURLs maybe\n"},
+		{"invalid count", "TopURLs many\n"},
+		{"invalid timeout", "VisitTimeout -5\n"},
+		{"invalid regexp", "IgnoreURL re:(\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(strings.NewReader(tt.conf)); err == nil {
+				t.Errorf("Load(%q) succeeded, want an error", tt.conf)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rbscholtus/go-webalizer/internal/group"
+	"github.com/rbscholtus/go-webalizer/internal/iprange"
+)
+
+// file is the layout of YAML and TOML configuration files. Durations are strings, such as "30m".
+// Unset numbers are nil, so they keep their defaults.
+type file struct {
+	Input          []string      `yaml:"input" toml:"input"`
+	Format         string        `yaml:"format" toml:"format"`
+	OutputDir      string        `yaml:"output_dir" toml:"output_dir"`
+	HostName       string        `yaml:"host_name" toml:"host_name"`
+	Title          string        `yaml:"title" toml:"title"`
+	VisitTimeout   string        `yaml:"visit_timeout" toml:"visit_timeout"`
+	TimeZone       string        `yaml:"timezone" toml:"timezone"`
+	Schedule       string        `yaml:"schedule" toml:"schedule"`
+	Top            fileTopN      `yaml:"top" toml:"top"`
+	Group          fileGroups    `yaml:"group" toml:"group"`
+	Ignore         fileFilters   `yaml:"ignore" toml:"ignore"`
+	Hide           fileFilters   `yaml:"hide" toml:"hide"`
+	IgnoreRobots   bool          `yaml:"ignore_robots" toml:"ignore_robots"`
+	ExcludeMethods []string      `yaml:"exclude_methods" toml:"exclude_methods"`
+	Anonymize      string        `yaml:"anonymize" toml:"anonymize"`
+	Geo            fileGeoConfig `yaml:"geo" toml:"geo"`
+}
+
+// fileTopN holds the number of rows of the top-N tables in a configuration file.
+type fileTopN struct {
+	URLs          *int `yaml:"urls" toml:"urls"`
+	EntryPages    *int `yaml:"entry_pages" toml:"entry_pages"`
+	ExitPages     *int `yaml:"exit_pages" toml:"exit_pages"`
+	Sites         *int `yaml:"sites" toml:"sites"`
+	Referrers     *int `yaml:"referrers" toml:"referrers"`
+	SearchStrings *int `yaml:"search_strings" toml:"search_strings"`
+	Agents        *int `yaml:"agents" toml:"agents"`
+	Countries     *int `yaml:"countries" toml:"countries"`
+}
+
+// fileGroups holds the grouping rules in a configuration file.
+type fileGroups struct {
+	URLs      []fileRule `yaml:"urls" toml:"urls"`
+	Sites     []fileRule `yaml:"sites" toml:"sites"`
+	Referrers []fileRule `yaml:"referrers" toml:"referrers"`
+}
+
+// fileRule is a grouping rule in a configuration file.
+type fileRule struct {
+	Pattern string `yaml:"pattern" toml:"pattern"`
+	Name    string `yaml:"name" toml:"name"`
+}
+
+// fileFilters holds the patterns of the ignore or hide rules in a configuration file.
+type fileFilters struct {
+	URLs      []string `yaml:"urls" toml:"urls"`
+	Sites     []string `yaml:"sites" toml:"sites"`
+	Referrers []string `yaml:"referrers" toml:"referrers"`
+	Agents    []string `yaml:"agents" toml:"agents"`
+}
+
+// fileGeoConfig holds the settings of the country lookups in a configuration file.
+type fileGeoConfig struct {
+	CountryDB         string   `yaml:"country_db" toml:"country_db"`
+	Workers           *int     `yaml:"workers" toml:"workers"`
+	Cache             string   `yaml:"cache" toml:"cache"`
+	CacheTTL          string   `yaml:"cache_ttl" toml:"cache_ttl"`
+	Timeout           string   `yaml:"timeout" toml:"timeout"`
+	Offline           bool     `yaml:"offline" toml:"offline"`
+	Datacenters       []string `yaml:"datacenters" toml:"datacenters"`
+	Blocklists        []string `yaml:"blocklists" toml:"blocklists"`
+	MaxMindLicenseKey string   `yaml:"maxmind_license_key" toml:"maxmind_license_key"`
+	MaxMindAccountID  string   `yaml:"maxmind_account_id" toml:"maxmind_account_id"`
+}
+
+// LoadYAML reads a YAML configuration file, such as:
+//
+//	input: [/var/log/apache2/access.log]
+//	format: combined
+//	output_dir: /var/www/stats
+//	host_name: www.example.com
+//	title: Statistics of the Example site
+//	visit_timeout: 30m
+//	timezone: Europe/Amsterdam
+//	top:
+//	  urls: 50
+//	  sites: 20
+//	group:
+//	  urls:
+//	    - {pattern: /blog/*, name: Blog}
+//	ignore:
+//	  sites: [10.0.0.0/8]
+//	  agents: [UptimeRobot*]
+//	hide:
+//	  urls: ["*.css"]
+//	geo:
+//	  country_db: GeoLite2-Country.mmdb
+//	  cache: countries.tsv
+//	  timeout: 2s
+//
+// Besides these, the file may hold schedule, ignore_robots, exclude_methods, anonymize, and the geo settings
+// workers, cache_ttl, offline, datacenters, blocklists, maxmind_license_key, and maxmind_account_id, which mean
+// what the directives of the same names mean to Load. Unknown settings are an error.
+func LoadYAML(r io.Reader) (*Config, error) {
+	var f file
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return f.config()
+}
+
+// LoadTOML reads a TOML configuration file, holding the settings of LoadYAML, such as:
+//
+//	input = ["/var/log/apache2/access.log"]
+//	output_dir = "/var/www/stats"
+//	visit_timeout = "30m"
+//
+//	[top]
+//	urls = 50
+//
+//	[[group.urls]]
+//	pattern = "/blog/*"
+//	name = "Blog"
+//
+//	[ignore]
+//	sites = ["10.0.0.0/8"]
+func LoadTOML(r io.Reader) (*Config, error) {
+	var f file
+	md, err := toml.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown setting %q", undecoded[0].String())
+	}
+	return f.config()
+}
+
+// config converts the settings of a configuration file into a Config, validating them as Load does.
+func (f *file) config() (*Config, error) {
+	cfg := &Config{
+		Input:             f.Input,
+		Format:            f.Format,
+		OutputDir:         f.OutputDir,
+		HostName:          f.HostName,
+		Title:             f.Title,
+		TimeZone:          f.TimeZone,
+		Schedule:          f.Schedule,
+		Top:               DefaultTopN,
+		IgnoreRobots:      f.IgnoreRobots,
+		Anonymize:         strings.ToLower(f.Anonymize),
+		CountryDB:         f.Geo.CountryDB,
+		GeoCache:          f.Geo.Cache,
+		Offline:           f.Geo.Offline,
+		MaxMindLicenseKey: f.Geo.MaxMindLicenseKey,
+		MaxMindAccountID:  f.Geo.MaxMindAccountID,
+	}
+	for _, method := range f.ExcludeMethods {
+		cfg.ExcludeMethods = append(cfg.ExcludeMethods, strings.ToUpper(method))
+	}
+
+	var err error
+	if cfg.VisitTimeout, err = parseDuration("visit_timeout", f.VisitTimeout); err != nil {
+		return nil, err
+	}
+	if cfg.GeoCacheTTL, err = parseDuration("geo.cache_ttl", f.Geo.CacheTTL); err != nil {
+		return nil, err
+	}
+	if cfg.GeoTimeout, err = parseDuration("geo.timeout", f.Geo.Timeout); err != nil {
+		return nil, err
+	}
+	if f.Geo.Workers != nil {
+		if *f.Geo.Workers < 1 {
+			return nil, fmt.Errorf("geo.workers: invalid value %d, expected a number of 1 or more", *f.Geo.Workers)
+		}
+		cfg.GeoWorkers = *f.Geo.Workers
+	}
+
+	for name, top := range map[string]struct {
+		from *int
+		to   *int
+	}{
+		"urls":           {f.Top.URLs, &cfg.Top.URLs},
+		"entry_pages":    {f.Top.EntryPages, &cfg.Top.EntryPages},
+		"exit_pages":     {f.Top.ExitPages, &cfg.Top.ExitPages},
+		"sites":          {f.Top.Sites, &cfg.Top.Sites},
+		"referrers":      {f.Top.Referrers, &cfg.Top.Referrers},
+		"search_strings": {f.Top.SearchStrings, &cfg.Top.SearchStrings},
+		"agents":         {f.Top.Agents, &cfg.Top.Agents},
+		"countries":      {f.Top.Countries, &cfg.Top.Countries},
+	} {
+		if top.from == nil {
+			continue
+		}
+		if *top.from < 0 {
+			return nil, fmt.Errorf("top.%s: invalid value %d, expected a number of 0 or more", name, *top.from)
+		}
+		*top.to = *top.from
+	}
+
+	for _, rules := range []struct {
+		name    string
+		rules   *group.Rules
+		newRule func(pattern, name string) (group.Rule, error)
+		from    []fileRule
+	}{
+		{"group.urls", &cfg.Groups.URLs, group.NewRule, f.Group.URLs},
+		{"group.sites", &cfg.Groups.Sites, group.NewSiteRule, f.Group.Sites},
+		{"group.referrers", &cfg.Groups.Referrers, group.NewRule, f.Group.Referrers},
+	} {
+		for _, rule := range rules.from {
+			if err := addRule(rules.rules, rules.newRule, rule.Pattern, rule.Name); err != nil {
+				return nil, fmt.Errorf("%s: %v", rules.name, err)
+			}
+		}
+	}
+	if err := addFilters(&cfg.Ignore, "ignore", f.Ignore); err != nil {
+		return nil, err
+	}
+	if err := addFilters(&cfg.Hide, "hide", f.Hide); err != nil {
+		return nil, err
+	}
+
+	for _, list := range f.Geo.Datacenters {
+		cfg.Datacenters = append(cfg.Datacenters, iprange.ParseList(list, iprange.Providers))
+	}
+	for _, list := range f.Geo.Blocklists {
+		cfg.Blocklists = append(cfg.Blocklists, iprange.ParseList(list, iprange.Blocklists))
+	}
+	return cfg, nil
+}
+
+// addFilters adds the rules of the patterns in from to filters. name is the setting holding them, for errors.
+func addFilters(filters *group.Filters, name string, from fileFilters) error {
+	for _, patterns := range []struct {
+		name     string
+		rules    *group.Rules
+		newRule  func(pattern, name string) (group.Rule, error)
+		patterns []string
+	}{
+		{"urls", &filters.URLs, group.NewRule, from.URLs},
+		{"sites", &filters.Sites, group.NewSiteRule, from.Sites},
+		{"referrers", &filters.Referrers, group.NewRule, from.Referrers},
+		{"agents", &filters.Agents, group.NewRule, from.Agents},
+	} {
+		for _, pattern := range patterns.patterns {
+			if err := addRule(patterns.rules, patterns.newRule, pattern, ""); err != nil {
+				return fmt.Errorf("%s.%s: %v", name, patterns.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseDuration parses the positive duration of the setting name, or returns 0 if value is empty.
+func parseDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("invalid duration %q, expected a positive duration", value)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", name, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadStructured(t *testing.T) {
+	tests := []struct {
+		name string
+		load func(io.Reader) (*Config, error)
+		conf string
+	}{
+		{"YAML", LoadYAML, `
+input: [/var/log/apache2/access.log]
+format: combined
+output_dir: /var/www/stats
+visit_timeout: 30m
+top:
+  urls: 50
+  agents: 0
+group:
+  urls:
+    - {pattern: /blog/*, name: Blog}
+ignore:
+  sites: [10.0.0.0/8]
+`},
+		{"TOML", LoadTOML, `
+input = ["/var/log/apache2/access.log"]
+format = "combined"
+output_dir = "/var/www/stats"
+visit_timeout = "30m"
+
+[top]
+urls = 50
+agents = 0
+
+[[group.urls]]
+pattern = "/blog/*"
+name = "Blog"
+
+[ignore]
+sites = ["10.0.0.0/8"]
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.load(strings.NewReader(tt.conf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.Input, []string{"/var/log/apache2/access.log"}) {
+				t.Errorf("Input = %v", cfg.Input)
+			}
+			if cfg.Format != "combined" || cfg.OutputDir != "/var/www/stats" {
+				t.Errorf("Format = %q, OutputDir = %q", cfg.Format, cfg.OutputDir)
+			}
+			if cfg.VisitTimeout != 30*time.Minute {
+				t.Errorf("VisitTimeout = %v, want 30m", cfg.VisitTimeout)
+			}
+			if cfg.Top.URLs != 50 || cfg.Top.Agents != 0 || cfg.Top.Sites != DefaultTopN.Sites {
+				t.Errorf("Top = %+v", cfg.Top)
+			}
+			if name, ok := cfg.Groups.URLs.Group("/blog/a.html"); !ok || name != "Blog" {
+				t.Errorf("Groups.URLs.Group() = %q, %v, want Blog", name, ok)
+			}
+			if !cfg.Ignore.Sites.Match("10.1.2.3") {
+				t.Errorf("Ignore.Sites does not match 10.1.2.3")
+			}
+		})
+	}
+}
+
+func TestLoadStructuredInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		load func(io.Reader) (*Config, error)
+		conf string
+	}{
+		{"YAML unknown setting", LoadYAML, "outputdir: /tmp\n"},
+		{"YAML invalid duration", LoadYAML, "visit_timeout: soon\n"},
+		{"TOML unknown setting", LoadTOML, "outputdir = \"/tmp\"\n"},
+		{"TOML invalid regexp", LoadTOML, "[ignore]\nurls = [\"re:(\"]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.load(strings.NewReader(tt.conf)); err == nil {
+				t.Errorf("load(%q) succeeded, want an error", tt.conf)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package countrycache
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadCache adds the countries in the cache file at path, written by SaveCache, that were looked up less than
+// ttl ago, so they are not looked up again. A missing file is not an error, as it is written by the first run.
+func (cl *CountryLookup) LoadCache(path string, ttl time.Duration) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	lineNr := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNr++
+		fields := strings.Split(scanner.Text(), "\t")
+		// The country code was added as a fourth field, so files written before lack it.
+		if len(fields) != 3 && len(fields) != 4 {
+			return fmt.Errorf("%s:%d: expected visitor, country, time, and country code separated by tabs", path, lineNr)
+		}
+		unix, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid time %q", path, lineNr, fields[2])
+		}
+		lookedUp := time.Unix(unix, 0)
+		if time.Since(lookedUp) >= ttl {
+			continue
+		}
+		cl.countries[fields[0]] = fields[1]
+		cl.lookedUp[fields[0]] = lookedUp
+		if len(fields) == 4 && fields[3] != "" {
+			cl.codes[fields[1]] = fields[3]
+		}
+	}
+	return scanner.Err()
+}
+
+// SaveCache writes the countries that were looked up successfully, or loaded from the cache, to the cache file
+// at path, one visitor per line with its country, the Unix time it was looked up, and the ISO 3166 code of the
+// country, separated by tabs.
+// The file is replaced atomically.
+func (cl *CountryLookup) SaveCache(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	cl.mu.RLock()
+	for visitor, lookedUp := range cl.lookedUp {
+		country := cl.countries[visitor]
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", visitor, country, lookedUp.Unix(), cl.codes[country])
+	}
+	cl.mu.RUnlock()
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -1,23 +1,53 @@
-// Package countrycache provides a concurrent country lookup service using the MaxMind GeoIP2 database.
+// Package countrycache provides a concurrent country lookup service using a country database in the MaxMind DB
+// format, such as MaxMind GeoLite2-Country, DB-IP IP to Country, or IPinfo Country.
 package countrycache
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
+	"os"
+	"slices"
 	"sync"
+	"time"
 
-	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+//go:generate sh -c "curl -sSfL https://download.db-ip.com/free/dbip-country-lite-$(date +%Y-%m).mmdb.gz | gunzip > fallback/country.mmdb"
+
+// Countries of visitors that cannot be placed in a country.
+const (
+	// Unknown is the country of visitors whose IP address is not in the database.
+	Unknown = "Unknown"
+	// Unresolved is the country of visitors whose lookup failed, such as hostnames that cannot be resolved.
+	Unresolved = "Unresolved"
 )
 
 // CountryLookup represents a country lookup service.
 type CountryLookup struct {
-	// db is the underlying GeoIP2 database reader.
-	db *geoip2.Reader
+	// db is the underlying country database reader.
+	db *maxminddb.Reader
 	// countries is a map of visitor countries, where the key is the visitor IP or hostname and the value is the country name.
 	countries map[string]string
+	// codes is a map of the ISO 3166 codes of the countries in the countries map, keyed by country name.
+	codes map[string]string
+	// lookedUp holds the time each visitor in the countries map was looked up, for the visitors whose lookup
+	// succeeded. Only these are saved to the cache file.
+	lookedUp map[string]time.Time
+	// failures is the number of visitors whose lookup failed.
+	failures int
 	// numWorkers is the number of worker goroutines used for parallel lookups.
 	numWorkers int
-	// mu is a read-write mutex protecting access to the countries map.
+	// timeout is the time a single lookup may take, resolving a hostname, before it fails.
+	timeout time.Duration
+	// offline is set if hostnames are not resolved.
+	offline bool
+	// fallback looks up the IP addresses that are not in the database, or is nil.
+	fallback *WebService
+	// mu is a read-write mutex protecting access to the countries, codes, and lookedUp maps and failures.
 	mu *sync.RWMutex
 }
 
@@ -27,13 +57,84 @@ type result struct {
 	visitor string
 	// country is the country name.
 	country string
+	// code is the ISO 3166 code of the country, if known.
+	code string
+	// err is the error of the lookup, if it failed.
+	err error
+}
+
+// record is a country database record. The databases of MaxMind and DB-IP hold the country as a map with
+// the names by language and the ISO code, as do the responses of the MaxMind web service. The IPinfo databases
+// hold the country name as a string, in country_name if country holds the country code, or in country if
+// country_code holds the code.
+type record struct {
+	// Country is the country of the IP address, as a map or a string.
+	Country any `maxminddb:"country" json:"country"`
+	// RegisteredCountry is the country the network is registered in, as a map, used if Country is missing.
+	RegisteredCountry any `maxminddb:"registered_country" json:"registered_country"`
+	// CountryName is the country name in IPinfo databases that hold the country code in Country.
+	CountryName string `maxminddb:"country_name" json:"-"`
+	// CountryCode is the country code in IPinfo databases that hold the country name in Country.
+	CountryCode string `maxminddb:"country_code" json:"-"`
+}
+
+// name returns the English country name of the record, or an empty string if it holds no country.
+func (r *record) name() string {
+	if r.CountryName != "" {
+		return r.CountryName
+	}
+	for _, country := range []any{r.Country, r.RegisteredCountry} {
+		switch c := country.(type) {
+		case string:
+			return c
+		case map[string]any:
+			names, _ := c["names"].(map[string]any)
+			if name, ok := names["en"].(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// code returns the ISO 3166 code of the country of the record, or an empty string if it holds none.
+func (r *record) code() string {
+	if r.CountryCode != "" {
+		return r.CountryCode
+	}
+	if c, ok := r.Country.(string); ok && r.CountryName != "" {
+		return c
+	}
+	for _, country := range []any{r.Country, r.RegisteredCountry} {
+		if c, ok := country.(map[string]any); ok {
+			if code, ok := c["iso_code"].(string); ok {
+				return code
+			}
+		}
+	}
+	return ""
+}
+
+// Available reports whether countries can be looked up with the database dbPath: if it exists, or the
+// fallback database is embedded.
+func Available(dbPath string) bool {
+	if fallbackDB != nil {
+		return true
+	}
+	_, err := os.Stat(dbPath)
+	return err == nil
 }
 
 // NewCountryLookup returns a new CountryLookup instance.
-// dbPath is the path to the GeoIP2 database file.
+// dbPath is the path to the country database file. If it does not exist, the embedded fallback database is used, if any.
 // numWorkers is the number of worker goroutines to use for parallel lookups.
-func NewCountryLookup(dbPath string, numWorkers int) (*CountryLookup, error) {
-	db, err := geoip2.Open(dbPath)
+// timeout is the time a single lookup may take before it fails, so a slow DNS resolver cannot stall the lookups.
+func NewCountryLookup(dbPath string, numWorkers int, timeout time.Duration) (*CountryLookup, error) {
+	db, err := maxminddb.Open(dbPath)
+	if errors.Is(err, os.ErrNotExist) && fallbackDB != nil {
+		slog.Warn("country database not found, using the embedded fallback database", "path", dbPath)
+		db, err = maxminddb.FromBytes(fallbackDB)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -41,44 +142,88 @@ func NewCountryLookup(dbPath string, numWorkers int) (*CountryLookup, error) {
 	cl := &CountryLookup{
 		db:         db,
 		countries:  make(map[string]string),
+		codes:      make(map[string]string),
+		lookedUp:   make(map[string]time.Time),
 		numWorkers: numWorkers,
+		timeout:    timeout,
 		mu:         &sync.RWMutex{},
 	}
 
 	return cl, nil
 }
 
-// Close closes the underlying GeoIP2 database.
+// errOffline is the error of the lookups of hostnames in offline mode.
+var errOffline = errors.New("hostname not resolved in offline mode")
+
+// SetOffline selects whether hostnames are left unresolved, so no lookup uses the network. The countries of
+// hostnames are then Unresolved, without counting them as failures.
+func (cl *CountryLookup) SetOffline(offline bool) {
+	cl.offline = offline
+}
+
+// SetFallback selects the web service the IP addresses that are not in the database are looked up in,
+// or nil for none. It is not used in offline mode.
+func (cl *CountryLookup) SetFallback(fallback *WebService) {
+	cl.fallback = fallback
+}
+
+// Close closes the underlying country database.
 func (cl *CountryLookup) Close() error {
 	return cl.db.Close()
 }
 
-// lookupCountry performs a country lookup for a single visitor.
-// visitor is the visitor IP or hostname.
-func (cl *CountryLookup) lookupCountry(visitor string) (string, error) {
+// lookupCountry performs a country lookup for a single visitor, returning Unknown if it is not in the database.
+// visitor is the visitor IP or hostname. Hostnames are resolved within the timeout of the lookup.
+// Returns the country name and its ISO 3166 code, if known.
+func (cl *CountryLookup) lookupCountry(ctx context.Context, visitor string) (string, string, error) {
 	var ip net.IP
 	if parsedIP := net.ParseIP(visitor); parsedIP != nil {
 		ip = parsedIP
+	} else if cl.offline {
+		return "", "", errOffline
 	} else {
-		ips, err := net.LookupIP(visitor)
-		if err != nil || len(ips) == 0 {
-			return "", err
+		ctx, cancel := context.WithTimeout(ctx, cl.timeout)
+		defer cancel()
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", visitor)
+		if err != nil {
+			return "", "", err
+		}
+		if len(ips) == 0 {
+			return "", "", fmt.Errorf("no IP addresses for %s", visitor)
 		}
 		ip = ips[0]
 	}
 
-	record, err := cl.db.Country(ip)
-	if err != nil {
-		return "", err
+	var r record
+	if err := cl.db.Lookup(ip, &r); err != nil {
+		return "", "", err
+	}
+	if r.name() == "" && cl.fallback != nil && !cl.offline {
+		// A failing web service leaves the IP address Unknown rather than failing its lookup.
+		if _, err := cl.fallback.lookup(ctx, ip, &r); err != nil {
+			slog.Warn("web service lookup error", "ip", ip, "error", err)
+		}
 	}
 
-	return record.Country.Names["en"], nil
+	name := r.name()
+	if name == "" {
+		return Unknown, "", nil
+	}
+	return name, r.code(), nil
 }
 
 // ParallelLookup performs parallel country lookups for a list of visitors.
-// visitors is a slice of visitor IPs or hostnames.
-// The results are stored in the countries map.
-func (cl *CountryLookup) ParallelLookup(visitors []string) {
+// visitors is a slice of visitor IPs or hostnames. Visitors whose country is cached already are skipped.
+// The results are stored in the countries map. If ctx is canceled, the remaining visitors are not looked up
+// and the error of ctx is returned.
+func (cl *CountryLookup) ParallelLookup(ctx context.Context, visitors []string) error {
+	cl.mu.RLock()
+	visitors = slices.DeleteFunc(slices.Clone(visitors), func(visitor string) bool {
+		_, ok := cl.countries[visitor]
+		return ok
+	})
+	cl.mu.RUnlock()
+
 	// workChan is a channel for feeding work to the worker goroutines.
 	workChan := make(chan string)
 	// resultChan is a buffered channel for collecting results from the worker goroutines.
@@ -92,12 +237,12 @@ func (cl *CountryLookup) ParallelLookup(visitors []string) {
 		go func() {
 			defer wg.Done()
 			for visitor := range workChan {
-				country, err := cl.lookupCountry(visitor)
-				if err != nil {
-					slog.Warn("lookup error", "error", err)
-					country = "Vietnam"
+				country, code, err := cl.lookupCountry(ctx, visitor)
+				if ctx.Err() != nil {
+					// Canceled lookups are left out rather than counted as failures.
+					continue
 				}
-				resultChan <- result{visitor, country}
+				resultChan <- result{visitor, country, code, err}
 			}
 		}()
 	}
@@ -105,10 +250,14 @@ func (cl *CountryLookup) ParallelLookup(visitors []string) {
 	// Feed work to the worker goroutines.
 	slog.Info("Looking up visitors", "count", len(visitors))
 	go func() {
+		defer close(workChan)
 		for _, visitor := range visitors {
-			workChan <- visitor
+			select {
+			case workChan <- visitor:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(workChan)
 	}()
 
 	// Collect results in a separate goroutine.
@@ -117,9 +266,7 @@ func (cl *CountryLookup) ParallelLookup(visitors []string) {
 	go func() {
 		defer wg2.Done()
 		for r := range resultChan {
-			cl.mu.Lock()
-			cl.countries[r.visitor] = r.country
-			cl.mu.Unlock()
+			cl.store(r)
 		}
 	}()
 
@@ -130,6 +277,8 @@ func (cl *CountryLookup) ParallelLookup(visitors []string) {
 	close(resultChan)
 	// Wait for the results collection goroutine to finish.
 	wg2.Wait()
+
+	return ctx.Err()
 }
 
 // Lookup returns the country of a visitor.
@@ -141,3 +290,60 @@ func (cl *CountryLookup) Lookup(visitor string) (string, bool) {
 	ret, ok := cl.countries[visitor]
 	return ret, ok
 }
+
+// LookupOne returns the country of a visitor, looking it up in the database if it is not cached yet.
+// visitor is the visitor IP or hostname.
+// Returns the country name, or Unknown or Unresolved, and a boolean indicating whether the country was found.
+func (cl *CountryLookup) LookupOne(ctx context.Context, visitor string) (string, bool) {
+	if country, ok := cl.Lookup(visitor); ok {
+		return country, found(country)
+	}
+	country, code, err := cl.lookupCountry(ctx, visitor)
+	country = cl.store(result{visitor, country, code, err})
+	return country, found(country)
+}
+
+// store stores the result of a lookup in the countries map and returns the country of the visitor.
+// Failed lookups are stored as Unresolved and counted as failures, unless they failed in offline mode.
+func (cl *CountryLookup) store(r result) string {
+	if r.err != nil {
+		if !errors.Is(r.err, errOffline) {
+			slog.Warn("lookup error", "error", r.err)
+		}
+		r.country = Unresolved
+	}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.countries[r.visitor] = r.country
+	if r.code != "" {
+		cl.codes[r.country] = r.code
+	}
+	switch {
+	case r.err == nil:
+		cl.lookedUp[r.visitor] = time.Now()
+	case !errors.Is(r.err, errOffline):
+		cl.failures++
+	}
+	return r.country
+}
+
+// Code returns the ISO 3166 code of a country returned by Lookup.
+// It returns false if the code of the country is not known, such as for Unknown and Unresolved.
+func (cl *CountryLookup) Code(country string) (string, bool) {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	code, ok := cl.codes[country]
+	return code, ok
+}
+
+// Failures returns the number of visitors whose lookup failed, counted as Unresolved.
+func (cl *CountryLookup) Failures() int {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.failures
+}
+
+// found reports whether country is a country, rather than Unknown or Unresolved.
+func found(country string) bool {
+	return country != "" && country != Unknown && country != Unresolved
+}
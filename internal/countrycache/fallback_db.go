@@ -0,0 +1,11 @@
+//go:build fallbackdb
+
+package countrycache
+
+import _ "embed"
+
+// fallbackDB is the coarse country database used when the database file does not exist. It is downloaded
+// by go generate, and the build fails if it was not.
+//
+//go:embed fallback/country.mmdb
+var fallbackDB []byte
@@ -0,0 +1,8 @@
+//go:build !fallbackdb
+
+package countrycache
+
+// fallbackDB is nil without the fallbackdb build tag: the binary embeds no fallback database, and countries
+// are only looked up if the database file exists. Build with make, or with -tags fallbackdb after go
+// generate, to embed it.
+var fallbackDB []byte
@@ -0,0 +1,143 @@
+package countrycache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webServiceURL is the address of the MaxMind GeoIP2 Precision Country web service, followed by the IP address.
+const webServiceURL = "https://geoip.maxmind.com/geoip/v2.1/country/"
+
+// WebServiceRate is the default number of queries per second sent to the web service.
+const WebServiceRate = 10
+
+// webServiceTimeout is the time a query to the web service may take.
+const webServiceTimeout = 10 * time.Second
+
+// WebService looks up the countries of IP addresses that are not in the country database in the MaxMind
+// GeoIP2 Precision web service, which is paid per query. Queries are spread out to a rate, and stop for the
+// rest of the run once the account is refused, such as when it is out of queries.
+type WebService struct {
+	// accountID and licenseKey are the credentials of the MaxMind account.
+	accountID, licenseKey string
+	// client sends the queries.
+	client *http.Client
+	// interval is the time between two queries.
+	interval time.Duration
+	// mu protects next and disabled.
+	mu sync.Mutex
+	// next is the time the next query may be sent.
+	next time.Time
+	// disabled is set once the account was refused.
+	disabled bool
+}
+
+// NewWebService returns a WebService querying with the MaxMind account ID and license key, at most rate
+// times per second.
+func NewWebService(accountID string, licenseKey string, rate int) *WebService {
+	return &WebService{
+		accountID:  accountID,
+		licenseKey: licenseKey,
+		client:     &http.Client{Timeout: webServiceTimeout},
+		interval:   time.Second / time.Duration(max(rate, 1)),
+	}
+}
+
+// lookup looks up ip in the web service into r. It returns false if ip is not in the web service, is not
+// public, or the web service is disabled.
+func (ws *WebService) lookup(ctx context.Context, ip net.IP, r *record) (bool, error) {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return false, nil
+	}
+	if err := ws.wait(ctx); errors.Is(err, errDisabled) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, webServiceURL+ip.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(ws.accountID, ws.licenseKey)
+	req.Header.Set("Accept", "application/json")
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return true, json.NewDecoder(resp.Body).Decode(r)
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusPaymentRequired,
+		resp.StatusCode == http.StatusForbidden:
+		ws.disable(webServiceError(resp))
+		return false, nil
+	default:
+		return false, webServiceError(resp)
+	}
+}
+
+// wait waits until the next query may be sent, and returns an error if the web service is disabled
+// or ctx is canceled.
+func (ws *WebService) wait(ctx context.Context) error {
+	ws.mu.Lock()
+	if ws.disabled {
+		ws.mu.Unlock()
+		return errDisabled
+	}
+	now := time.Now()
+	at := ws.next
+	if at.Before(now) {
+		at = now
+	}
+	ws.next = at.Add(ws.interval)
+	ws.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// errDisabled is the error of the queries after the web service was disabled.
+var errDisabled = errors.New("the MaxMind web service refused the account")
+
+// disable stops the queries for the rest of the run, warning about the cause once.
+func (ws *WebService) disable(cause error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if !ws.disabled {
+		ws.disabled = true
+		slog.Warn("stopped the MaxMind web service lookups", "error", cause)
+	}
+}
+
+// webServiceError returns the error of a response with the code and message of the web service, if any.
+func webServiceError(resp *http.Response) error {
+	var body struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &body) == nil && body.Code != "" {
+		return fmt.Errorf("%s: %s: %s", resp.Status, body.Code, body.Error)
+	}
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+}
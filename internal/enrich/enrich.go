@@ -0,0 +1,166 @@
+// Package enrich looks up the country, city, and autonomous system of the IP addresses of log entries in
+// MaxMind DB databases, in one pass per IP address, for the parser to add to the entries it hands to entry sinks.
+package enrich
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/asncache"
+	"github.com/rbscholtus/go-webalizer/internal/citycache"
+	"github.com/rbscholtus/go-webalizer/internal/countrycache"
+)
+
+// Geo is what the enrichers know of an IP address. The fields of lookups that are not run are empty.
+type Geo struct {
+	// Country is the country name, or countrycache.Unknown or countrycache.Unresolved.
+	Country string
+	// CountryCode is the ISO 3166 code of the country, if known.
+	CountryCode string
+	// City is the city in the form "City, Region, Country", if known.
+	City string
+	// Lat and Lon are the latitude and longitude of the city, if known.
+	Lat, Lon float64
+	// ASN is the autonomous system in the form "AS15169 Google LLC", if known.
+	ASN string
+}
+
+// Enricher adds what it knows of an IP address or hostname to geo.
+type Enricher interface {
+	// Enrich sets the fields of geo it looks up for ip.
+	Enrich(ctx context.Context, ip string, geo *Geo)
+	// Close releases the databases of the enricher.
+	Close() error
+}
+
+// Options selects the databases the IP addresses are looked up in.
+type Options struct {
+	// CountryDB is the country database, or empty to leave out countries.
+	CountryDB string
+	// CityDB is the GeoLite2-City database, or empty to leave out cities.
+	CityDB string
+	// ASNDB is the GeoLite2-ASN database, or empty to leave out autonomous systems.
+	ASNDB string
+	// Timeout is the time the country lookup of a hostname may take before it fails.
+	Timeout time.Duration
+	// Offline leaves hostnames unresolved, so the lookups do not use the network.
+	Offline bool
+	// Fallback looks up the IP addresses that are not in the country database, or is nil.
+	Fallback *countrycache.WebService
+}
+
+// Pipeline is an Enricher that runs the lookups of several enrichers, once per IP address.
+type Pipeline struct {
+	// enrichers are the enrichers run for each IP address.
+	enrichers []Enricher
+	// cache is a map of what is known of the IP addresses looked up, keyed by IP address.
+	cache map[string]Geo
+}
+
+// NewPipeline returns a Pipeline running the enrichers.
+func NewPipeline(enrichers ...Enricher) *Pipeline {
+	return &Pipeline{enrichers: enrichers, cache: make(map[string]Geo)}
+}
+
+// Open returns a Pipeline looking up IP addresses in the databases selected by opts. A country database that
+// cannot be opened is skipped with a warning, leaving out the countries as the reports do.
+func Open(opts Options) (*Pipeline, error) {
+	p := NewPipeline()
+	if opts.CountryDB != "" {
+		cl, err := countrycache.NewCountryLookup(opts.CountryDB, 1, opts.Timeout)
+		if err != nil {
+			slog.Warn("skipping the country lookups of log entries", "error", err)
+		} else {
+			cl.SetOffline(opts.Offline)
+			cl.SetFallback(opts.Fallback)
+			p.enrichers = append(p.enrichers, countryEnricher{cl})
+		}
+	}
+	if opts.CityDB != "" {
+		cl, err := citycache.NewCityLookup(opts.CityDB)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.enrichers = append(p.enrichers, cityEnricher{cl})
+	}
+	if opts.ASNDB != "" {
+		al, err := asncache.NewASNLookup(opts.ASNDB)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.enrichers = append(p.enrichers, asnEnricher{al})
+	}
+	return p, nil
+}
+
+// Enrich sets geo to what the enrichers of the pipeline know of ip, running them only the first time ip is seen.
+func (p *Pipeline) Enrich(ctx context.Context, ip string, geo *Geo) {
+	if cached, ok := p.cache[ip]; ok {
+		*geo = cached
+		return
+	}
+	*geo = Geo{}
+	for _, e := range p.enrichers {
+		e.Enrich(ctx, ip, geo)
+	}
+	p.cache[ip] = *geo
+}
+
+// Close closes the enrichers of the pipeline, returning the first error.
+func (p *Pipeline) Close() error {
+	var first error
+	for _, e := range p.enrichers {
+		if err := e.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// countryEnricher looks up the country of IP addresses and hostnames.
+type countryEnricher struct {
+	cl *countrycache.CountryLookup
+}
+
+func (e countryEnricher) Enrich(ctx context.Context, ip string, geo *Geo) {
+	geo.Country, _ = e.cl.LookupOne(ctx, ip)
+	geo.CountryCode, _ = e.cl.Code(geo.Country)
+}
+
+func (e countryEnricher) Close() error {
+	return e.cl.Close()
+}
+
+// cityEnricher looks up the city and its location of IP addresses.
+type cityEnricher struct {
+	cl *citycache.CityLookup
+}
+
+func (e cityEnricher) Enrich(_ context.Context, ip string, geo *Geo) {
+	city, ok := e.cl.Lookup(ip)
+	if !ok {
+		return
+	}
+	geo.City = city
+	geo.Lat, geo.Lon, _ = e.cl.Location(city)
+}
+
+func (e cityEnricher) Close() error {
+	return e.cl.Close()
+}
+
+// asnEnricher looks up the autonomous system of IP addresses.
+type asnEnricher struct {
+	al *asncache.ASNLookup
+}
+
+func (e asnEnricher) Enrich(_ context.Context, ip string, geo *Geo) {
+	geo.ASN, _ = e.al.Lookup(ip)
+}
+
+func (e asnEnricher) Close() error {
+	return e.al.Close()
+}
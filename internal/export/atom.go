@@ -0,0 +1,110 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// atomDays is the number of days in the Atom feed.
+const atomDays = 30
+
+// atomFeed is the root element of an Atom feed, with one entry per day.
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Generator string      `xml:"generator"`
+	Author    string      `xml:"author>name"`
+	Link      atomLink    `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+// atomLink links a feed or entry to the report.
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// atomEntry is the summary of a day.
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+// atomContent is the plain-text content of an entry.
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// WriteAtom writes an Atom feed of the last 30 days, newest first, where each entry summarizes the hits,
+// visits, bytes, and error rate of a day and links to the report at link, usually relative to the feed.
+// The IDs of the feed and its entries are derived from the title, which should thus name the site.
+func WriteAtom(w io.Writer, stats *logstats.LogStats, title string, link string, now time.Time) error {
+	days := stats.DailyAggregates()
+	dates := slices.Sorted(maps.Keys(days))
+	slices.Reverse(dates)
+	dates = dates[:min(len(dates), atomDays)]
+
+	id := "urn:go-webalizer:" + url.PathEscape(title)
+	feed := atomFeed{
+		ID:        id,
+		Title:     title,
+		Updated:   now.UTC().Format(time.RFC3339),
+		Generator: "go-webalizer",
+		Author:    "go-webalizer",
+		Link:      atomLink{Href: link},
+	}
+	for i, date := range dates {
+		data := days[date]
+		var errors uint64
+		for code, hits := range stats.RespCodes[date] {
+			if code >= 400 {
+				errors += hits
+			}
+		}
+		errorRate := 0.0
+		if data.Hits > 0 {
+			errorRate = float64(errors) / float64(data.Hits) * 100
+		}
+
+		// The last day may still be incomplete, so it is updated whenever the feed is.
+		updated := date + "T23:59:59Z"
+		if i == 0 {
+			updated = feed.Updated
+		}
+
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      id + ":" + date,
+			Title:   fmt.Sprintf("%s: %d hits, %d visits", date, data.Hits, data.Visits),
+			Updated: updated,
+			Link:    atomLink{Href: link},
+			Content: atomContent{
+				Type: "text",
+				Text: fmt.Sprintf("Hits: %d\nVisits: %d\nKBytes: %d\nError rate: %.1f%% (%d responses 4xx and 5xx)",
+					data.Hits, data.Visits, data.Bytes/1024, errorRate, errors),
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
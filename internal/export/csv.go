@@ -0,0 +1,36 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// csvHeader is the header row of the CSV export.
+var csvHeader = []string{"date", "hits", "files", "pages", "bytes", "visits", "sites"}
+
+// WriteCSV writes the daily statistics as CSV, with a header row and a row per day, oldest first.
+func WriteCSV(w io.Writer, stats *logstats.LogStats) error {
+	days := stats.DailyAggregates()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, date := range slices.Sorted(maps.Keys(days)) {
+		d := days[date]
+		row := []string{date}
+		for _, n := range []uint64{d.Hits, d.Files, d.Pages, d.Bytes, d.Visits, d.Sites} {
+			row = append(row, strconv.FormatUint(n, 10))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,330 @@
+// Package export converts log statistics into a documented schema for consumption by scripts,
+// dashboards, and front-ends.
+//
+// The schema of the JSON export is:
+//
+//	{
+//	  "generated": "2019-02-22T10:00:00Z",       // time of the export
+//	  "summary":   {"first": "2019-01-01", "last": "2019-02-21", "hits": 0, "files": 0, "pages": 0,
+//	                "bytes": 0, "visits": 0, "sites": 0},
+//	  "monthly":   [Period, ...],                 // all months, oldest first, including imported history
+//	  "daily":     [Period, ...],                 // the days of the last month, oldest first
+//	  "hourly":    [Period, ...],                 // hours of day "00" to "23" over the last month
+//	  "top":       {"urls": [Entry, ...], ...}    // top-N lists over the last month, most counted first
+//	}
+//
+// A Period is {"key": "2019-01", "label": "Jan", "hits": 0, "files": 0, "pages": 0, "bytes": 0,
+// "visits": 0, "sites": 0}, and an Entry is {"key": "/index.html", "count": 0}.
+// Visits and sites are not tracked per hour and are zero in the hourly periods.
+package export
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/config"
+	"github.com/rbscholtus/go-webalizer/internal/group"
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// Report holds the exported aggregates.
+type Report struct {
+	// Generated is the time of the export.
+	Generated time.Time `json:"generated"`
+	// Summary holds the grand totals.
+	Summary Summary `json:"summary"`
+	// Monthly holds the totals per month, oldest first.
+	Monthly []Period `json:"monthly"`
+	// Daily holds the totals per day of the last month, oldest first.
+	Daily []Period `json:"daily"`
+	// Hourly holds the totals per hour of day over the last month.
+	Hourly []Period `json:"hourly"`
+	// Top holds the top-N lists over the last month.
+	Top Top `json:"top"`
+}
+
+// Summary holds the grand totals and the period they cover.
+type Summary struct {
+	// First is the first day covered, in the format "YYYY-MM-DD".
+	First string `json:"first"`
+	// Last is the last day covered, in the format "YYYY-MM-DD".
+	Last string `json:"last"`
+	// Hits is the total number of hits.
+	Hits uint64 `json:"hits"`
+	// Files is the total number of file requests.
+	Files uint64 `json:"files"`
+	// Pages is the total number of page requests.
+	Pages uint64 `json:"pages"`
+	// Bytes is the total number of bytes transferred.
+	Bytes uint64 `json:"bytes"`
+	// Visits is the total number of visits.
+	Visits uint64 `json:"visits"`
+	// Sites is the number of unique visitor IP addresses.
+	Sites uint64 `json:"sites"`
+}
+
+// Period holds the totals of a month, day, or hour of day.
+type Period struct {
+	// Key identifies the period: "YYYY-MM" for months, "YYYY-MM-DD" for days, and "HH" for hours.
+	Key string `json:"key"`
+	// Label is the display name of the period.
+	Label string `json:"label"`
+	// Hits is the number of hits.
+	Hits uint64 `json:"hits"`
+	// Files is the number of file requests.
+	Files uint64 `json:"files"`
+	// Pages is the number of page requests.
+	Pages uint64 `json:"pages"`
+	// Bytes is the number of bytes transferred.
+	Bytes uint64 `json:"bytes"`
+	// Visits is the number of visits.
+	Visits uint64 `json:"visits"`
+	// Sites is the number of sites.
+	Sites uint64 `json:"sites"`
+}
+
+// Top holds the top-N lists, most counted first.
+type Top struct {
+	// URLs are the URL paths with the most hits.
+	URLs []Entry `json:"urls"`
+	// EntryPages are the pages most visits started on.
+	EntryPages []Entry `json:"entry_pages"`
+	// ExitPages are the pages most visits ended on.
+	ExitPages []Entry `json:"exit_pages"`
+	// Sites are the visitor IP addresses with the most hits.
+	Sites []Entry `json:"sites"`
+	// Referrers are the referring sites with the most hits.
+	Referrers []Entry `json:"referrers"`
+	// SearchStrings are the search strings with the most hits.
+	SearchStrings []Entry `json:"search_strings"`
+	// Browsers are the browser versions with the most visits.
+	Browsers []Entry `json:"browsers"`
+	// UserAgents are the user agents, as sent by the clients, with the most visits.
+	UserAgents []Entry `json:"user_agents"`
+	// OSes are the operating systems with the most visits.
+	OSes []Entry `json:"oses"`
+	// Countries are the countries with the most visits.
+	Countries []Entry `json:"countries"`
+	// Methods are the request methods with the most hits.
+	Methods []Entry `json:"methods"`
+	// ResponseCodes are the response codes with the most hits.
+	ResponseCodes []Entry `json:"response_codes"`
+}
+
+// Entry is a key of a top-N list and its count of hits or visits.
+type Entry struct {
+	// Key is the URL path, site, referrer, or other value counted.
+	Key string `json:"key"`
+	// Count is the number of hits or visits.
+	Count uint64 `json:"count"`
+	// Code is the ISO 3166 code of a country, if known, which is stable where country names are not.
+	Code string `json:"code,omitempty"`
+}
+
+// Options selects what is exported.
+type Options struct {
+	// Top holds the lengths of the top-N lists. The lists without a length of their own, such as the
+	// operating systems, are otherTopN long.
+	Top config.TopN
+	// Groups holds the grouping rules applied to the top-N lists.
+	Groups group.Groups
+	// Hide holds the rules of keys left out of the top-N lists.
+	Hide group.Filters
+}
+
+// otherTopN is the length of the top-N lists without a length in Options.Top.
+const otherTopN = 10
+
+// MarkdownTopN holds the lengths of the top-N lists of the concise Markdown report, unless other lengths
+// are configured.
+var MarkdownTopN = config.TopN{
+	URLs:          10,
+	EntryPages:    10,
+	ExitPages:     10,
+	Sites:         10,
+	Referrers:     10,
+	SearchStrings: 10,
+	Agents:        10,
+	Countries:     10,
+}
+
+// New returns the export of stats.
+func New(stats *logstats.LogStats, opts Options) *Report {
+	report := &Report{
+		Generated: time.Now().UTC(),
+		Summary:   NewSummary(stats),
+		Monthly:   Monthly(stats),
+		Daily:     periods(stats.RecentAggregates()),
+		Hourly:    periods(stats.HourlyAggregates()),
+	}
+
+	lists := topLists(stats, opts)
+	n := opts.Top
+	report.Top = Top{
+		URLs:          top(lists["urls"](), n.URLs),
+		EntryPages:    top(lists["entry_pages"](), n.EntryPages),
+		ExitPages:     top(lists["exit_pages"](), n.ExitPages),
+		Sites:         top(lists["sites"](), n.Sites),
+		Referrers:     top(lists["referrers"](), n.Referrers),
+		SearchStrings: top(lists["search_strings"](), n.SearchStrings),
+		Browsers:      top(lists["browsers"](), n.Agents),
+		UserAgents:    top(lists["user_agents"](), n.Agents),
+		OSes:          top(lists["oses"](), otherTopN),
+		Countries:     countryCodes(stats, top(lists["countries"](), n.Countries)),
+		Methods:       top(lists["methods"](), otherTopN),
+		ResponseCodes: top(lists["response_codes"](), otherTopN),
+	}
+
+	return report
+}
+
+// NewSummary returns the grand totals of stats.
+func NewSummary(stats *logstats.LogStats) Summary {
+	summary := stats.Summary()
+	return Summary{
+		First:  summary.First,
+		Last:   summary.Last,
+		Hits:   summary.Hits,
+		Files:  summary.Files,
+		Pages:  summary.Pages,
+		Bytes:  summary.Bytes,
+		Visits: summary.Visits,
+		Sites:  summary.Sites,
+	}
+}
+
+// Monthly returns the totals of all months of stats, oldest first, including imported history.
+func Monthly(stats *logstats.LogStats) []Period {
+	return periods(stats.AggregatesByMonth())
+}
+
+// Daily returns the totals of all days of stats, oldest first. Unlike the daily periods of New, it is not
+// limited to the last month.
+func Daily(stats *logstats.LogStats) []Period {
+	return periods(stats.DailyAggregates())
+}
+
+// TopList returns the n most counted entries of the top-N list name, one of the keys of the top of the
+// JSON export, such as "urls" or "countries". If n is 0, all entries are returned. It returns false if
+// there is no list name.
+func TopList(stats *logstats.LogStats, name string, n int, opts Options) ([]Entry, bool) {
+	counts, ok := topLists(stats, opts)[name]
+	if !ok {
+		return nil, false
+	}
+	list := top(counts(), n)
+	if name == "countries" {
+		list = countryCodes(stats, list)
+	}
+	return list, true
+}
+
+// topLists returns the functions counting the keys of each top-N list, after grouping and hiding, by
+// the JSON names of the lists.
+func topLists(stats *logstats.LogStats, opts Options) map[string]func() map[string]uint64 {
+	return map[string]func() map[string]uint64{
+		"urls": func() map[string]uint64 {
+			urlHits := make(map[string]uint64)
+			for urlPath, hb := range stats.URLAggregates() {
+				urlHits[urlPath] = hb.Hits
+			}
+			return opts.Groups.URLs.Apply(opts.Hide.URLs.Hide(urlHits))
+		},
+		"entry_pages": func() map[string]uint64 {
+			entries, _ := stats.EntryExitAggregates()
+			return opts.Groups.URLs.Apply(opts.Hide.URLs.Hide(entries))
+		},
+		"exit_pages": func() map[string]uint64 {
+			_, exits := stats.EntryExitAggregates()
+			return opts.Groups.URLs.Apply(opts.Hide.URLs.Hide(exits))
+		},
+		"sites": func() map[string]uint64 {
+			return opts.Groups.Sites.Apply(opts.Hide.Sites.Hide(stats.SiteAggregates()))
+		},
+		"referrers": func() map[string]uint64 {
+			return opts.Groups.Referrers.Apply(opts.Hide.Referrers.Hide(stats.ReferrerDomainAggregates()))
+		},
+		"search_strings": stats.SearchStringAggregates,
+		"browsers": func() map[string]uint64 {
+			_, browsers := stats.BrowserAggregates()
+			return browsers
+		},
+		"user_agents": func() map[string]uint64 {
+			return opts.Hide.Agents.Hide(stats.UserAgentAggregates())
+		},
+		"oses": func() map[string]uint64 {
+			oses, _ := stats.OSDeviceAggregates()
+			return oses
+		},
+		"countries": stats.CountryAggregates,
+		"methods": func() map[string]uint64 {
+			methods, _ := stats.MethRespAggregates()
+			return methods
+		},
+		"response_codes": func() map[string]uint64 {
+			_, codes := stats.MethRespAggregates()
+			codeHits := make(map[string]uint64, len(codes))
+			for code, hits := range codes {
+				codeHits[strconv.Itoa(int(code))] = hits
+			}
+			return codeHits
+		},
+	}
+}
+
+// countryCodes sets the ISO 3166 codes of the countries in list, and returns it.
+func countryCodes(stats *logstats.LogStats, list []Entry) []Entry {
+	for i, country := range list {
+		list[i].Code = stats.CountryCodes[country.Key]
+	}
+	return list
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// periods returns the aggregates as periods, sorted by key.
+func periods(aggr map[string]*logstats.HFPBVSData) []Period {
+	list := make([]Period, 0, len(aggr))
+	for _, key := range slices.Sorted(maps.Keys(aggr)) {
+		data := aggr[key]
+		list = append(list, Period{
+			Key:    key,
+			Label:  data.Category,
+			Hits:   data.Hits,
+			Files:  data.Files,
+			Pages:  data.Pages,
+			Bytes:  data.Bytes,
+			Visits: data.Visits,
+			Sites:  data.Sites,
+		})
+	}
+	return list
+}
+
+// top returns the n keys with the highest counts, sorted by count and then by key.
+func top(counts map[string]uint64, n int) []Entry {
+	list := make([]Entry, 0, len(counts))
+	for key, count := range counts {
+		list = append(list, Entry{Key: key, Count: count})
+	}
+	slices.SortFunc(list, func(a, b Entry) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes a concise Markdown report: the summary and the top URLs, referrers, browsers, and
+// countries, as long as the top-N lists of the report, which are MarkdownTopN long by default.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	s := r.Summary
+	fmt.Fprintf(&b, "## Usage Summary %s to %s\n\n", s.First, s.Last)
+	b.WriteString("| Hits | Files | Pages | KBytes | Visits | Sites |\n")
+	b.WriteString("|-----:|------:|------:|-------:|-------:|------:|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d | %d |\n", s.Hits, s.Files, s.Pages, s.Bytes/1024, s.Visits, s.Sites)
+
+	writeMarkdownTop(&b, "Top URLs", "URL", "Hits", r.Top.URLs)
+	writeMarkdownTop(&b, "Top Referrers", "Site", "Hits", r.Top.Referrers)
+	writeMarkdownTop(&b, "Top Browsers", "Browser", "Visits", r.Top.Browsers)
+	writeMarkdownTop(&b, "Top Countries", "Country", "Visits", r.Top.Countries)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownTop writes the first entries of a top-N list as a Markdown table.
+func writeMarkdownTop(b *strings.Builder, title string, keyHeader string, countHeader string, entries []Entry) {
+	fmt.Fprintf(b, "\n### %s\n\n", title)
+	if len(entries) == 0 {
+		b.WriteString("None.\n")
+		return
+	}
+	fmt.Fprintf(b, "| # | %s | %s |\n", keyHeader, countHeader)
+	b.WriteString("|--:|:---|---:|\n")
+	for i, e := range entries {
+		fmt.Fprintf(b, "| %d | %s | %d |\n", i+1, markdownEscape(e.Key), e.Count)
+	}
+}
+
+// markdownEscape escapes characters that would break a Markdown table cell or its formatting.
+var markdownEscape = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", "&lt;", "\n", " ",
+).Replace
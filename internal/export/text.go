@@ -0,0 +1,71 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// textTopN is the length of the top-N lists in the plain-text report.
+const textTopN = 10
+
+// WriteText writes a plain-text report with aligned tables: the summary, the daily statistics
+// of the last month, and the top-N lists.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	s := r.Summary
+	writeTextTitle(tw, fmt.Sprintf("Summary Period: %s - %s", s.First, s.Last))
+	fmt.Fprintln(tw, "Hits\tFiles\tPages\tKBytes\tVisits\tSites\t")
+	fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t\n", s.Hits, s.Files, s.Pages, s.Bytes/1024, s.Visits, s.Sites)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	writeTextTitle(tw, "Daily Statistics")
+	fmt.Fprintln(tw, "Day\tHits\tFiles\tPages\tKBytes\tVisits\tSites\t")
+	for _, p := range r.Daily {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t\n", p.Label, p.Hits, p.Files, p.Pages, p.Bytes/1024, p.Visits, p.Sites)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	tops := []struct {
+		title, keyHeader, countHeader string
+		entries                       []Entry
+	}{
+		{"Top URLs", "URL", "Hits", r.Top.URLs},
+		{"Top Entry Pages", "URL", "Visits", r.Top.EntryPages},
+		{"Top Exit Pages", "URL", "Visits", r.Top.ExitPages},
+		{"Top Sites", "Site", "Hits", r.Top.Sites},
+		{"Top Referrers", "Site", "Hits", r.Top.Referrers},
+		{"Top Search Strings", "Search String", "Hits", r.Top.SearchStrings},
+		{"Top Browsers", "Browser", "Visits", r.Top.Browsers},
+		{"Top User Agents", "User Agent", "Visits", r.Top.UserAgents},
+		{"Top Countries", "Country", "Visits", r.Top.Countries},
+	}
+	for _, top := range tops {
+		if err := writeTextTop(w, top.title, top.keyHeader, top.countHeader, top.entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTextTitle writes a title underlined with dashes, preceded by an empty line.
+func writeTextTitle(w io.Writer, title string) {
+	fmt.Fprintf(w, "\n%s\n%s\n", title, strings.Repeat("-", len(title)))
+}
+
+// writeTextTop writes the first entries of a top-N list as an aligned table, with the keys left-aligned.
+func writeTextTop(w io.Writer, title string, keyHeader string, countHeader string, entries []Entry) error {
+	writeTextTitle(w, title)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "#\t%s\t%s\n", countHeader, keyHeader)
+	for i, e := range entries[:min(len(entries), textTopN)] {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", i+1, e.Count, strings.ReplaceAll(e.Key, "\t", " "))
+	}
+	return tw.Flush()
+}
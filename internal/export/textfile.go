@@ -0,0 +1,92 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// WriteTextfile writes the current aggregates in the Prometheus text format read by the textfile collector
+// of node_exporter. The totals are gauges with a "period" label: "total" for all statistics, "month" for
+// the last calendar month, and "day" for the last day. The responses and methods are counted over the month
+// up to the last day, like the top-N tables of the report.
+func WriteTextfile(w io.Writer, stats *logstats.LogStats) error {
+	var b strings.Builder
+
+	summary := stats.Summary()
+	periods := map[string]*logstats.HFPBVSData{
+		"total": {
+			Hits:   summary.Hits,
+			Files:  summary.Files,
+			Pages:  summary.Pages,
+			Bytes:  summary.Bytes,
+			Visits: summary.Visits,
+			Sites:  summary.Sites,
+		},
+	}
+	if months := stats.AggregatesByMonth(); len(months) > 0 {
+		periods["month"] = months[slices.Max(slices.Collect(maps.Keys(months)))]
+	}
+	if days := stats.DailyAggregates(); len(days) > 0 {
+		periods["day"] = days[slices.Max(slices.Collect(maps.Keys(days)))]
+	}
+
+	totals := []struct {
+		name, help string
+		value      func(data *logstats.HFPBVSData) uint64
+	}{
+		{"hits", "Number of hits.", func(data *logstats.HFPBVSData) uint64 { return data.Hits }},
+		{"files", "Number of file requests.", func(data *logstats.HFPBVSData) uint64 { return data.Files }},
+		{"pages", "Number of page requests.", func(data *logstats.HFPBVSData) uint64 { return data.Pages }},
+		{"bytes", "Number of bytes transferred.", func(data *logstats.HFPBVSData) uint64 { return data.Bytes }},
+		{"visits", "Number of visits.", func(data *logstats.HFPBVSData) uint64 { return data.Visits }},
+		{"sites", "Number of unique visitor IP addresses.", func(data *logstats.HFPBVSData) uint64 { return data.Sites }},
+	}
+	for _, total := range totals {
+		values := make(map[string]uint64, len(periods))
+		for period, data := range periods {
+			values[period] = total.value(data)
+		}
+		writeGauge(&b, "webalizer_"+total.name, total.help, "period", values)
+	}
+
+	methods, codes := stats.MethRespAggregates()
+	responses := make(map[string]uint64, len(codes))
+	for code, hits := range codes {
+		responses[strconv.Itoa(int(code))] = hits
+	}
+	writeGauge(&b, "webalizer_responses", "Number of responses in the month up to the last day, by response code.", "code", responses)
+	writeGauge(&b, "webalizer_requests", "Number of requests in the month up to the last day, by method.", "method", methods)
+
+	if summary.Last != "" {
+		if last, err := time.Parse(time.DateOnly, summary.Last); err == nil {
+			writeGauge(&b, "webalizer_last_day_timestamp_seconds", "Start of the last day with statistics.", "", map[string]uint64{"": uint64(last.Unix())})
+		}
+	}
+	writeGauge(&b, "webalizer_generated_timestamp_seconds", "Time the statistics were written.", "", map[string]uint64{"": uint64(time.Now().Unix())})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeGauge writes a gauge with a value per label value, sorted by label value. A gauge without
+// a label has a single value keyed by "".
+func writeGauge(b *strings.Builder, name string, help string, label string, values map[string]uint64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if label == "" {
+			fmt.Fprintf(b, "%s %d\n", name, values[key])
+			continue
+		}
+		fmt.Fprintf(b, "%s{%s=\"%s\"} %d\n", name, label, labelEscape(key), values[key])
+	}
+}
+
+// labelEscape escapes a Prometheus label value.
+var labelEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
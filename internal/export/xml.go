@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// xmlStats is the root element of the XML export, in the style of the XML dumps of Webalizer:
+//
+//	<webalizer generator="go-webalizer">
+//	  <month year="2019" month="1" hits="0" files="0" pages="0" visits="0" sites="0" kbytes="0">
+//	    <day date="2019-01-01" hits="0" files="0" pages="0" visits="0" sites="0" kbytes="0"/>
+//	  </month>
+//	</webalizer>
+//
+// Months only come with days if they were parsed from a log, not imported from history.
+type xmlStats struct {
+	XMLName   xml.Name   `xml:"webalizer"`
+	Generator string     `xml:"generator,attr"`
+	Months    []xmlMonth `xml:"month"`
+}
+
+// xmlMonth holds the totals of a month and its days.
+type xmlMonth struct {
+	Year  int `xml:"year,attr"`
+	Month int `xml:"month,attr"`
+	xmlTotals
+	Days []xmlDay `xml:"day"`
+}
+
+// xmlDay holds the totals of a day.
+type xmlDay struct {
+	Date string `xml:"date,attr"`
+	xmlTotals
+}
+
+// xmlTotals holds the totals of a month or day, as attributes.
+type xmlTotals struct {
+	Hits   uint64 `xml:"hits,attr"`
+	Files  uint64 `xml:"files,attr"`
+	Pages  uint64 `xml:"pages,attr"`
+	Visits uint64 `xml:"visits,attr"`
+	Sites  uint64 `xml:"sites,attr"`
+	KBytes uint64 `xml:"kbytes,attr"`
+}
+
+// newXMLTotals returns the totals of an aggregate.
+func newXMLTotals(data *logstats.HFPBVSData) xmlTotals {
+	return xmlTotals{
+		Hits:   data.Hits,
+		Files:  data.Files,
+		Pages:  data.Pages,
+		Visits: data.Visits,
+		Sites:  data.Sites,
+		KBytes: data.Bytes / 1024,
+	}
+}
+
+// WriteXML writes the monthly and daily statistics as XML.
+func WriteXML(w io.Writer, stats *logstats.LogStats) error {
+	months := stats.AggregatesByMonth()
+	days := stats.DailyAggregates()
+
+	root := xmlStats{Generator: "go-webalizer"}
+	for _, key := range slices.Sorted(maps.Keys(months)) {
+		year, _ := strconv.Atoi(key[:4])
+		month, _ := strconv.Atoi(key[5:7])
+		m := xmlMonth{Year: year, Month: month, xmlTotals: newXMLTotals(months[key])}
+		for _, date := range slices.Sorted(maps.Keys(days)) {
+			if date[:7] == key {
+				m.Days = append(m.Days, xmlDay{Date: date, xmlTotals: newXMLTotals(days[date])})
+			}
+		}
+		root.Months = append(root.Months, m)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
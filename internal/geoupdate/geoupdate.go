@@ -0,0 +1,103 @@
+// Package geoupdate downloads and refreshes the MaxMind GeoLite2 databases with a MaxMind license key.
+package geoupdate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Editions of the GeoLite2 databases.
+const (
+	// Country is the GeoLite2-Country database.
+	Country = "GeoLite2-Country"
+	// City is the GeoLite2-City database.
+	City = "GeoLite2-City"
+	// ASN is the GeoLite2-ASN database.
+	ASN = "GeoLite2-ASN"
+)
+
+// MaxAge is the age after which a downloaded database is refreshed. MaxMind updates the GeoLite2 databases twice a week.
+const MaxAge = 7 * 24 * time.Hour
+
+// downloadURL is the address the databases are downloaded from, as a gzipped tar archive.
+const downloadURL = "https://download.maxmind.com/app/geoip_download"
+
+// Update downloads the database edition to path with the MaxMind licenseKey, unless the file at path
+// is younger than maxAge. It reports whether the database was downloaded.
+// The file is replaced atomically, so a failed download leaves an existing database in place.
+func Update(ctx context.Context, edition string, licenseKey string, path string, maxAge time.Duration) (bool, error) {
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < maxAge {
+		return false, nil
+	}
+
+	query := url.Values{"edition_id": {edition}, "license_key": {licenseKey}, "suffix": {"tar.gz"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The error holds the URL, which holds the license key.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return false, fmt.Errorf("downloading %s: %v", edition, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("downloading %s: %s: %s", edition, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := extract(resp.Body, tmp); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("downloading %s: %v", edition, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// extract copies the .mmdb file in the gzipped tar archive r to w.
+func extract(r io.Reader, w io.Writer) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return errors.New("no .mmdb file in the archive")
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg && strings.HasSuffix(hdr.Name, ".mmdb") {
+			_, err := io.Copy(w, tr)
+			return err
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package grafana builds Grafana dashboards on the statistics written for the Prometheus textfile
+// collector or to the SQLite store, and pushes them to Grafana through its HTTP API.
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Datasource types the dashboards can be built for.
+const (
+	// Prometheus queries the metrics written with --write-textfile and scraped by node_exporter.
+	Prometheus = "prometheus"
+	// SQLite queries the SQLite database written with --sqlite, through the frser-sqlite-datasource plugin.
+	SQLite = "sqlite"
+)
+
+// pluginIDs are the Grafana plugin IDs of the datasource types.
+var pluginIDs = map[string]string{
+	Prometheus: "prometheus",
+	SQLite:     "frser-sqlite-datasource",
+}
+
+// Dashboard is a Grafana dashboard model.
+type Dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+// TimeRange is the default time range of a dashboard.
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating holds the template variables of a dashboard.
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a template variable. The dashboards have a single variable selecting the datasource.
+type Variable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+// Panel is a dashboard panel.
+type Panel struct {
+	ID          int           `json:"id"`
+	Type        string        `json:"type"`
+	Title       string        `json:"title"`
+	GridPos     GridPos       `json:"gridPos"`
+	Datasource  DatasourceRef `json:"datasource"`
+	Targets     []Target      `json:"targets"`
+	FieldConfig FieldConfig   `json:"fieldConfig"`
+}
+
+// GridPos is the position and size of a panel, in grid units of a 24 units wide dashboard.
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// DatasourceRef refers to the datasource selected by the datasource variable.
+type DatasourceRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// FieldConfig holds the display options of the values of a panel.
+type FieldConfig struct {
+	Defaults FieldDefaults `json:"defaults"`
+}
+
+// FieldDefaults holds the default display options of the values of a panel.
+type FieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+// Target is a panel query, a PromQL expression or an SQL query.
+type Target struct {
+	RefID        string   `json:"refId"`
+	Expr         string   `json:"expr,omitempty"`
+	LegendFormat string   `json:"legendFormat,omitempty"`
+	QueryText    string   `json:"queryText,omitempty"`
+	RawQueryText string   `json:"rawQueryText,omitempty"`
+	QueryType    string   `json:"queryType,omitempty"`
+	TimeColumns  []string `json:"timeColumns,omitempty"`
+}
+
+// panelSpec describes a panel independently of its position.
+type panelSpec struct {
+	kind, title, unit string
+	width             int
+	queries           []string
+	legends           []string
+}
+
+// New returns the dashboard for the datasource type Prometheus or SQLite, titled after site if set.
+func New(datasource string, site string) (*Dashboard, error) {
+	pluginID, ok := pluginIDs[datasource]
+	if !ok {
+		return nil, fmt.Errorf("unsupported datasource %q", datasource)
+	}
+
+	title := "Web Usage Statistics"
+	if site != "" {
+		title += " for " + site
+	}
+	dashboard := &Dashboard{
+		UID:           "go-webalizer-" + datasource,
+		Title:         title,
+		Tags:          []string{"go-webalizer", "web"},
+		Timezone:      "utc",
+		SchemaVersion: 39,
+		Time:          TimeRange{From: "now-90d", To: "now"},
+		Templating: Templating{List: []Variable{
+			{Name: "datasource", Label: "Datasource", Type: "datasource", Query: pluginID},
+		}},
+	}
+
+	specs := prometheusPanels
+	if datasource == SQLite {
+		specs = sqlitePanels
+	}
+	x, y, rowHeight := 0, 0, 0
+	for i, spec := range specs {
+		height := 8
+		if spec.kind == "stat" {
+			height = 4
+		}
+		if x+spec.width > 24 {
+			x, y = 0, y+rowHeight
+			rowHeight = 0
+		}
+		panel := Panel{
+			ID:          i + 1,
+			Type:        spec.kind,
+			Title:       spec.title,
+			GridPos:     GridPos{X: x, Y: y, W: spec.width, H: height},
+			Datasource:  DatasourceRef{Type: pluginID, UID: "${datasource}"},
+			FieldConfig: FieldConfig{Defaults: FieldDefaults{Unit: spec.unit}},
+		}
+		for j, query := range spec.queries {
+			target := Target{RefID: string(rune('A' + j))}
+			if datasource == SQLite {
+				target.QueryText, target.RawQueryText = query, query
+				target.QueryType = "table"
+				if spec.kind == "timeseries" {
+					target.QueryType = "time series"
+					target.TimeColumns = []string{"time"}
+				}
+			} else {
+				target.Expr = query
+				if j < len(spec.legends) {
+					target.LegendFormat = spec.legends[j]
+				}
+			}
+			panel.Targets = append(panel.Targets, target)
+		}
+		dashboard.Panels = append(dashboard.Panels, panel)
+		x += spec.width
+		rowHeight = max(rowHeight, height)
+	}
+
+	return dashboard, nil
+}
+
+// prometheusPanels query the gauges written by export.WriteTextfile.
+var prometheusPanels = []panelSpec{
+	{kind: "stat", title: "Hits (last day)", width: 6, queries: []string{`webalizer_hits{period="day"}`}},
+	{kind: "stat", title: "Visits (last day)", width: 6, queries: []string{`webalizer_visits{period="day"}`}},
+	{kind: "stat", title: "Sites (last day)", width: 6, queries: []string{`webalizer_sites{period="day"}`}},
+	{kind: "stat", title: "Traffic (last day)", unit: "bytes", width: 6, queries: []string{`webalizer_bytes{period="day"}`}},
+	{kind: "timeseries", title: "Daily Hits, Files, and Pages", width: 12,
+		queries: []string{`webalizer_hits{period="day"}`, `webalizer_files{period="day"}`, `webalizer_pages{period="day"}`},
+		legends: []string{"Hits", "Files", "Pages"}},
+	{kind: "timeseries", title: "Daily Visits and Sites", width: 12,
+		queries: []string{`webalizer_visits{period="day"}`, `webalizer_sites{period="day"}`},
+		legends: []string{"Visits", "Sites"}},
+	{kind: "timeseries", title: "Daily Traffic", unit: "bytes", width: 12,
+		queries: []string{`webalizer_bytes{period="day"}`}, legends: []string{"Bytes"}},
+	{kind: "timeseries", title: "Responses by Code (month to date)", width: 12,
+		queries: []string{`webalizer_responses`}, legends: []string{"{{code}}"}},
+	{kind: "timeseries", title: "Requests by Method (month to date)", width: 12,
+		queries: []string{`webalizer_requests`}, legends: []string{"{{method}}"}},
+	{kind: "stat", title: "Time Since Last Run", unit: "s", width: 12,
+		queries: []string{`time() - webalizer_generated_timestamp_seconds`}},
+}
+
+// sqliteTime selects the date of a row as the time column, limited to the dashboard time range.
+const sqliteTime = `CAST(strftime('%s', date) AS INTEGER)`
+
+// sqliteRange limits the rows to the dashboard time range.
+const sqliteRange = `WHERE ` + sqliteTime + ` BETWEEN $__unixEpochFrom() AND $__unixEpochTo()`
+
+// sqlitePanels query the tables of the SQLite store.
+var sqlitePanels = []panelSpec{
+	{kind: "stat", title: "Hits", width: 6, queries: []string{`SELECT SUM(hits) AS hits FROM totals ` + sqliteRange}},
+	{kind: "stat", title: "Pages", width: 6, queries: []string{`SELECT SUM(pages) AS pages FROM totals ` + sqliteRange}},
+	{kind: "stat", title: "Visits", width: 6, queries: []string{`SELECT SUM(visits) AS visits FROM visit_metrics ` + sqliteRange}},
+	{kind: "stat", title: "Traffic", unit: "bytes", width: 6, queries: []string{`SELECT SUM(bytes) AS bytes FROM totals ` + sqliteRange}},
+	{kind: "timeseries", title: "Daily Hits, Files, and Pages", width: 12,
+		queries: []string{`SELECT ` + sqliteTime + ` AS time, hits AS Hits, files AS Files, pages AS Pages FROM totals ` + sqliteRange + ` ORDER BY date`}},
+	{kind: "timeseries", title: "Daily Traffic", unit: "bytes", width: 12,
+		queries: []string{`SELECT ` + sqliteTime + ` AS time, bytes AS Bytes FROM totals ` + sqliteRange + ` ORDER BY date`}},
+	{kind: "timeseries", title: "Daily Visits", width: 12,
+		queries: []string{`SELECT ` + sqliteTime + ` AS time, visits AS Visits FROM visit_metrics ` + sqliteRange + ` ORDER BY date`}},
+	{kind: "barchart", title: "Responses by Code", width: 12,
+		queries: []string{`SELECT CAST(code AS TEXT) AS code, SUM(hits) AS hits FROM resp_codes ` + sqliteRange + ` GROUP BY code ORDER BY code`}},
+	{kind: "table", title: "Top URLs", width: 12,
+		queries: []string{`SELECT url_path AS URL, SUM(hits) AS Hits, SUM(bytes) AS Bytes FROM url_paths ` + sqliteRange + ` GROUP BY url_path ORDER BY Hits DESC LIMIT 30`}},
+	{kind: "table", title: "Top Referrers", width: 12,
+		queries: []string{`SELECT domain AS Site, SUM(hits) AS Hits FROM referrer_domains ` + sqliteRange + ` GROUP BY domain ORDER BY Hits DESC LIMIT 30`}},
+	{kind: "table", title: "Top Countries", width: 12,
+		queries: []string{`SELECT country AS Country, SUM(visits) AS Visits FROM country_visits ` + sqliteRange + ` GROUP BY country ORDER BY Visits DESC LIMIT 30`}},
+	{kind: "table", title: "Top Browsers", width: 12,
+		queries: []string{`SELECT browser AS Browser, SUM(visits) AS Visits FROM browsers ` + sqliteRange + ` GROUP BY browser ORDER BY Visits DESC LIMIT 15`}},
+}
+
+// WriteJSON writes the dashboard as indented JSON, for importing it in Grafana.
+func (d *Dashboard) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// Push creates or replaces the dashboard in the Grafana instance at baseURL, authenticating with
+// the service account token.
+func (d *Dashboard) Push(ctx context.Context, baseURL string, token string) error {
+	body, err := json.Marshal(struct {
+		Dashboard *Dashboard `json:"dashboard"`
+		Overwrite bool       `json:"overwrite"`
+		Message   string     `json:"message"`
+	}{d, true, "Updated by go-webalizer"})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/api/dashboards/db", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushing dashboard: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package group merges URLs, sites, and referrers matching Webalizer-style grouping rules into named groups.
+package group
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix marks a pattern as a regular expression.
+const regexPrefix = "re:"
+
+// Rule merges the keys matching a pattern into a named group.
+type Rule struct {
+	// Name is the name of the group.
+	Name string
+	// match reports whether a key belongs to the group.
+	match func(key string) bool
+}
+
+// NewRule returns a rule grouping the keys that match pattern under name.
+// A pattern starting with "re:" is a regular expression. Otherwise, like in Webalizer, a leading or
+// trailing "*" matches any suffix or prefix, and a pattern without wildcards matches keys containing it.
+// If name is empty, the pattern is used as the name.
+func NewRule(pattern string, name string) (Rule, error) {
+	if name == "" {
+		name = pattern
+	}
+
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		return Rule{Name: name, match: re.MatchString}, nil
+	}
+
+	prefix, hasPrefix := strings.CutSuffix(pattern, "*")
+	suffix, hasSuffix := strings.CutPrefix(prefix, "*")
+	switch {
+	case hasPrefix && hasSuffix:
+		return Rule{Name: name, match: func(key string) bool { return strings.Contains(key, suffix) }}, nil
+	case hasPrefix:
+		return Rule{Name: name, match: func(key string) bool { return strings.HasPrefix(key, prefix) }}, nil
+	case hasSuffix:
+		return Rule{Name: name, match: func(key string) bool { return strings.HasSuffix(key, suffix) }}, nil
+	default:
+		return Rule{Name: name, match: func(key string) bool { return strings.Contains(key, pattern) }}, nil
+	}
+}
+
+// NewSiteRule returns a rule grouping sites under name.
+// A pattern in CIDR notation, such as "10.0.0.0/8", matches the IP addresses in the subnet;
+// other patterns are handled like in NewRule.
+func NewSiteRule(pattern string, name string) (Rule, error) {
+	prefix, err := netip.ParsePrefix(pattern)
+	if err != nil {
+		return NewRule(pattern, name)
+	}
+
+	if name == "" {
+		name = pattern
+	}
+	return Rule{Name: name, match: func(key string) bool {
+		addr, err := netip.ParseAddr(key)
+		return err == nil && prefix.Contains(addr.Unmap())
+	}}, nil
+}
+
+// Rules is a list of rules. A key belongs to the group of the first rule it matches.
+type Rules []Rule
+
+// NewRules returns rules matching the given patterns, named after their patterns.
+func NewRules(patterns []string) (Rules, error) {
+	return newRules(patterns, NewRule)
+}
+
+// NewSiteRules returns site rules matching the given patterns, named after their patterns.
+func NewSiteRules(patterns []string) (Rules, error) {
+	return newRules(patterns, NewSiteRule)
+}
+
+// newRules creates a rule for each pattern with newRule.
+func newRules(patterns []string, newRule func(pattern, name string) (Rule, error)) (Rules, error) {
+	rules := make(Rules, 0, len(patterns))
+	for _, pattern := range patterns {
+		rule, err := newRule(pattern, "")
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Match reports whether key matches any rule.
+func (rules Rules) Match(key string) bool {
+	_, ok := rules.Group(key)
+	return ok
+}
+
+// Group returns the name of the group key belongs to, and false if it matches no rule.
+func (rules Rules) Group(key string) (string, bool) {
+	for _, rule := range rules {
+		if rule.match(key) {
+			return rule.Name, true
+		}
+	}
+	return "", false
+}
+
+// Apply returns counts with the counts of grouped keys summed under their group names.
+// counts is returned unchanged if there are no rules.
+func (rules Rules) Apply(counts map[string]uint64) map[string]uint64 {
+	if len(rules) == 0 {
+		return counts
+	}
+
+	grouped := make(map[string]uint64, len(counts))
+	for key, count := range counts {
+		if name, ok := rules.Group(key); ok {
+			key = name
+		}
+		grouped[key] += count
+	}
+	return grouped
+}
+
+// Hide returns counts without the keys matching any rule.
+// counts is returned unchanged if there are no rules.
+func (rules Rules) Hide(counts map[string]uint64) map[string]uint64 {
+	if len(rules) == 0 {
+		return counts
+	}
+
+	visible := make(map[string]uint64, len(counts))
+	for key, count := range counts {
+		if !rules.Match(key) {
+			visible[key] = count
+		}
+	}
+	return visible
+}
+
+// Groups holds the grouping rules of each dimension.
+type Groups struct {
+	// URLs groups URL paths.
+	URLs Rules
+	// Sites groups visitor IP addresses.
+	Sites Rules
+	// Referrers groups referrers.
+	Referrers Rules
+}
+
+// Filters holds rules selecting log lines or table rows by site, URL, referrer, and user agent.
+type Filters struct {
+	// Sites matches visitor IP addresses.
+	Sites Rules
+	// URLs matches URL paths.
+	URLs Rules
+	// Referrers matches referrers.
+	Referrers Rules
+	// Agents matches user agents.
+	Agents Rules
+}
+
+// Match reports whether a request matches any of the filters.
+func (f Filters) Match(ip string, urlPath string, referrer string, userAgent string) bool {
+	return f.Sites.Match(ip) || f.URLs.Match(urlPath) || f.Referrers.Match(referrer) || f.Agents.Match(userAgent)
+}
@@ -0,0 +1,130 @@
+package group
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"/blog/*", "/blog/a.html", true},
+		{"/blog/*", "/about/blog/", false},
+		{"*.png", "/img/x.png", true},
+		{"*.png", "/img/x.png?v=1", false},
+		{"*bot*", "Googlebot/2.1", true},
+		{"admin", "/wp-admin/index.php", true},
+		{"admin", "/login", false},
+		{"re:^/api/v[0-9]+/", "/api/v1/items", true},
+		{"re:^/api/v[0-9]+/", "/old/api/v1/items", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.key, func(t *testing.T) {
+			rule, err := NewRule(tt.pattern, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.match(tt.key); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSiteRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"10.0.0.0/8", "10.1.2.3", true},
+		{"10.0.0.0/8", "::ffff:10.1.2.3", true},
+		{"10.0.0.0/8", "11.1.2.3", false},
+		{"192.0.2.1/32", "192.0.2.1", true},
+		{"192.0.2.1/32", "192.0.2.10", false},
+		{"2001:db8::/32", "2001:db8::1", true},
+		{"10.0.0.0/8", "host.example.com", false},
+		{"*.example.com", "host.example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.key, func(t *testing.T) {
+			rule, err := NewSiteRule(tt.pattern, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.match(tt.key); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRuleInvalid(t *testing.T) {
+	if _, err := NewRule("re:(", ""); err == nil {
+		t.Error("NewRule(\"re:(\") succeeded, want an error")
+	}
+}
+
+func TestApplyHide(t *testing.T) {
+	blog, err := NewRule("/blog/*", "Blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := NewRule("*.png", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := Rules{blog, images}
+	counts := map[string]uint64{"/blog/a.html": 3, "/blog/b.html": 2, "/x.png": 4, "/index.html": 1}
+
+	tests := []struct {
+		name string
+		got  map[string]uint64
+		want map[string]uint64
+	}{
+		{"Apply", rules.Apply(counts), map[string]uint64{"Blog": 5, "*.png": 4, "/index.html": 1}},
+		{"Hide", rules.Hide(counts), map[string]uint64{"/index.html": 1}},
+		{"Apply without rules", Rules(nil).Apply(counts), counts},
+		{"Hide without rules", Rules(nil).Hide(counts), counts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !maps.Equal(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFiltersMatch(t *testing.T) {
+	sites, err := NewSiteRules([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agents, err := NewRules([]string{"*bot*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := Filters{Sites: sites, Agents: agents}
+
+	tests := []struct {
+		name      string
+		ip        string
+		userAgent string
+		want      bool
+	}{
+		{"matching site", "10.1.2.3", "Mozilla/5.0", true},
+		{"matching agent", "192.0.2.1", "Googlebot/2.1", true},
+		{"no match", "192.0.2.1", "Mozilla/5.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.Match(tt.ip, "/", "-", tt.userAgent); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Package history imports the history and incremental state files written by classic Webalizer.
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// ReadHistFile reads a webalizer.hist file and returns its monthly totals, keyed by month string in the format "YYYY-MM".
+func ReadHistFile(fileName string) (map[string]*logstats.HFPBVSData, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadHist(file)
+}
+
+// ReadHist reads monthly totals in webalizer.hist format.
+// Each non-comment line holds: month year hits files sites kbytes first_day last_day [pages visits].
+// The pages and visits columns are missing in files written by Webalizer 1.x.
+func ReadHist(r io.Reader) (map[string]*logstats.HFPBVSData, error) {
+	months := make(map[string]*logstats.HFPBVSData)
+
+	lineNr := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 8 && len(fields) != 10 {
+			return nil, fmt.Errorf("line %d: expected 8 or 10 fields, got %d", lineNr, len(fields))
+		}
+		values, err := parseNumbers(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNr, err)
+		}
+
+		// Skip the empty slots Webalizer writes for months without data.
+		if values[2] == 0 {
+			continue
+		}
+
+		data := &logstats.HFPBVSData{
+			Hits:  values[2],
+			Files: values[3],
+			Sites: values[4],
+			Bytes: values[5] * 1024,
+		}
+		if len(values) == 10 {
+			data.Pages = values[8]
+			data.Visits = values[9]
+		}
+
+		key, err := monthKey(values[1], values[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNr, err)
+		}
+		data.Category = monthCategory(key)
+		months[key] = data
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history: %v", err)
+	}
+
+	return months, nil
+}
+
+// ReadCurrentFile reads a webalizer.current file and returns the totals of the month it covers.
+func ReadCurrentFile(fileName string) (map[string]*logstats.HFPBVSData, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadCurrent(file)
+}
+
+// ReadCurrent reads the monthly totals from a webalizer.current incremental state file.
+// Only the header of the file is used: the timestamp of the last record (year month day hour min sec), which
+// determines the month, followed by the totals line as written by save_state in preserve.c of Webalizer 2.x:
+// hits files sites urls referrers agents bytes [pages visits users]. The daily totals and the per-item hash
+// tables that follow are ignored.
+func ReadCurrent(r io.Reader) (map[string]*logstats.HFPBVSData, error) {
+	var records [][]uint64
+
+	lineNr := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() && len(records) < 2 {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		values, err := parseNumbers(strings.Fields(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNr, err)
+		}
+		records = append(records, values)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading state: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("incomplete state file: missing timestamp or totals")
+	}
+
+	timestamp, totals := records[0], records[1]
+	if len(timestamp) < 2 {
+		return nil, fmt.Errorf("invalid timestamp record")
+	}
+	if len(totals) < 7 {
+		return nil, fmt.Errorf("invalid totals record: expected at least 7 fields, got %d", len(totals))
+	}
+
+	key, err := monthKey(timestamp[0], timestamp[1])
+	if err != nil {
+		return nil, err
+	}
+
+	data := &logstats.HFPBVSData{
+		Category: monthCategory(key),
+		Hits:     totals[0],
+		Files:    totals[1],
+		Sites:    totals[2],
+		Bytes:    totals[6],
+	}
+	if len(totals) >= 9 {
+		data.Pages = totals[7]
+		data.Visits = totals[8]
+	}
+
+	return map[string]*logstats.HFPBVSData{key: data}, nil
+}
+
+// parseNumbers parses whitespace-separated numeric fields.
+// Byte counts are written by Webalizer as floating point values, so those are accepted and truncated.
+func parseNumbers(fields []string) ([]uint64, error) {
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(field, 64)
+			if ferr != nil || f < 0 {
+				return nil, fmt.Errorf("invalid number %q", field)
+			}
+			value = uint64(f)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// monthKey returns the month string in the format "YYYY-MM".
+func monthKey(year, month uint64) (string, error) {
+	if month < 1 || month > 12 {
+		return "", fmt.Errorf("invalid month %d", month)
+	}
+	if year < 1970 || year > 9999 {
+		return "", fmt.Errorf("invalid year %d", year)
+	}
+	return fmt.Sprintf("%04d-%02d", year, month), nil
+}
+
+// monthCategory returns the category name for a month string in the format "YYYY-MM".
+func monthCategory(key string) string {
+	date, _ := time.Parse("2006-01", key)
+	return date.Format("Jan")
+}
@@ -0,0 +1,135 @@
+package history
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+func TestReadHist(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]*logstats.HFPBVSData
+		wantErr bool
+	}{
+		{
+			name:  "Webalizer 2.x",
+			input: "# comment\n6 2013 17543 11219 1523 267198 1 30 6271 2417\n",
+			want: map[string]*logstats.HFPBVSData{
+				"2013-06": {Category: "Jun", Hits: 17543, Files: 11219, Sites: 1523, Bytes: 267198 * 1024, Pages: 6271, Visits: 2417},
+			},
+		},
+		{
+			name:  "Webalizer 1.x without pages and visits",
+			input: "12 1999 100 80 10 50 1 31\n",
+			want: map[string]*logstats.HFPBVSData{
+				"1999-12": {Category: "Dec", Hits: 100, Files: 80, Sites: 10, Bytes: 50 * 1024},
+			},
+		},
+		{
+			name:  "empty slots are skipped",
+			input: "5 2013 0 0 0 0 0 0 0 0\n6 2013 1 1 1 1 1 30 1 1\n",
+			want: map[string]*logstats.HFPBVSData{
+				"2013-06": {Category: "Jun", Hits: 1, Files: 1, Sites: 1, Bytes: 1024, Pages: 1, Visits: 1},
+			},
+		},
+		{
+			name:    "wrong number of fields",
+			input:   "6 2013 1 1 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid month",
+			input:   "13 2013 1 1 1 1 1 30 1 1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadHist(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadHist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				assertMonths(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCurrent(t *testing.T) {
+	state, err := os.ReadFile("testdata/webalizer.current")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]*logstats.HFPBVSData
+		wantErr bool
+	}{
+		{
+			name:  "Webalizer 2.x state file",
+			input: string(state),
+			want: map[string]*logstats.HFPBVSData{
+				"2013-06": {Category: "Jun", Hits: 17543, Files: 11219, Sites: 1523, Bytes: 273610493, Pages: 6271, Visits: 2417},
+			},
+		},
+		{
+			name:  "without pages and visits",
+			input: "2013 6 30 23 59 58\n100 80 10 5 3 2 51200\n",
+			want: map[string]*logstats.HFPBVSData{
+				"2013-06": {Category: "Jun", Hits: 100, Files: 80, Sites: 10, Bytes: 51200},
+			},
+		},
+		{
+			name:  "bytes written as a floating point value",
+			input: "2013 6 30 23 59 58\n100 80 10 5 3 2 5.12e+04 40 20 0\n",
+			want: map[string]*logstats.HFPBVSData{
+				"2013-06": {Category: "Jun", Hits: 100, Files: 80, Sites: 10, Bytes: 51200, Pages: 40, Visits: 20},
+			},
+		},
+		{
+			name:    "missing totals",
+			input:   "2013 6 30 23 59 58\n",
+			wantErr: true,
+		},
+		{
+			name:    "short totals",
+			input:   "2013 6 30 23 59 58\n100 80 10\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCurrent(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadCurrent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				assertMonths(t, got, tt.want)
+			}
+		})
+	}
+}
+
+// assertMonths fails the test if the monthly totals got differ from want.
+func assertMonths(t *testing.T, got, want map[string]*logstats.HFPBVSData) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d months, want %d", len(got), len(want))
+	}
+	for month, w := range want {
+		g, ok := got[month]
+		if !ok {
+			t.Fatalf("missing month %s", month)
+		}
+		if *g != *w {
+			t.Errorf("month %s = %+v, want %+v", month, *g, *w)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 // Package main provides a CLI application to process Apache log files.
 package http
 
+import "strings"
+
 var HttpMethods = map[string]string{
 	"GET":        "Retrieve a resource",
 	"POST":       "Create a new resource",
@@ -188,3 +190,134 @@ var ContentTypes = map[string]string{
 	"font/woff2": "Web Open Font Format 2 (WOFF2) fonts",
 	"font/otf":   "OpenType fonts",
 }
+
+// ExtContentTypes maps lower-case file extensions to the content types in ContentTypes.
+// Extensions of server-side scripts map to "text/html", as that is what they usually produce.
+var ExtContentTypes = map[string]string{
+	// Text
+	".html":     "text/html",
+	".htm":      "text/html",
+	".shtml":    "text/html",
+	".xhtml":    "text/html",
+	".php":      "text/html",
+	".asp":      "text/html",
+	".aspx":     "text/html",
+	".jsp":      "text/html",
+	".cgi":      "text/html",
+	".pl":       "text/html",
+	".txt":      "text/plain",
+	".css":      "text/css",
+	".js":       "text/javascript",
+	".mjs":      "text/javascript",
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+
+	// Data interchange
+	".json":   "application/json",
+	".xml":    "application/xml",
+	".yaml":   "application/x-yaml",
+	".yml":    "application/x-yaml",
+	".ndjson": "application/x-ndjson",
+	".atom":   "application/atom+xml",
+	".rss":    "application/rss+xml",
+
+	// Binary
+	".bin":  "application/octet-stream",
+	".exe":  "application/octet-stream",
+	".dmg":  "application/octet-stream",
+	".iso":  "application/octet-stream",
+	".pdf":  "application/pdf",
+	".zip":  "application/zip",
+	".gz":   "application/gzip",
+	".tgz":  "application/gzip",
+	".tar":  "application/x-tar",
+	".rar":  "application/x-rar-compressed",
+	".7z":   "application/x-7z-compressed",
+	".swf":  "application/x-shockwave-flash",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+
+	// Images
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".webp": "image/webp",
+	".ico":  "image/x-icon",
+
+	// Audio
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".aac":  "audio/aac",
+	".oga":  "audio/ogg",
+	".flac": "audio/flac",
+
+	// Video
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".mov":  "video/quicktime",
+	".avi":  "video/x-msvideo",
+
+	// Fonts
+	".ttf":   "font/ttf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".otf":   "font/otf",
+}
+
+// Content categories returned by ContentCategory.
+const (
+	CategoryHTML      = "HTML"
+	CategoryAssets    = "Scripts & Styles"
+	CategoryImages    = "Images"
+	CategoryAudio     = "Audio"
+	CategoryVideo     = "Video"
+	CategoryFonts     = "Fonts"
+	CategoryDownloads = "Downloads"
+	CategoryAPI       = "API"
+	CategoryOther     = "Other"
+)
+
+// ContentCategories lists the content categories in display order.
+var ContentCategories = []string{
+	CategoryHTML, CategoryAssets, CategoryImages, CategoryAudio, CategoryVideo,
+	CategoryFonts, CategoryDownloads, CategoryAPI, CategoryOther,
+}
+
+// ContentCategory returns the content category of a content type in ContentTypes.
+func ContentCategory(contentType string) string {
+	switch contentType {
+	case "text/html":
+		return CategoryHTML
+	case "text/css", "text/javascript", "application/javascript":
+		return CategoryAssets
+	case "application/json", "application/xml", "text/xml", "application/x-yaml", "application/x-ndjson",
+		"application/x-www-form-urlencoded", "multipart/form-data":
+		return CategoryAPI
+	}
+
+	mainType, _, _ := strings.Cut(contentType, "/")
+	switch mainType {
+	case "image":
+		return CategoryImages
+	case "audio":
+		return CategoryAudio
+	case "video":
+		return CategoryVideo
+	case "font":
+		return CategoryFonts
+	case "application":
+		return CategoryDownloads
+	}
+	return CategoryOther
+}
@@ -0,0 +1,194 @@
+// Package iprange matches IP addresses against named lists of IP ranges, such as the published ranges of cloud
+// providers, read from files or downloaded.
+package iprange
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// KnownList is a published list of IP ranges.
+type KnownList struct {
+	// Name is the name of the list, such as the name of the provider.
+	Name string
+	// URL is the address the IP ranges are downloaded from.
+	URL string
+}
+
+// Providers holds the lists of the cloud and datacenter providers that publish their IP ranges, keyed by a short name.
+var Providers = map[string]KnownList{
+	"aws":          {"AWS", "https://ip-ranges.amazonaws.com/ip-ranges.json"},
+	"gcp":          {"Google Cloud", "https://www.gstatic.com/ipranges/cloud.json"},
+	"oracle":       {"Oracle Cloud", "https://docs.oracle.com/en-us/iaas/tools/public_ip_ranges.json"},
+	"digitalocean": {"DigitalOcean", "https://digitalocean.com/geo/google.csv"},
+	"linode":       {"Linode", "https://geoip.linode.com/"},
+}
+
+// Blocklists holds published lists of IP ranges used for abuse, keyed by a short name.
+var Blocklists = map[string]KnownList{
+	"spamhaus-drop":   {"Spamhaus DROP", "https://www.spamhaus.org/drop/drop_v4.json"},
+	"spamhaus-dropv6": {"Spamhaus DROPv6", "https://www.spamhaus.org/drop/drop_v6.json"},
+}
+
+// List is a named list of IP ranges in a file or at a URL.
+type List struct {
+	// Name is the name of the list, such as the name of the provider.
+	Name string
+	// Source is the file or the URL the ranges are read from.
+	Source string
+}
+
+// ParseList parses a list given as the short name of one of the known lists, such as Providers, as NAME=SOURCE,
+// or as a file or URL named after the file or the host of the URL.
+func ParseList(value string, known map[string]KnownList) List {
+	if list, ok := known[strings.ToLower(value)]; ok {
+		return List{Name: list.Name, Source: list.URL}
+	}
+	if name, source, ok := strings.Cut(value, "="); ok && !IsURL(value) {
+		return List{Name: name, Source: source}
+	}
+	if u, err := url.Parse(value); err == nil && IsURL(value) {
+		return List{Name: u.Hostname(), Source: value}
+	}
+	return List{Name: strings.TrimSuffix(filepath.Base(value), filepath.Ext(value)), Source: value}
+}
+
+// Set is a set of IP ranges, each with the name of the list it was read from.
+type Set struct {
+	// names is a map of the names of the lists of the ranges, keyed by the masked range.
+	names map[netip.Prefix]string
+	// lengths holds the prefix lengths of the ranges, longest first, so the most specific range matches.
+	lengths []int
+}
+
+// NewSet returns a new empty Set.
+func NewSet() *Set {
+	return &Set{names: make(map[netip.Prefix]string)}
+}
+
+// Len returns the number of ranges in the set.
+func (s *Set) Len() int {
+	return len(s.names)
+}
+
+// Add adds the range to the set, named name. A range that is in the set already keeps its first name.
+func (s *Set) Add(prefix netip.Prefix, name string) {
+	prefix = prefix.Masked()
+	if _, ok := s.names[prefix]; ok {
+		return
+	}
+	s.names[prefix] = name
+	bits := prefix.Bits()
+	if i, found := slices.BinarySearchFunc(s.lengths, bits, func(a, b int) int { return b - a }); !found {
+		s.lengths = slices.Insert(s.lengths, i, bits)
+	}
+}
+
+// Lookup returns the name of the list of the most specific range holding the IP address ip,
+// and false if ip is not an IP address or not in any range.
+func (s *Set) Lookup(ip string) (string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", false
+	}
+	addr = addr.Unmap()
+	for _, bits := range s.lengths {
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			// The length is of the ranges of the other address family.
+			continue
+		}
+		if name, ok := s.names[prefix]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Read adds the ranges in r, named name, and returns the number of ranges read. Every word in r that is
+// an IP range in CIDR notation or a single IP address is added, so plain lists, CSV files, the JSON files
+// published by cloud providers, and lists such as Spamhaus DROP are all read.
+func (s *Set) Read(r io.Reader, name string) (int, error) {
+	n := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		words := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return !strings.ContainsRune("0123456789abcdefABCDEF.:/", r)
+		})
+		for _, word := range words {
+			if prefix, ok := parseRange(word); ok {
+				s.Add(prefix, name)
+				n++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return n, err
+	}
+	if n == 0 {
+		return 0, errors.New("no IP ranges found")
+	}
+	return n, nil
+}
+
+// parseRange parses an IP range in CIDR notation or a single IP address.
+func parseRange(word string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(word); err == nil {
+		return prefix, true
+	}
+	addr, err := netip.ParseAddr(word)
+	if err != nil || addr.IsUnspecified() {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// IsURL reports whether source is downloaded rather than read from a file.
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Load adds the ranges in the file or at the URL source, named name, and returns the number of ranges read.
+func (s *Set) Load(ctx context.Context, source string, name string) (int, error) {
+	if !IsURL(source) {
+		file, err := os.Open(source)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		n, err := s.Read(file, name)
+		if err != nil {
+			return n, fmt.Errorf("reading %s: %v", source, err)
+		}
+		return n, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("downloading %s: %s", source, resp.Status)
+	}
+	n, err := s.Read(resp.Body, name)
+	if err != nil {
+		return n, fmt.Errorf("downloading %s: %v", source, err)
+	}
+	return n, nil
+}
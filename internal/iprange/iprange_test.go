@@ -0,0 +1,93 @@
+package iprange
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	s := NewSet()
+	s.Add(netip.MustParsePrefix("10.0.0.0/8"), "wide")
+	s.Add(netip.MustParsePrefix("10.1.0.0/16"), "narrow")
+	s.Add(netip.MustParsePrefix("10.1.0.0/16"), "duplicate")
+	s.Add(netip.MustParsePrefix("2001:db8::/32"), "v6")
+	s.Add(netip.MustParsePrefix("192.0.2.77/24"), "unmasked")
+
+	tests := []struct {
+		ip     string
+		want   string
+		wantOK bool
+	}{
+		{"10.2.3.4", "wide", true},
+		{"10.1.3.4", "narrow", true},
+		{"::ffff:10.1.3.4", "narrow", true},
+		{"2001:db8::1", "v6", true},
+		{"192.0.2.1", "unmasked", true},
+		{"172.16.0.1", "", false},
+		{"2001:db9::1", "", false},
+		{"host.example.com", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, ok := s.Lookup(tt.ip)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.ip, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+	if s.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", s.Len())
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"plain list", "192.0.2.0/24\n198.51.100.0/24\n", 2, false},
+		{"single addresses", "192.0.2.1\n2001:db8::1\n", 2, false},
+		{"CSV", "192.0.2.0/24,US,US-CA,San Francisco,\n", 1, false},
+		{"JSON", `{"prefixes": [{"ip_prefix": "192.0.2.0/24", "region": "us-east-1"}]}`, 1, false},
+		{"Spamhaus DROP", `{"cidr":"192.0.2.0/24","sblid":"SBL000001","rir":"arin"}`, 1, false},
+		{"no ranges", "# nothing here\n", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewSet().Read(strings.NewReader(tt.input), "list")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != tt.want {
+				t.Errorf("Read() = %d ranges, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		value string
+		known map[string]KnownList
+		want  List
+	}{
+		{"aws", Providers, List{Name: "AWS", Source: Providers["aws"].URL}},
+		{"AWS", Providers, List{Name: "AWS", Source: Providers["aws"].URL}},
+		{"spamhaus-drop", Blocklists, List{Name: "Spamhaus DROP", Source: Blocklists["spamhaus-drop"].URL}},
+		{"spamhaus-drop", Providers, List{Name: "spamhaus-drop", Source: "spamhaus-drop"}},
+		{"office=/etc/office.txt", Providers, List{Name: "office", Source: "/etc/office.txt"}},
+		{"https://example.com/ranges.txt", Providers, List{Name: "example.com", Source: "https://example.com/ranges.txt"}},
+		{"https://example.com/ranges?list=a=b", Providers, List{Name: "example.com", Source: "https://example.com/ranges?list=a=b"}},
+		{"/etc/ranges/partners.txt", Providers, List{Name: "partners", Source: "/etc/ranges/partners.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ParseList(tt.value, tt.known); got != tt.want {
+				t.Errorf("ParseList(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package logstats
+
+// Change holds a count of two sets of statistics, such as the hits on a URL path before and after a redesign.
+type Change struct {
+	// Before is the count in the earlier statistics.
+	Before uint64
+	// After is the count in the later statistics.
+	After uint64
+}
+
+// Delta returns the difference from Before to After.
+func (c Change) Delta() int64 {
+	return int64(c.After) - int64(c.Before)
+}
+
+// Diff holds the differences between two sets of statistics, such as two saved runs or two periods.
+type Diff struct {
+	// Totals compares the totals, with Current the later and Previous the earlier statistics.
+	Totals Comparison
+	// URLs holds the hits per URL path in either statistics.
+	URLs map[string]Change
+	// NewReferrers holds the hits per referrer domain of the later statistics that are not in the earlier ones.
+	NewReferrers map[string]uint64
+	// NewNotFound holds the 404 Not Found hits per URL path and referrer of the later statistics,
+	// for the URL paths that did not return 404 Not Found in the earlier ones.
+	NewNotFound map[string]map[string]uint64
+}
+
+// Compare returns the differences from before to after, named by the periods or runs they cover.
+// All days of both statistics are compared.
+func Compare(before *LogStats, after *LogStats, beforeName string, afterName string) *Diff {
+	diff := &Diff{
+		Totals: Comparison{
+			Current:        afterName,
+			Previous:       beforeName,
+			CurrentTotals:  after.Summary().totals(),
+			PreviousTotals: before.Summary().totals(),
+		},
+		URLs:         make(map[string]Change),
+		NewReferrers: make(map[string]uint64),
+		NewNotFound:  make(map[string]map[string]uint64),
+	}
+
+	for urlPath, hits := range before.urlHits() {
+		diff.URLs[urlPath] = Change{Before: hits}
+	}
+	for urlPath, hits := range after.urlHits() {
+		change := diff.URLs[urlPath]
+		change.After = hits
+		diff.URLs[urlPath] = change
+	}
+
+	seen := make(map[string]bool)
+	for _, domains := range before.ReferrerDomains {
+		for domain := range domains {
+			seen[domain] = true
+		}
+	}
+	for _, domains := range after.ReferrerDomains {
+		for domain, hb := range domains {
+			if !seen[domain] {
+				diff.NewReferrers[domain] += hb.Hits
+			}
+		}
+	}
+
+	clear(seen)
+	for _, urlPaths := range before.NotFound {
+		for urlPath := range urlPaths {
+			seen[urlPath] = true
+		}
+	}
+	for _, urlPaths := range after.NotFound {
+		for urlPath, referrers := range urlPaths {
+			if seen[urlPath] {
+				continue
+			}
+			if diff.NewNotFound[urlPath] == nil {
+				diff.NewNotFound[urlPath] = make(map[string]uint64)
+			}
+			for referrer, hits := range referrers {
+				diff.NewNotFound[urlPath][referrer] += hits
+			}
+		}
+	}
+
+	return diff
+}
+
+// totals returns the totals of the summary.
+func (summary *Summary) totals() HFPBVSData {
+	return HFPBVSData{
+		Hits:   summary.Hits,
+		Files:  summary.Files,
+		Pages:  summary.Pages,
+		Bytes:  summary.Bytes,
+		Visits: summary.Visits,
+		Sites:  summary.Sites,
+	}
+}
+
+// urlHits returns the hits per URL path over all days.
+func (stats *LogStats) urlHits() map[string]uint64 {
+	hits := make(map[string]uint64)
+	for _, urlPaths := range stats.URLPaths {
+		for urlPath, methods := range urlPaths {
+			for _, hb := range methods {
+				hits[urlPath] += hb.Hits
+			}
+		}
+	}
+	return hits
+}
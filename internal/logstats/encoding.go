@@ -0,0 +1,231 @@
+package logstats
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is a serialization format for LogStats.
+type Format int
+
+const (
+	// JSON encodes LogStats as a JSON document.
+	JSON Format = iota
+	// Gob encodes LogStats as a compact binary gob stream.
+	Gob
+)
+
+// FormatFromPath returns Gob for file names ending in ".gob" and JSON otherwise.
+func FormatFromPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".gob") {
+		return Gob
+	}
+	return JSON
+}
+
+// Save writes the statistics to w in the given format.
+func (stats *LogStats) Save(w io.Writer, format Format) error {
+	switch format {
+	case JSON:
+		return json.NewEncoder(w).Encode(stats)
+	case Gob:
+		return gob.NewEncoder(w).Encode(stats)
+	default:
+		return fmt.Errorf("unknown format %d", format)
+	}
+}
+
+// Load replaces the statistics with the ones read from r in the given format.
+func (stats *LogStats) Load(r io.Reader, format Format) error {
+	loaded := &LogStats{}
+	var err error
+	switch format {
+	case JSON:
+		err = json.NewDecoder(r).Decode(loaded)
+	case Gob:
+		err = gob.NewDecoder(r).Decode(loaded)
+	default:
+		err = fmt.Errorf("unknown format %d", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Empty maps are omitted by gob and missing from files written by older versions.
+	loaded.initMaps()
+	*stats = *loaded
+	return nil
+}
+
+// SaveFile writes the statistics to a file, choosing the format by its extension.
+func (stats *LogStats) SaveFile(fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := stats.Save(f, FormatFromPath(fileName)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFile reads statistics from a file, choosing the format by its extension.
+func LoadFile(fileName string) (*LogStats, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := &LogStats{}
+	if err := stats.Load(f, FormatFromPath(fileName)); err != nil {
+		return nil, fmt.Errorf("loading %s: %v", fileName, err)
+	}
+	return stats, nil
+}
+
+// initMaps allocates any maps that are nil, for NewLogStats and for the maps missing from a file read by Load.
+func (stats *LogStats) initMaps() {
+	if stats.Hits == nil {
+		stats.Hits = make(map[string]uint64)
+	}
+	if stats.Files == nil {
+		stats.Files = make(map[string]uint64)
+	}
+	if stats.NotModified == nil {
+		stats.NotModified = make(map[string]uint64)
+	}
+	if stats.Pages == nil {
+		stats.Pages = make(map[string]uint64)
+	}
+	if stats.Bytes == nil {
+		stats.Bytes = make(map[string]uint64)
+	}
+	if stats.Schemes == nil {
+		stats.Schemes = make(map[string]map[string]uint64)
+	}
+	if stats.Hours == nil {
+		stats.Hours = make(map[string]*[24]HFPB)
+	}
+	if stats.Visits == nil {
+		stats.Visits = make(map[string]map[string]uint64)
+	}
+	if stats.CtrVisits == nil {
+		stats.CtrVisits = make(map[string]map[string]uint64)
+	}
+	if stats.CountryCodes == nil {
+		stats.CountryCodes = make(map[string]string)
+	}
+	if stats.CityLocations == nil {
+		stats.CityLocations = make(map[string]*GeoPoint)
+	}
+	if stats.CtrTraffic == nil {
+		stats.CtrTraffic = make(map[string]map[string]*HitsBytesVisits)
+	}
+	if stats.CityVisits == nil {
+		stats.CityVisits = make(map[string]map[string]uint64)
+	}
+	if stats.ASNs == nil {
+		stats.ASNs = make(map[string]map[string]*HitsBytesVisits)
+	}
+	if stats.Datacenters == nil {
+		stats.Datacenters = make(map[string]map[string]*HitsBytesVisits)
+	}
+	if stats.Flagged == nil {
+		stats.Flagged = make(map[string]map[string]*HitsBytesVisits)
+	}
+	if stats.EntryPages == nil {
+		stats.EntryPages = make(map[string]map[string]uint64)
+	}
+	if stats.ExitPages == nil {
+		stats.ExitPages = make(map[string]map[string]uint64)
+	}
+	if stats.Transitions == nil {
+		stats.Transitions = make(map[string]map[string]uint64)
+	}
+	if stats.Acquisitions == nil {
+		stats.Acquisitions = make(map[string]map[string]uint64)
+	}
+	if stats.VisitMetrics == nil {
+		stats.VisitMetrics = make(map[string]*VisitMetrics)
+	}
+	if stats.PagesPerVisit == nil {
+		stats.PagesPerVisit = make(map[string]map[string]uint64)
+	}
+	if stats.Browsers == nil {
+		stats.Browsers = make(map[string]map[string]uint64)
+	}
+	if stats.OSes == nil {
+		stats.OSes = make(map[string]map[string]uint64)
+	}
+	if stats.Devices == nil {
+		stats.Devices = make(map[string]map[string]uint64)
+	}
+	if stats.FirstVisit == nil {
+		stats.FirstVisit = make(map[string]time.Time)
+	}
+	if stats.LastVisit == nil {
+		stats.LastVisit = make(map[string]time.Time)
+	}
+	if stats.Sites == nil {
+		stats.Sites = make(map[string]map[string]uint64)
+	}
+	if stats.Methods == nil {
+		stats.Methods = make(map[string]map[string]uint64)
+	}
+	if stats.RespCodes == nil {
+		stats.RespCodes = make(map[string]map[uint16]uint64)
+	}
+	if stats.IPs == nil {
+		stats.IPs = make(map[string]map[string]*HitsBytesVisits)
+	}
+	if stats.UserAgents == nil {
+		stats.UserAgents = make(map[string]map[string]*HitsBytesVisits)
+	}
+	if stats.URLPaths == nil {
+		stats.URLPaths = make(map[string]map[string]map[string]*HitsBytes)
+	}
+	if stats.Partial == nil {
+		stats.Partial = make(map[string]map[string]*HitsBytes)
+	}
+	if stats.FullSizes == nil {
+		stats.FullSizes = make(map[string]uint64)
+	}
+	if stats.Referrers == nil {
+		stats.Referrers = make(map[string]map[string]*HitsBytes)
+	}
+	if stats.NotFound == nil {
+		stats.NotFound = make(map[string]map[string]map[string]uint64)
+	}
+	if stats.ContentBytes == nil {
+		stats.ContentBytes = make(map[string]map[string]uint64)
+	}
+	if stats.Sizes == nil {
+		stats.Sizes = make(map[string]map[int]uint64)
+	}
+	if stats.Errors == nil {
+		stats.Errors = make(map[string]map[string]map[uint16]uint64)
+	}
+	if stats.ReferrerDomains == nil {
+		stats.ReferrerDomains = make(map[string]map[string]*HitsBytes)
+	}
+	if stats.SearchStrings == nil {
+		stats.SearchStrings = make(map[string]map[string]uint64)
+	}
+	if stats.Robots == nil {
+		stats.Robots = make(map[string]map[string]uint64)
+	}
+	if stats.RobotVisits == nil {
+		stats.RobotVisits = make(map[string]uint64)
+	}
+	if stats.History == nil {
+		stats.History = make(map[string]*HFPBVSData)
+	}
+}
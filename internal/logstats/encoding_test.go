@@ -0,0 +1,85 @@
+package logstats
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sampleStats returns statistics with an entry in most maps.
+func sampleStats() *LogStats {
+	const date = "2024-03-01"
+	at := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+
+	stats := NewLogStats()
+	stats.Hits[date] = 3
+	stats.Files[date] = 2
+	stats.NotModified[date] = 1
+	stats.Pages[date] = 2
+	stats.Bytes[date] = 300
+	stats.AddScheme(date, "https")
+	stats.UpdateHourStats(date, 12, 300, true, true)
+	stats.Visits[date] = map[string]uint64{"192.0.2.1": 1}
+	stats.Sites[date] = map[string]uint64{"192.0.2.1": 3}
+	stats.Methods[date] = map[string]uint64{"GET": 3}
+	stats.RespCodes[date] = map[uint16]uint64{200: 2, 304: 1}
+	stats.AddEntryPage(date, "/")
+	stats.AddExitPage(date, "/about")
+	stats.AddTransition(date, "/", "/about")
+	stats.AddVisitMetrics(date, time.Minute, 2)
+	stats.AddClient(date, "Firefox", "125", "Linux", "Desktop")
+	stats.UpdateIPStats(date, "192.0.2.1", 300, true)
+	stats.UpdateUserAgentStats(date, "Mozilla/5.0", 300, true)
+	stats.UpdateURLStats(date, "/", "GET", 300)
+	stats.UpdateReferrerStats(date, "https://www.example.com/", 300)
+	stats.AddVisitorHit("192.0.2.1", at)
+	stats.AddPartial(date, "/video.mp4", 1000)
+	stats.AddFullSize("/video.mp4", 5000)
+	stats.AddNotFound(date, "/missing", "-")
+	stats.AddError(date, "/missing", 404)
+	stats.AddContentBytes(date, "HTML", 300)
+	stats.AddSize(date, 300)
+	stats.AddSearchString(date, "webalizer")
+	stats.AddRobot(date, "Googlebot", true)
+	stats.History["2023-12"] = &HFPBVSData{Category: "Dec", Hits: 10, Files: 8, Pages: 5, Bytes: 1000, Visits: 4, Sites: 2}
+	return stats
+}
+
+func TestSaveLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		stats  *LogStats
+	}{
+		{"JSON", JSON, sampleStats()},
+		{"gob", Gob, sampleStats()},
+		{"empty JSON", JSON, NewLogStats()},
+		{"empty gob", Gob, NewLogStats()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.stats.Save(&buf, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			loaded := &LogStats{}
+			if err := loaded.Load(&buf, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, tt.stats) {
+				t.Errorf("Load() = %+v, want %+v", loaded, tt.stats)
+			}
+		})
+	}
+}
+
+func TestNewLogStatsMaps(t *testing.T) {
+	v := reflect.ValueOf(NewLogStats()).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if field.IsExported() && field.Type.Kind() == reflect.Map && v.Field(i).IsNil() {
+			t.Errorf("NewLogStats() leaves %s nil", field.Name)
+		}
+	}
+}
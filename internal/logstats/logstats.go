@@ -2,9 +2,24 @@
 package logstats
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/rbscholtus/go-webalizer/internal/asncache"
+	"github.com/rbscholtus/go-webalizer/internal/citycache"
 	"github.com/rbscholtus/go-webalizer/internal/countrycache"
+	"github.com/rbscholtus/go-webalizer/internal/iprange"
+	"github.com/rbscholtus/go-webalizer/internal/topk"
+	"golang.org/x/net/publicsuffix"
 )
 
 // HitsBytes holds aggregated metrics for hits and bytes.
@@ -21,6 +36,30 @@ func (hb *HitsBytes) AddTraffic(bytes uint64) {
 	hb.Bytes += bytes
 }
 
+// HFPB holds aggregated metrics for hits, files, pages, and bytes.
+type HFPB struct {
+	// Hits is the total number of hits.
+	Hits uint64
+	// Files is the total number of file requests.
+	Files uint64
+	// Pages is the total number of page requests.
+	Pages uint64
+	// Bytes is the total number of bytes transferred.
+	Bytes uint64
+}
+
+// AddTraffic increments the hits, files, pages, and bytes counters.
+func (hfpb *HFPB) AddTraffic(bytes uint64, isFile bool, isPage bool) {
+	hfpb.Hits++
+	hfpb.Bytes += bytes
+	if isFile {
+		hfpb.Files++
+	}
+	if isPage {
+		hfpb.Pages++
+	}
+}
+
 // HitsBytesVisits holds aggregated metrics for hits, bytes, and visits.
 type HitsBytesVisits struct {
 	// Hits is the total number of hits.
@@ -40,25 +79,325 @@ func (hbv *HitsBytesVisits) AddTraffic(bytes uint64, isNewVisit bool) {
 	}
 }
 
+// Limits caps the number of entries kept for high-cardinality dimensions. A limit of zero means unlimited.
+// Capped dimensions keep their most frequent entries using the Space-Saving algorithm: an entry replacing an
+// evicted one inherits its hits, so hits of capped entries may be overestimated by at most the hits of the
+// evicted entry.
+//
+// The URL paths, referrers, and user agents are capped per day, so their memory grows with the number of days
+// held, which is bounded by writing the days to a DaySink. The lists of a period are summed from the days, so
+// an entry evicted on the days it was infrequent is undercounted on those days, even if it ranks high over the
+// period. The visitors are capped over the whole period.
+type Limits struct {
+	// URLPaths is the maximum number of URL paths per day.
+	URLPaths int
+	// Referrers is the maximum number of referrers, and of referring domains, per day.
+	Referrers int
+	// UserAgents is the maximum number of user agents per day.
+	UserAgents int
+	// Visitors is the maximum number of visitors whose first and last hits are kept. An evicted visitor
+	// starts a new visit on its next hit, and counts as a new visitor.
+	Visitors int
+}
+
+// PartialContent holds the partial responses of a URL path and the size of a full response.
+type PartialContent struct {
+	// Hits is the number of 206 Partial Content responses.
+	Hits uint64
+	// Bytes is the number of bytes transferred by the partial responses.
+	Bytes uint64
+	// FullSize is the size of a complete response, or 0 if no complete response was seen.
+	FullSize uint64
+}
+
+// FullDownloads returns the number of full downloads the partial responses add up to.
+// It returns false if the size of a complete response is unknown.
+func (pc *PartialContent) FullDownloads() (float64, bool) {
+	if pc.FullSize == 0 {
+		return 0, false
+	}
+	return float64(pc.Bytes) / float64(pc.FullSize), true
+}
+
+// Summary holds the grand totals of all statistics.
+type Summary struct {
+	// First is the first day covered, in the format "YYYY-MM-DD".
+	First string
+	// Last is the last day covered, in the format "YYYY-MM-DD".
+	Last string
+	// Hits is the total number of hits.
+	Hits uint64
+	// Files is the total number of file requests.
+	Files uint64
+	// Pages is the total number of page requests.
+	Pages uint64
+	// Bytes is the total number of bytes transferred.
+	Bytes uint64
+	// Visits is the total number of visits.
+	Visits uint64
+	// Sites is the number of unique visitor IP addresses.
+	Sites uint64
+	// Errors is the number of hits with a 4xx or 5xx response code.
+	Errors uint64
+}
+
+// ErrorRate returns the percentage of hits with a 4xx or 5xx response code.
+// Hits of imported history count as successful, as their response codes are unknown.
+func (s *Summary) ErrorRate() float64 {
+	if s.Hits == 0 {
+		return 0
+	}
+	return float64(s.Errors) * 100 / float64(s.Hits)
+}
+
+// Comparison holds the totals of a period and of an earlier period to compare it with.
+type Comparison struct {
+	// Current is the name of the period, such as "Jan 2019".
+	Current string
+	// Previous is the name of the earlier period, such as "Jan 2018".
+	Previous string
+	// CurrentTotals are the totals of the period.
+	CurrentTotals HFPBVSData
+	// PreviousTotals are the totals of the earlier period, all zero if it is not covered by the statistics.
+	PreviousTotals HFPBVSData
+}
+
+// PercentChange returns the change from previous to current in percent.
+// It returns false if previous is zero, as the change is then undefined.
+func PercentChange(current uint64, previous uint64) (float64, bool) {
+	if previous == 0 {
+		return 0, false
+	}
+	return (float64(current) - float64(previous)) * 100 / float64(previous), true
+}
+
+// RollingAverages holds the averages of daily metrics over a window of days.
+type RollingAverages struct {
+	// Hits is the average number of hits per day.
+	Hits float64
+	// Visits is the average number of visits per day.
+	Visits float64
+	// Bytes is the average number of bytes transferred per day.
+	Bytes float64
+}
+
+// GeoPoint is a location on earth.
+type GeoPoint struct {
+	// Lat is the latitude in degrees.
+	Lat float64
+	// Lon is the longitude in degrees.
+	Lon float64
+}
+
+// HourlyAverages holds the averages of the metrics of an hour of day over the days of a period.
+type HourlyAverages struct {
+	// Hits is the average number of hits in the hour per day.
+	Hits float64
+	// Files is the average number of files in the hour per day.
+	Files float64
+	// Pages is the average number of pages in the hour per day.
+	Pages float64
+	// Bytes is the average number of bytes transferred in the hour per day.
+	Bytes float64
+}
+
+// VisitMetrics holds aggregated metrics of completed visits.
+type VisitMetrics struct {
+	// Visits is the number of completed visits.
+	Visits uint64
+	// Duration is the total duration of the visits in seconds, from their first to their last hit.
+	Duration uint64
+	// Bounces is the number of visits with at most one page view.
+	Bounces uint64
+}
+
+// AddVisit counts a completed visit.
+func (vm *VisitMetrics) AddVisit(duration time.Duration, pages uint64) {
+	vm.Visits++
+	vm.Duration += uint64(duration / time.Second)
+	if pages <= 1 {
+		vm.Bounces++
+	}
+}
+
+// AvgDuration returns the average visit duration.
+func (vm *VisitMetrics) AvgDuration() time.Duration {
+	if vm.Visits == 0 {
+		return 0
+	}
+	return time.Duration(vm.Duration/vm.Visits) * time.Second
+}
+
+// BounceRate returns the percentage of visits with at most one page view.
+func (vm *VisitMetrics) BounceRate() float64 {
+	if vm.Visits == 0 {
+		return 0
+	}
+	return float64(vm.Bounces) * 100 / float64(vm.Visits)
+}
+
+// PagesBuckets are the buckets of the pages-per-visit distribution, in ascending order.
+var PagesBuckets = []string{"1", "2-3", "4-10", "10+"}
+
+// PagesBucket returns the pages-per-visit bucket for a visit with the given number of page views.
+// A visit without page views, such as one that only downloaded files, counts as a visit of one page.
+func PagesBucket(pages uint64) string {
+	switch {
+	case pages <= 1:
+		return PagesBuckets[0]
+	case pages <= 3:
+		return PagesBuckets[1]
+	case pages <= 10:
+		return PagesBuckets[2]
+	default:
+		return PagesBuckets[3]
+	}
+}
+
+// sizeBinsPerDoubling is the number of response size bins per power of two.
+// With four bins, a size estimated from its bin is off by at most 19%.
+const sizeBinsPerDoubling = 4
+
+// SizeBin returns the response size histogram bin of a size in bytes.
+// Bin 0 holds empty responses; bin i > 0 holds sizes from SizeBinLower(i) up to SizeBinLower(i+1).
+func SizeBin(size uint64) int {
+	if size == 0 {
+		return 0
+	}
+	return 1 + int(math.Floor(sizeBinsPerDoubling*math.Log2(float64(size))))
+}
+
+// SizeBinLower returns the smallest size in bytes of a response size histogram bin.
+func SizeBinLower(bin int) float64 {
+	if bin <= 0 {
+		return 0
+	}
+	return math.Exp2(float64(bin-1) / sizeBinsPerDoubling)
+}
+
+// SizePercentile estimates the p-th percentile (0-100) of the sizes in a response size histogram,
+// returning the geometric middle of the bin holding it, or 0 if the histogram is empty.
+func SizePercentile(hist map[int]uint64, p float64) uint64 {
+	total := uint64(0)
+	for _, count := range hist {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(p / 100 * float64(total)))
+	seen := uint64(0)
+	for _, bin := range slices.Sorted(maps.Keys(hist)) {
+		seen += hist[bin]
+		if seen >= rank {
+			if bin == 0 {
+				return 0
+			}
+			return uint64(math.Round(math.Sqrt(SizeBinLower(bin) * SizeBinLower(bin+1))))
+		}
+	}
+	return 0
+}
+
+// SizeBuckets are the buckets of the response size distribution, in ascending order.
+var SizeBuckets = []string{"0 B", "< 1 KiB", "1-16 KiB", "16-128 KiB", "128 KiB-1 MiB", "1-16 MiB", "16 MiB+"}
+
+// sizeBucketLimits are the exclusive upper limits of the buckets in SizeBuckets but the last.
+// They are powers of two, so every histogram bin falls in a single bucket.
+var sizeBucketLimits = []float64{1, 1 << 10, 16 << 10, 128 << 10, 1 << 20, 16 << 20}
+
+// SizeBucket returns the response size distribution bucket of a response size histogram bin.
+func SizeBucket(bin int) string {
+	lower := SizeBinLower(bin)
+	for i, limit := range sizeBucketLimits {
+		if lower < limit {
+			return SizeBuckets[i]
+		}
+	}
+	return SizeBuckets[len(SizeBuckets)-1]
+}
+
+// SizePercentiles holds estimated percentiles of response sizes in bytes.
+type SizePercentiles struct {
+	// P50 is the median response size.
+	P50 uint64
+	// P90 is the 90th percentile response size.
+	P90 uint64
+	// P99 is the 99th percentile response size.
+	P99 uint64
+}
+
 // LogStats holds aggregated metrics parsed from web server log files.
 type LogStats struct {
 	// Hits is a map of hits per day, keyed by date string in the format "YYYY-MM-DD".
 	Hits map[string]uint64
 	// Files is a map of file requests per day, keyed by date string in the format "YYYY-MM-DD".
+	// Which response codes count as files is decided by the parser, by default 200 and 206.
 	Files map[string]uint64
+	// NotModified is a map of 304 Not Modified responses per day, keyed by date string in the format "YYYY-MM-DD".
+	// These are cache hits for which no file was sent.
+	NotModified map[string]uint64
 	// Pages is a map of page requests per day, keyed by date string in the format "YYYY-MM-DD".
 	Pages map[string]uint64
 	// Bytes is a map of bytes transferred per day, keyed by date string in the format "YYYY-MM-DD".
 	Bytes map[string]uint64
-	// Visits is a map of visits per day, keyed by date string in the format "YYYY-MM-DD" and IP address.
+	// Schemes is a map of hits per URL scheme ("http" or "https") per day, keyed by date string in the format "YYYY-MM-DD"
+	// and scheme. It is only filled for log formats that include the server port.
+	Schemes map[string]map[string]uint64
+	// Hours is a map of hourly statistics per day, keyed by date string in the format "YYYY-MM-DD" and indexed by hour of day.
+	Hours map[string]*[24]HFPB
+	// Visits is a map of visits per day, keyed by date string in the format "YYYY-MM-DD" and visitor.
+	// Visitors are identified by IP address, or by a key returned by VisitorKey.
 	Visits map[string]map[string]uint64
 	// CtrVisits is a map of visits per day, keyed by date string in the format "YYYY-MM-DD" and country.
 	CtrVisits map[string]map[string]uint64
-	// FirstVisit is a map of first visit timestamps, keyed by IP address.
+	// CtrTraffic is a map of country statistics per day, keyed by date string in the format "YYYY-MM-DD" and
+	// country. It is derived from IPs by LookupCountries.
+	CtrTraffic map[string]map[string]*HitsBytesVisits
+	// CountryCodes is a map of the ISO 3166 codes of the countries in CtrVisits and CtrTraffic, keyed by country.
+	// It is derived by LookupCountries.
+	CountryCodes map[string]string
+	// CityVisits is a map of visits per day, keyed by date string in the format "YYYY-MM-DD" and city.
+	// It is derived from Visits by LookupCities.
+	CityVisits map[string]map[string]uint64
+	// CityLocations is a map of the locations of the cities in CityVisits, keyed by city.
+	// It is derived by LookupCities.
+	CityLocations map[string]*GeoPoint
+	// ASNs is a map of autonomous system statistics per day, keyed by date string in the format "YYYY-MM-DD" and
+	// autonomous system. It is derived from IPs by LookupASNs.
+	ASNs map[string]map[string]*HitsBytesVisits
+	// Datacenters is a map of the statistics of visitors from the IP ranges of cloud and datacenter providers per
+	// day, keyed by date string in the format "YYYY-MM-DD" and provider. It is derived from IPs by LookupDatacenters.
+	Datacenters map[string]map[string]*HitsBytesVisits
+	// Flagged is a map of the statistics of visitors from the IP ranges of blocklists per day, keyed by date
+	// string in the format "YYYY-MM-DD" and blocklist. It is derived from IPs by LookupFlagged.
+	Flagged map[string]map[string]*HitsBytesVisits
+	// EntryPages is a map of visits per entry page per day, keyed by date string in the format "YYYY-MM-DD" and URL path.
+	EntryPages map[string]map[string]uint64
+	// ExitPages is a map of visits per exit page per day, keyed by date string in the format "YYYY-MM-DD" and URL path.
+	ExitPages map[string]map[string]uint64
+	// Transitions is a map of page-to-page transitions within visits per day, keyed by date string in the format
+	// "YYYY-MM-DD" and a key returned by TransitionKey.
+	Transitions map[string]map[string]uint64
+	// Acquisitions is a map of visits per referring domain, entry page, and exit page per day, keyed by date
+	// string in the format "YYYY-MM-DD" of the start of the visits and a key returned by AcquisitionKey.
+	Acquisitions map[string]map[string]uint64
+	// VisitMetrics is a map of completed visit metrics per day, keyed by the date string the visits started on in the format "YYYY-MM-DD".
+	VisitMetrics map[string]*VisitMetrics
+	// PagesPerVisit is a map of completed visits per day, keyed by the date string the visits started on in the format "YYYY-MM-DD" and pages-per-visit bucket.
+	PagesPerVisit map[string]map[string]uint64
+	// Browsers is a map of visits per browser per day, keyed by date string in the format "YYYY-MM-DD" and a key returned by BrowserKey.
+	Browsers map[string]map[string]uint64
+	// OSes is a map of visits per operating system per day, keyed by date string in the format "YYYY-MM-DD" and operating system.
+	OSes map[string]map[string]uint64
+	// Devices is a map of visits per device type per day, keyed by date string in the format "YYYY-MM-DD" and device type.
+	Devices map[string]map[string]uint64
+	// FirstVisit is a map of first visit timestamps, keyed by visitor.
 	FirstVisit map[string]time.Time
-	// LastVisit is a map of last visit timestamps, keyed by IP address.
+	// LastVisit is a map of last visit timestamps, keyed by visitor.
 	LastVisit map[string]time.Time
-	// Sites is a map of sites per day, keyed by date string in the format "YYYY-MM-DD" and IP address.
+	// Sites is a map of sites per day, keyed by date string in the format "YYYY-MM-DD" and visitor.
 	Sites map[string]map[string]uint64
 	// Methods is a map of HTTP methods per day, keyed by date string in the format "YYYY-MM-DD" and method.
 	Methods map[string]map[string]uint64
@@ -70,29 +409,236 @@ type LogStats struct {
 	UserAgents map[string]map[string]*HitsBytesVisits
 	// URLPaths is a map of URL path statistics per day, keyed by date string in the format "YYYY-MM-DD", URL path, and method.
 	URLPaths map[string]map[string]map[string]*HitsBytes
+	// Partial is a map of 206 Partial Content responses per day, keyed by date string in the format "YYYY-MM-DD"
+	// and URL path, as logged for range requests of audio and video.
+	Partial map[string]map[string]*HitsBytes
+	// FullSizes is a map of the largest complete (200 OK) response size, keyed by URL path.
+	// It is used to estimate how many full downloads the partial responses add up to.
+	FullSizes map[string]uint64
 	// Referrers is a map of referrer statistics per day, keyed by date string in the format "YYYY-MM-DD" and referrer.
 	Referrers map[string]map[string]*HitsBytes
+	// NotFound is a map of hits that returned 404 Not Found per day, keyed by date string in the format "YYYY-MM-DD",
+	// URL path, and referrer, so broken links can be traced to the pages linking to them.
+	NotFound map[string]map[string]map[string]uint64
+	// ContentBytes is a map of bytes transferred per content category per day, keyed by date string in the format
+	// "YYYY-MM-DD" and content category.
+	ContentBytes map[string]map[string]uint64
+	// Sizes is a map of response size histograms per day, keyed by date string in the format "YYYY-MM-DD" and
+	// histogram bin as returned by SizeBin.
+	Sizes map[string]map[int]uint64
+	// Errors is a map of hits that returned a 4xx or 5xx response code per day, keyed by date string in the format
+	// "YYYY-MM-DD", URL path, and response code.
+	Errors map[string]map[string]map[uint16]uint64
+	// ReferrerDomains is a map of referrer statistics per day, keyed by date string in the format "YYYY-MM-DD" and
+	// registered domain of the referrer, as returned by ReferrerDomain.
+	ReferrerDomains map[string]map[string]*HitsBytes
+	// SearchStrings is a map of hits per search string per day, keyed by date string in the format "YYYY-MM-DD" and search string.
+	SearchStrings map[string]map[string]uint64
+	// Robots is a map of hits per robot per day, keyed by date string in the format "YYYY-MM-DD" and the name of the robot,
+	// as classified from its user agent. Robots are counted even if they are left out of all other statistics.
+	Robots map[string]map[string]uint64
+	// RobotVisits is a map of the visits by robots per day, keyed by date string in the format "YYYY-MM-DD".
+	// It stays empty if robots are left out of all other statistics.
+	RobotVisits map[string]uint64
+	// History is a map of imported monthly totals, keyed by month string in the format "YYYY-MM".
+	History map[string]*HFPBVSData
+
+	// limits caps the capped dimensions.
+	limits Limits
+	// sketches holds the top-K summaries of the capped dimensions, keyed by date string.
+	sketches map[string]*daySketches
+	// visitors holds the top-K summary of the visitors in FirstVisit and LastVisit, or is nil.
+	visitors *topk.SpaceSaving
+}
+
+// daySketches holds the top-K summaries of the capped dimensions for one day.
+type daySketches struct {
+	urlPaths        *topk.SpaceSaving
+	referrers       *topk.SpaceSaving
+	referrerDomains *topk.SpaceSaving
+	userAgents      *topk.SpaceSaving
 }
 
 // NewLogStats returns a new LogStats instance.
 func NewLogStats() *LogStats {
-	return &LogStats{
-		Hits:       make(map[string]uint64),
-		Files:      make(map[string]uint64),
-		Pages:      make(map[string]uint64),
-		Bytes:      make(map[string]uint64),
-		Visits:     make(map[string]map[string]uint64),
-		CtrVisits:  make(map[string]map[string]uint64),
-		FirstVisit: make(map[string]time.Time),
-		LastVisit:  make(map[string]time.Time),
-		Sites:      make(map[string]map[string]uint64),
-		Methods:    make(map[string]map[string]uint64),
-		RespCodes:  make(map[string]map[uint16]uint64),
-		IPs:        make(map[string]map[string]*HitsBytesVisits),
-		UserAgents: make(map[string]map[string]*HitsBytesVisits),
-		URLPaths:   make(map[string]map[string]map[string]*HitsBytes),
-		Referrers:  make(map[string]map[string]*HitsBytes),
+	stats := &LogStats{}
+	stats.initMaps()
+	return stats
+}
+
+// SetLimits caps the number of entries kept for high-cardinality dimensions.
+// Limits apply to entries added after the call.
+func (stats *LogStats) SetLimits(limits Limits) {
+	stats.limits = limits
+	stats.visitors = nil
+	if limits.Visitors > 0 {
+		stats.visitors = topk.New(limits.Visitors)
+	}
+}
+
+// noSketches is used for all days when no dimension is capped per day.
+var noSketches = &daySketches{}
+
+// daySketches returns the top-K summaries for a date, creating them if needed.
+func (stats *LogStats) daySketches(date string) *daySketches {
+	if stats.limits.URLPaths == 0 && stats.limits.Referrers == 0 && stats.limits.UserAgents == 0 {
+		return noSketches
+	}
+	if stats.sketches == nil {
+		stats.sketches = make(map[string]*daySketches)
+	}
+	ds, ok := stats.sketches[date]
+	if !ok {
+		ds = &daySketches{}
+		if stats.limits.URLPaths > 0 {
+			ds.urlPaths = topk.New(stats.limits.URLPaths)
+		}
+		if stats.limits.Referrers > 0 {
+			ds.referrers = topk.New(stats.limits.Referrers)
+			ds.referrerDomains = topk.New(stats.limits.Referrers)
+		}
+		if stats.limits.UserAgents > 0 {
+			ds.userAgents = topk.New(stats.limits.UserAgents)
+		}
+		stats.sketches[date] = ds
+	}
+	return ds
+}
+
+// track counts a hit for key in a top-K summary and returns the hits a new key inherits.
+// The summary may be nil for uncapped dimensions. An evicted key is passed to evict.
+func track(sketch *topk.SpaceSaving, key string, evict func(key string)) uint64 {
+	if sketch == nil {
+		return 0
+	}
+	evicted, inherited, ok := sketch.Offer(key)
+	if !ok {
+		return 0
+	}
+	evict(evicted)
+	return inherited
+}
+
+// AddScheme counts a hit served over the given URL scheme.
+func (stats *LogStats) AddScheme(date string, scheme string) {
+	if stats.Schemes[date] == nil {
+		stats.Schemes[date] = make(map[string]uint64)
+	}
+	stats.Schemes[date][scheme]++
+}
+
+// UpdateHourStats updates the hourly statistics for a given date and hour of day.
+func (stats *LogStats) UpdateHourStats(date string, hour int, bytes uint64, isFile bool, isPage bool) {
+	if stats.Hours[date] == nil {
+		stats.Hours[date] = &[24]HFPB{}
+	}
+	stats.Hours[date][hour].AddTraffic(bytes, isFile, isPage)
+}
+
+// AddEntryPage counts a visit that started on the given page.
+func (stats *LogStats) AddEntryPage(date string, URLPath string) {
+	if stats.EntryPages[date] == nil {
+		stats.EntryPages[date] = make(map[string]uint64)
+	}
+	stats.EntryPages[date][URLPath]++
+}
+
+// AddExitPage counts a visit that ended on the given page.
+func (stats *LogStats) AddExitPage(date string, URLPath string) {
+	if stats.ExitPages[date] == nil {
+		stats.ExitPages[date] = make(map[string]uint64)
+	}
+	stats.ExitPages[date][URLPath]++
+}
+
+// TransitionKey returns the key of a transition from one page to the next within a visit.
+func TransitionKey(from string, to string) string {
+	return from + "\t" + to
+}
+
+// TransitionPages returns the pages a transition key was made of.
+func TransitionPages(key string) (from string, to string) {
+	from, to, _ = strings.Cut(key, "\t")
+	return from, to
+}
+
+// AddTransition counts a visit moving from one page to the next.
+func (stats *LogStats) AddTransition(date string, from string, to string) {
+	if stats.Transitions[date] == nil {
+		stats.Transitions[date] = make(map[string]uint64)
+	}
+	stats.Transitions[date][TransitionKey(from, to)]++
+}
+
+// DirectReferrer is the referring domain of visits that did not come from another site.
+const DirectReferrer = "(direct)"
+
+// AcquisitionKey returns the key of the visits that came from the referring domain, started on the
+// entry page, and ended on the exit page.
+func AcquisitionKey(referrer string, entry string, exit string) string {
+	return referrer + "\t" + entry + "\t" + exit
+}
+
+// AcquisitionPages returns the referring domain, entry page, and exit page an acquisition key was made of.
+func AcquisitionPages(key string) (referrer string, entry string, exit string) {
+	referrer, rest, _ := strings.Cut(key, "\t")
+	entry, exit, _ = strings.Cut(rest, "\t")
+	return referrer, entry, exit
+}
+
+// AddAcquisition counts a completed visit that started on the given date, came from the referring domain,
+// and went from the entry page to the exit page.
+func (stats *LogStats) AddAcquisition(date string, referrer string, entry string, exit string) {
+	if stats.Acquisitions[date] == nil {
+		stats.Acquisitions[date] = make(map[string]uint64)
+	}
+	stats.Acquisitions[date][AcquisitionKey(referrer, entry, exit)]++
+}
+
+// AddVisitMetrics counts a completed visit that started on the given date,
+// updating the visit metrics and the pages-per-visit distribution.
+func (stats *LogStats) AddVisitMetrics(date string, duration time.Duration, pages uint64) {
+	if stats.VisitMetrics[date] == nil {
+		stats.VisitMetrics[date] = &VisitMetrics{}
+	}
+	stats.VisitMetrics[date].AddVisit(duration, pages)
+
+	if stats.PagesPerVisit[date] == nil {
+		stats.PagesPerVisit[date] = make(map[string]uint64)
+	}
+	stats.PagesPerVisit[date][PagesBucket(pages)]++
+}
+
+// BrowserKey returns the key of a browser family and major version, such as "Chrome/126".
+func BrowserKey(browser string, version string) string {
+	if version == "" {
+		return browser
+	}
+	return browser + "/" + version
+}
+
+// BrowserFamily returns the browser family of a key returned by BrowserKey.
+func BrowserFamily(key string) string {
+	family, _, _ := strings.Cut(key, "/")
+	return family
+}
+
+// AddClient counts a visit made with the given browser, operating system, and device type.
+func (stats *LogStats) AddClient(date string, browser string, version string, os string, device string) {
+	if stats.Browsers[date] == nil {
+		stats.Browsers[date] = make(map[string]uint64)
+	}
+	stats.Browsers[date][BrowserKey(browser, version)]++
+
+	if stats.OSes[date] == nil {
+		stats.OSes[date] = make(map[string]uint64)
+	}
+	stats.OSes[date][os]++
+
+	if stats.Devices[date] == nil {
+		stats.Devices[date] = make(map[string]uint64)
 	}
+	stats.Devices[date][device]++
 }
 
 // UpdateIPStats updates the IP statistics for a given date and IP address.
@@ -111,8 +657,11 @@ func (stats *LogStats) UpdateUserAgentStats(date string, userAgent string, bytes
 	if stats.UserAgents[date] == nil {
 		stats.UserAgents[date] = make(map[string]*HitsBytesVisits)
 	}
+	inherited := track(stats.daySketches(date).userAgents, userAgent, func(key string) {
+		delete(stats.UserAgents[date], key)
+	})
 	if _, ok := stats.UserAgents[date][userAgent]; !ok {
-		stats.UserAgents[date][userAgent] = &HitsBytesVisits{}
+		stats.UserAgents[date][userAgent] = &HitsBytesVisits{Hits: inherited}
 	}
 	stats.UserAgents[date][userAgent].AddTraffic(bytes, isNewVisit)
 }
@@ -122,24 +671,273 @@ func (stats *LogStats) UpdateURLStats(date string, URLPath string, method string
 	if stats.URLPaths[date] == nil {
 		stats.URLPaths[date] = make(map[string]map[string]*HitsBytes)
 	}
+	inherited := track(stats.daySketches(date).urlPaths, URLPath, func(key string) {
+		delete(stats.URLPaths[date], key)
+	})
 	if _, ok := stats.URLPaths[date][URLPath]; !ok {
 		stats.URLPaths[date][URLPath] = make(map[string]*HitsBytes)
 	}
 	if _, ok := stats.URLPaths[date][URLPath][method]; !ok {
-		stats.URLPaths[date][URLPath][method] = &HitsBytes{}
+		stats.URLPaths[date][URLPath][method] = &HitsBytes{Hits: inherited}
 	}
 	stats.URLPaths[date][URLPath][method].AddTraffic(bytes)
 }
 
+// AddVisitorHit records the time of a hit by a visitor as its last hit, and as its first hit if it was not
+// seen before.
+func (stats *LogStats) AddVisitorHit(visitor string, t time.Time) {
+	track(stats.visitors, visitor, func(key string) {
+		delete(stats.FirstVisit, key)
+		delete(stats.LastVisit, key)
+	})
+	if _, ok := stats.FirstVisit[visitor]; !ok {
+		stats.FirstVisit[visitor] = t
+	}
+	stats.LastVisit[visitor] = t
+}
+
+// AddPartial counts a 206 Partial Content response for a URL path.
+func (stats *LogStats) AddPartial(date string, URLPath string, bytes uint64) {
+	if stats.Partial[date] == nil {
+		stats.Partial[date] = make(map[string]*HitsBytes)
+	}
+	if stats.Partial[date][URLPath] == nil {
+		stats.Partial[date][URLPath] = &HitsBytes{}
+	}
+	stats.Partial[date][URLPath].AddTraffic(bytes)
+}
+
+// AddFullSize records the size of a complete response for a URL path, keeping the largest size.
+func (stats *LogStats) AddFullSize(URLPath string, bytes uint64) {
+	if bytes > stats.FullSizes[URLPath] {
+		stats.FullSizes[URLPath] = bytes
+	}
+}
+
 // UpdateReferrerStats updates the referrer statistics for a given date and referrer.
 func (stats *LogStats) UpdateReferrerStats(date string, Referrer string, bytes uint64) {
 	if stats.Referrers[date] == nil {
 		stats.Referrers[date] = make(map[string]*HitsBytes)
 	}
+	inherited := track(stats.daySketches(date).referrers, Referrer, func(key string) {
+		delete(stats.Referrers[date], key)
+	})
 	if _, ok := stats.Referrers[date][Referrer]; !ok {
-		stats.Referrers[date][Referrer] = &HitsBytes{}
+		stats.Referrers[date][Referrer] = &HitsBytes{Hits: inherited}
 	}
 	stats.Referrers[date][Referrer].AddTraffic(bytes)
+
+	if domain, ok := ReferrerDomain(Referrer); ok {
+		if stats.ReferrerDomains[date] == nil {
+			stats.ReferrerDomains[date] = make(map[string]*HitsBytes)
+		}
+		inherited := track(stats.daySketches(date).referrerDomains, domain, func(key string) {
+			delete(stats.ReferrerDomains[date], key)
+		})
+		if _, ok := stats.ReferrerDomains[date][domain]; !ok {
+			stats.ReferrerDomains[date][domain] = &HitsBytes{Hits: inherited}
+		}
+		stats.ReferrerDomains[date][domain].AddTraffic(bytes)
+	}
+}
+
+// AddNotFound counts a hit on a URL path that returned 404 Not Found, attributed to its referrer.
+func (stats *LogStats) AddNotFound(date string, URLPath string, referrer string) {
+	if stats.NotFound[date] == nil {
+		stats.NotFound[date] = make(map[string]map[string]uint64)
+	}
+	if stats.NotFound[date][URLPath] == nil {
+		stats.NotFound[date][URLPath] = make(map[string]uint64)
+	}
+	stats.NotFound[date][URLPath][referrer]++
+}
+
+// AddContentBytes adds the bytes of a response to its content category.
+func (stats *LogStats) AddContentBytes(date string, category string, bytes uint64) {
+	if stats.ContentBytes[date] == nil {
+		stats.ContentBytes[date] = make(map[string]uint64)
+	}
+	stats.ContentBytes[date][category] += bytes
+}
+
+// AddSize counts a response in the response size histogram.
+func (stats *LogStats) AddSize(date string, bytes uint64) {
+	if stats.Sizes[date] == nil {
+		stats.Sizes[date] = make(map[int]uint64)
+	}
+	stats.Sizes[date][SizeBin(bytes)]++
+}
+
+// AddError counts a hit on a URL path that returned an error response code.
+func (stats *LogStats) AddError(date string, URLPath string, code uint16) {
+	if stats.Errors[date] == nil {
+		stats.Errors[date] = make(map[string]map[uint16]uint64)
+	}
+	if stats.Errors[date][URLPath] == nil {
+		stats.Errors[date][URLPath] = make(map[uint16]uint64)
+	}
+	stats.Errors[date][URLPath][code]++
+}
+
+// ReferrerDomain returns the registered domain of a referrer URL, such as "example.co.uk" for
+// "https://www.example.co.uk/page", using the public suffix list. Hosts that are IP addresses or
+// public suffixes themselves are returned as they are. ok is false if the referrer is not a URL.
+func ReferrerDomain(referrer string) (string, bool) {
+	if !strings.HasPrefix(referrer, "http") {
+		return "", false
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if net.ParseIP(host) != nil {
+		return host, true
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host, true
+	}
+	return domain, true
+}
+
+// AddSearchString counts a hit referred by a search engine with the given search string.
+func (stats *LogStats) AddSearchString(date string, query string) {
+	if stats.SearchStrings[date] == nil {
+		stats.SearchStrings[date] = make(map[string]uint64)
+	}
+	stats.SearchStrings[date][query]++
+}
+
+// AddRobot counts a hit by a robot, and whether it started a visit.
+func (stats *LogStats) AddRobot(date string, robot string, newVisit bool) {
+	if stats.Robots[date] == nil {
+		stats.Robots[date] = make(map[string]uint64)
+	}
+	stats.Robots[date][robot]++
+	if newVisit {
+		stats.RobotVisits[date]++
+	}
+}
+
+// Dates returns the dates for which per-day statistics are held, in no particular order.
+// Exit pages and visit metrics are recorded when a visit ends, which may be after the other statistics
+// of its day were evicted. Methods and robots may be counted for days without hits if they are excluded.
+func (stats *LogStats) Dates() []string {
+	seen := make(map[string]struct{}, len(stats.Hits))
+	for date := range stats.Hits {
+		seen[date] = struct{}{}
+	}
+	for date := range stats.Methods {
+		seen[date] = struct{}{}
+	}
+	for date := range stats.ExitPages {
+		seen[date] = struct{}{}
+	}
+	for date := range stats.VisitMetrics {
+		seen[date] = struct{}{}
+	}
+	for date := range stats.Robots {
+		seen[date] = struct{}{}
+	}
+	return slices.Collect(maps.Keys(seen))
+}
+
+// Months returns the months of the parsed log in ascending order, in the format "YYYY-MM".
+func (stats *LogStats) Months() []string {
+	seen := make(map[string]struct{})
+	for date := range stats.Hits {
+		seen[date[:7]] = struct{}{}
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// MonthStats returns a copy of the statistics of the days in a month, in the format "YYYY-MM", so the
+// aggregates of the last month describe that month. First and last visits are kept for all visitors,
+// and imported history is left out.
+func (stats *LogStats) MonthStats(month string) *LogStats {
+	return stats.RangeStats(month+"-01", month+"-31")
+}
+
+// RangeStats returns a copy of the statistics of the days from first to last, inclusive, in the
+// format "YYYY-MM-DD". First and last visits are kept for all visitors, and imported history is left out.
+func (stats *LogStats) RangeStats(first string, last string) *LogStats {
+	rs := NewLogStats()
+	rs.Merge(stats)
+	rs.History = make(map[string]*HFPBVSData)
+	for _, date := range rs.Dates() {
+		if date < first || date > last {
+			rs.Evict(date)
+		}
+	}
+	return rs
+}
+
+// Evict removes all per-day statistics for the given date.
+// Per-visitor timestamps and imported history are kept.
+func (stats *LogStats) Evict(date string) {
+	delete(stats.Hits, date)
+	delete(stats.Files, date)
+	delete(stats.NotModified, date)
+	delete(stats.Pages, date)
+	delete(stats.Bytes, date)
+	delete(stats.Schemes, date)
+	delete(stats.Hours, date)
+	delete(stats.Visits, date)
+	delete(stats.CtrVisits, date)
+	delete(stats.CtrTraffic, date)
+	delete(stats.CityVisits, date)
+	delete(stats.ASNs, date)
+	delete(stats.Datacenters, date)
+	delete(stats.Flagged, date)
+	delete(stats.EntryPages, date)
+	delete(stats.ExitPages, date)
+	delete(stats.Transitions, date)
+	delete(stats.Acquisitions, date)
+	delete(stats.VisitMetrics, date)
+	delete(stats.PagesPerVisit, date)
+	delete(stats.Browsers, date)
+	delete(stats.OSes, date)
+	delete(stats.Devices, date)
+	delete(stats.Sites, date)
+	delete(stats.Methods, date)
+	delete(stats.RespCodes, date)
+	delete(stats.IPs, date)
+	delete(stats.UserAgents, date)
+	delete(stats.URLPaths, date)
+	delete(stats.Partial, date)
+	delete(stats.Referrers, date)
+	delete(stats.NotFound, date)
+	delete(stats.ContentBytes, date)
+	delete(stats.Sizes, date)
+	delete(stats.Errors, date)
+	delete(stats.ReferrerDomains, date)
+	delete(stats.SearchStrings, date)
+	delete(stats.Robots, date)
+	delete(stats.RobotVisits, date)
+	delete(stats.sketches, date)
+}
+
+// ImportHistory adds imported monthly totals, keyed by month string in the format "YYYY-MM".
+// Totals for a month that was already imported are replaced.
+func (stats *LogStats) ImportHistory(months map[string]*HFPBVSData) {
+	for month, data := range months {
+		value := *data
+		stats.History[month] = &value
+	}
+}
+
+// VisitorKey returns the key identifying a visitor by IP address and user agent.
+func VisitorKey(ip string, userAgent string) string {
+	return ip + "\t" + userAgent
+}
+
+// VisitorIP returns the IP address of a visitor key.
+// Keys that identify visitors by IP address alone are returned unchanged.
+func VisitorIP(visitor string) string {
+	ip, _, _ := strings.Cut(visitor, "\t")
+	return ip
 }
 
 // uniqueVisitors returns a list of unique visitor IP addresses.
@@ -148,31 +946,81 @@ func uniqueVisitors(visitorsByDate map[string]map[string]uint64) []string {
 	seen := make(map[string]struct{})
 	for _, visitors := range visitorsByDate {
 		for visitor := range visitors {
-			if _, exists := seen[visitor]; exists {
+			ip := VisitorIP(visitor)
+			if _, exists := seen[ip]; exists {
 				continue
 			}
-			seen[visitor] = struct{}{}
-			keys = append(keys, visitor)
+			seen[ip] = struct{}{}
+			keys = append(keys, ip)
 		}
 	}
 	return keys
 }
 
-// LookupCountries performs a country lookup for all unique visitors and updates the CtrVisits map.
-func (stats *LogStats) LookupCountries() error {
+// CountryDB is the default GeoLite2-Country database that countries are looked up in.
+const CountryDB = "./GeoLite2-Country.mmdb"
+
+// GeoWorkers is the default number of country lookups run in parallel.
+const GeoWorkers = 32
+
+// GeoTimeout is the default time a single country lookup may take, resolving a hostname, before it fails.
+const GeoTimeout = 5 * time.Second
+
+// GeoCacheTTL is the default age after which the countries in the cache file are looked up again.
+const GeoCacheTTL = 7 * 24 * time.Hour
+
+// CountryOptions selects how the countries of visitors are looked up.
+type CountryOptions struct {
+	// DB is the country database, such as CountryDB.
+	DB string
+	// Workers is the number of lookups run in parallel.
+	Workers int
+	// Timeout is the time a single lookup may take before it fails.
+	Timeout time.Duration
+	// Cache is the file the countries are kept in between runs, or empty for none.
+	Cache string
+	// CacheTTL is the age after which the countries in the cache file are looked up again.
+	CacheTTL time.Duration
+	// Offline leaves hostnames unresolved, so the lookups do not use the network.
+	Offline bool
+	// Fallback looks up the IP addresses that are not in the database, or is nil.
+	Fallback *countrycache.WebService
+}
+
+// LookupCountries performs a country lookup for all unique visitors as selected by opts, and updates
+// the CtrVisits and CtrTraffic maps. The codes of the countries are added to CountryCodes. Visitors that are not in
+// the database are counted as countrycache.Unknown, and visitors whose lookup failed as countrycache.Unresolved. It returns the number of failed lookups.
+// A cache file that cannot be read or written is skipped with a warning. If ctx is canceled, the lookups
+// stop and the error of ctx is returned.
+func (stats *LogStats) LookupCountries(ctx context.Context, opts CountryOptions) (int, error) {
 	// Create a new country cache instance.
-	cl, err := countrycache.NewCountryLookup("./GeoLite2-Country.mmdb", 32)
+	cl, err := countrycache.NewCountryLookup(opts.DB, opts.Workers, opts.Timeout)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer cl.Close()
+	cl.SetOffline(opts.Offline)
+	cl.SetFallback(opts.Fallback)
+	if opts.Cache != "" {
+		if err := cl.LoadCache(opts.Cache, opts.CacheTTL); err != nil {
+			slog.Warn("could not read the country cache", "error", err)
+		}
+		defer func() {
+			if err := cl.SaveCache(opts.Cache); err != nil {
+				slog.Warn("could not write the country cache", "error", err)
+			}
+		}()
+	}
 
 	// Get a list of unique visitor IP addresses.
 	visitors := uniqueVisitors(stats.Visits)
 	// Perform a parallel country lookup for all unique visitors.
-	cl.ParallelLookup(visitors)
+	if err := cl.ParallelLookup(ctx, visitors); err != nil {
+		return cl.Failures(), err
+	}
 
-	// Update the CtrVisits map with the country lookup results.
+	// Rebuild the CtrVisits map with the country lookup results.
+	stats.CtrVisits = make(map[string]map[string]uint64)
 	for date, ipMaps := range stats.Visits {
 		for visitor, visits := range ipMaps {
 			if stats.CtrVisits[date] == nil {
@@ -180,43 +1028,144 @@ func (stats *LogStats) LookupCountries() error {
 			}
 
 			// Get the country name for the visitor IP address.
-			if country, ok := cl.Lookup(visitor); ok {
+			if country, ok := cl.Lookup(VisitorIP(visitor)); ok {
 				// Increment the visit count for the country.
 				stats.CtrVisits[date][country] += visits
 			}
 		}
 	}
 
-	return nil
-}
+	// Rebuild the CtrTraffic map from the IP statistics.
+	stats.CtrTraffic = make(map[string]map[string]*HitsBytesVisits)
+	for date, ips := range stats.IPs {
+		for ip, hbv := range ips {
+			country, _ := cl.LookupOne(ctx, ip)
+			if stats.CtrTraffic[date] == nil {
+				stats.CtrTraffic[date] = make(map[string]*HitsBytesVisits)
+			}
+			stats.CtrTraffic[date][country] = addHitsBytesVisits(stats.CtrTraffic[date][country], hbv)
+			if code, ok := cl.Code(country); ok {
+				stats.CountryCodes[country] = code
+			}
+		}
+	}
 
-// HFPBVSData holds aggregated metrics for hits, files, pages, bytes, visits, and sites.
-type HFPBVSData struct {
-	// Category is the category name (e.g. month name).
-	Category string
-	// Hits is the total number of hits.
-	Hits uint64
-	// Files is the total number of file requests.
-	Files uint64
-	// Pages is the total number of page requests.
-	Pages uint64
-	// Bytes is the total number of bytes transferred.
-	Bytes uint64
-	// Visits is the total number of visits.
-	Visits uint64
-	// Sites is the total number of sites.
-	Sites uint64
+	return cl.Failures(), nil
 }
 
-// CategoryData holds a category and its corresponding count.
-type CategoryData struct {
-	// Category is the name of the category.
+// LookupCities looks up the city of all unique visitors in the GeoLite2-City database at dbPath
+// and rebuilds the CityVisits map. The locations of the cities are added to CityLocations.
+func (stats *LogStats) LookupCities(dbPath string) error {
+	cl, err := citycache.NewCityLookup(dbPath)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	stats.CityVisits = make(map[string]map[string]uint64)
+	for date, visitors := range stats.Visits {
+		for visitor, visits := range visitors {
+			if city, ok := cl.Lookup(VisitorIP(visitor)); ok {
+				if stats.CityVisits[date] == nil {
+					stats.CityVisits[date] = make(map[string]uint64)
+				}
+				stats.CityVisits[date][city] += visits
+				if lat, lon, ok := cl.Location(city); ok {
+					stats.CityLocations[city] = &GeoPoint{Lat: lat, Lon: lon}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// LookupASNs looks up the autonomous system of all IP addresses in the GeoLite2-ASN database at dbPath
+// and rebuilds the ASNs map from the IP statistics.
+func (stats *LogStats) LookupASNs(dbPath string) error {
+	al, err := asncache.NewASNLookup(dbPath)
+	if err != nil {
+		return err
+	}
+	defer al.Close()
+
+	stats.ASNs = make(map[string]map[string]*HitsBytesVisits)
+	for date, ips := range stats.IPs {
+		for ip, hbv := range ips {
+			system, ok := al.Lookup(ip)
+			if !ok {
+				system = "Unknown"
+			}
+			if stats.ASNs[date] == nil {
+				stats.ASNs[date] = make(map[string]*HitsBytesVisits)
+			}
+			stats.ASNs[date][system] = addHitsBytesVisits(stats.ASNs[date][system], hbv)
+		}
+	}
+
+	return nil
+}
+
+// LookupDatacenters looks up all IP addresses in the IP ranges of cloud and datacenter providers in ranges,
+// named by provider, and rebuilds the Datacenters map from the IP statistics. IP addresses outside the ranges
+// are left out.
+func (stats *LogStats) LookupDatacenters(ranges *iprange.Set) {
+	stats.Datacenters = stats.rangeTraffic(ranges)
+}
+
+// LookupFlagged looks up all IP addresses in the IP ranges of the blocklists in ranges, named by blocklist,
+// and rebuilds the Flagged map from the IP statistics. IP addresses outside the ranges are left out.
+func (stats *LogStats) LookupFlagged(ranges *iprange.Set) {
+	stats.Flagged = stats.rangeTraffic(ranges)
+}
+
+// rangeTraffic returns the statistics of the IP addresses in ranges per day, keyed by date and the name of the
+// list of their range.
+func (stats *LogStats) rangeTraffic(ranges *iprange.Set) map[string]map[string]*HitsBytesVisits {
+	traffic := make(map[string]map[string]*HitsBytesVisits)
+	for date, ips := range stats.IPs {
+		for ip, hbv := range ips {
+			list, ok := ranges.Lookup(ip)
+			if !ok {
+				continue
+			}
+			if traffic[date] == nil {
+				traffic[date] = make(map[string]*HitsBytesVisits)
+			}
+			traffic[date][list] = addHitsBytesVisits(traffic[date][list], hbv)
+		}
+	}
+	return traffic
+}
+
+// HFPBVSData holds aggregated metrics for hits, files, pages, bytes, visits, and sites.
+type HFPBVSData struct {
+	// Category is the category name (e.g. month name).
+	Category string
+	// Hits is the total number of hits.
+	Hits uint64
+	// Files is the total number of file requests.
+	Files uint64
+	// Pages is the total number of page requests.
+	Pages uint64
+	// Bytes is the total number of bytes transferred.
+	Bytes uint64
+	// Visits is the total number of visits.
+	Visits uint64
+	// Sites is the total number of sites.
+	Sites uint64
+}
+
+// CategoryData holds a category and its corresponding count.
+type CategoryData struct {
+	// Category is the name of the category.
 	Category string
 	// Count is the number of items in the category.
 	Count uint64
 }
 
 // AggregatesByMonth returns a map of aggregated metrics by month.
+// Imported history is used for months that do not appear in the parsed log.
 func (stats *LogStats) AggregatesByMonth() map[string]*HFPBVSData {
 	aggr := make(map[string]*HFPBVSData)
 	for dateStr, hits := range stats.Hits {
@@ -249,6 +1198,14 @@ func (stats *LogStats) AggregatesByMonth() map[string]*HFPBVSData {
 		}
 	}
 
+	// Fill in imported totals for months that are not covered by the parsed log.
+	for monthStr, data := range stats.History {
+		if _, ok := aggr[monthStr]; !ok {
+			value := *data
+			aggr[monthStr] = &value
+		}
+	}
+
 	return aggr
 }
 
@@ -281,9 +1238,17 @@ func (stats *LogStats) recentKeys() []string {
 
 // RecentAggregates returns a map of aggregated metrics for the last month.
 func (stats *LogStats) RecentAggregates() map[string]*HFPBVSData {
-	daysKeys := stats.recentKeys()
+	return stats.dayAggregates(stats.recentKeys())
+}
+
+// DailyAggregates returns a map of aggregated metrics for every day in the parsed log.
+func (stats *LogStats) DailyAggregates() map[string]*HFPBVSData {
+	return stats.dayAggregates(slices.Collect(maps.Keys(stats.Hits)))
+}
 
-	aggr := make(map[string]*HFPBVSData, 31)
+// dayAggregates returns a map of aggregated metrics for the given days.
+func (stats *LogStats) dayAggregates(daysKeys []string) map[string]*HFPBVSData {
+	aggr := make(map[string]*HFPBVSData, len(daysKeys))
 	for _, dateStr := range daysKeys {
 		t, _ := time.Parse("2006-01-02", dateStr)
 		formattedDate := t.Format("Jan 2")
@@ -305,6 +1270,330 @@ func (stats *LogStats) RecentAggregates() map[string]*HFPBVSData {
 	return aggr
 }
 
+// MonthDayAggregates returns a map of aggregated metrics for every day of a month, in the format "YYYY-MM",
+// keyed by date. Days without hits are included as zero, and the categories are the day numbers.
+func (stats *LogStats) MonthDayAggregates(month string) map[string]*HFPBVSData {
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil
+	}
+	daysKeys := make([]string, 0, 31)
+	for t := first; t.Month() == first.Month(); t = t.AddDate(0, 0, 1) {
+		daysKeys = append(daysKeys, t.Format("2006-01-02"))
+	}
+
+	aggr := stats.dayAggregates(daysKeys)
+	for dateStr, value := range aggr {
+		value.Category = strings.TrimPrefix(dateStr[8:], "0")
+	}
+	return aggr
+}
+
+// RecentNotModified returns a map of 304 Not Modified responses per day for the last month.
+func (stats *LogStats) RecentNotModified() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64, len(daysKeys))
+	for _, dateStr := range daysKeys {
+		aggr[dateStr] = stats.NotModified[dateStr]
+	}
+
+	return aggr
+}
+
+// Summary returns the grand totals of the parsed log and of imported months that are not covered by it.
+// The unique sites of imported months cannot be told apart from those of the log, and are added as is.
+func (stats *LogStats) Summary() *Summary {
+	summary := &Summary{}
+	months := make(map[string]bool)
+	for dateStr, hits := range stats.Hits {
+		if summary.First == "" || dateStr < summary.First {
+			summary.First = dateStr
+		}
+		if dateStr > summary.Last {
+			summary.Last = dateStr
+		}
+		months[dateStr[:7]] = true
+
+		summary.Hits += hits
+		summary.Files += stats.Files[dateStr]
+		summary.Pages += stats.Pages[dateStr]
+		summary.Bytes += stats.Bytes[dateStr]
+		for _, count := range stats.Visits[dateStr] {
+			summary.Visits += count
+		}
+		for code, count := range stats.RespCodes[dateStr] {
+			if code >= 400 {
+				summary.Errors += count
+			}
+		}
+	}
+	summary.Sites = uint64(len(uniqueVisitors(stats.Sites)))
+
+	for monthStr, data := range stats.History {
+		if months[monthStr] {
+			continue
+		}
+		if first := monthStr + "-01"; summary.First == "" || first < summary.First {
+			summary.First = first
+		}
+		if t, err := time.Parse("2006-01", monthStr); err == nil {
+			if last := t.AddDate(0, 1, -1).Format("2006-01-02"); last > summary.Last {
+				summary.Last = last
+			}
+		}
+
+		summary.Hits += data.Hits
+		summary.Files += data.Files
+		summary.Pages += data.Pages
+		summary.Bytes += data.Bytes
+		summary.Visits += data.Visits
+		summary.Sites += data.Sites
+	}
+
+	return summary
+}
+
+// lastDate returns the last date in the stats, or false if there is none.
+func (stats *LogStats) lastDate() (time.Time, bool) {
+	var lastKey string
+	for key := range stats.Hits {
+		if key > lastKey {
+			lastKey = key
+		}
+	}
+	t, err := time.Parse("2006-01-02", lastKey)
+	return t, err == nil
+}
+
+// periodTotals returns the totals of the days from first up to but excluding last.
+// Sites are summed per day, as the unique sites of a period are not tracked.
+func (stats *LogStats) periodTotals(first time.Time, last time.Time) HFPBVSData {
+	var data HFPBVSData
+	for t := first; t.Before(last); t = t.AddDate(0, 0, 1) {
+		dateStr := t.Format("2006-01-02")
+		data.Hits += stats.Hits[dateStr]
+		data.Files += stats.Files[dateStr]
+		data.Pages += stats.Pages[dateStr]
+		data.Bytes += stats.Bytes[dateStr]
+		for _, count := range stats.Visits[dateStr] {
+			data.Visits += count
+		}
+		data.Sites += uint64(len(stats.Sites[dateStr]))
+	}
+	return data
+}
+
+// YearOverYear compares the last month in the stats with the same month a year earlier,
+// which may come from imported history. It returns nil if the stats are empty.
+func (stats *LogStats) YearOverYear() *Comparison {
+	last, ok := stats.lastDate()
+	if !ok {
+		return nil
+	}
+	months := stats.AggregatesByMonth()
+	current := time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC)
+	previous := current.AddDate(-1, 0, 0)
+
+	cmp := &Comparison{Current: current.Format("Jan 2006"), Previous: previous.Format("Jan 2006")}
+	if data, ok := months[current.Format("2006-01")]; ok {
+		cmp.CurrentTotals = *data
+	}
+	if data, ok := months[previous.Format("2006-01")]; ok {
+		cmp.PreviousTotals = *data
+	}
+	return cmp
+}
+
+// WeekOverWeek compares the last 7 days in the stats with the 7 days before.
+// It returns nil if the stats are empty.
+func (stats *LogStats) WeekOverWeek() *Comparison {
+	last, ok := stats.lastDate()
+	if !ok {
+		return nil
+	}
+	end := last.AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -7)
+	previous := start.AddDate(0, 0, -7)
+
+	return &Comparison{
+		Current:        start.Format("Jan 2") + " - " + last.Format("Jan 2"),
+		Previous:       previous.Format("Jan 2") + " - " + start.AddDate(0, 0, -1).Format("Jan 2"),
+		CurrentTotals:  stats.periodTotals(start, end),
+		PreviousTotals: stats.periodTotals(previous, start),
+	}
+}
+
+// RollingAggregates returns, for every day of the last month, the averages of the daily hits, visits,
+// and bytes over the window of days ending on that day, such as 7 or 30 days.
+// Days without hits count as zero, but days before the first day in the log are left out of the window.
+func (stats *LogStats) RollingAggregates(days int) map[string]*RollingAverages {
+	daysKeys := stats.recentKeys()
+
+	firstKey := ""
+	for key := range stats.Hits {
+		if firstKey == "" || key < firstKey {
+			firstKey = key
+		}
+	}
+
+	aggr := make(map[string]*RollingAverages, len(daysKeys))
+	for _, dateStr := range daysKeys {
+		t, _ := time.Parse("2006-01-02", dateStr)
+
+		value := &RollingAverages{}
+		n := 0
+		for ; n < days; n++ {
+			key := t.AddDate(0, 0, -n).Format("2006-01-02")
+			if key < firstKey {
+				break
+			}
+			value.Hits += float64(stats.Hits[key])
+			value.Bytes += float64(stats.Bytes[key])
+			for _, count := range stats.Visits[key] {
+				value.Visits += float64(count)
+			}
+		}
+		if n > 0 {
+			value.Hits /= float64(n)
+			value.Visits /= float64(n)
+			value.Bytes /= float64(n)
+		}
+		aggr[dateStr] = value
+	}
+
+	return aggr
+}
+
+// HourlyAggregates returns a map of aggregated metrics by hour of day for the last month,
+// keyed by hour string in the format "HH". Visits and sites are not tracked per hour.
+func (stats *LogStats) HourlyAggregates() map[string]*HFPBVSData {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HFPBVSData, 24)
+	for hour := range 24 {
+		hourStr := fmt.Sprintf("%02d", hour)
+		aggr[hourStr] = &HFPBVSData{Category: hourStr}
+	}
+	for _, dateStr := range daysKeys {
+		hours := stats.Hours[dateStr]
+		if hours == nil {
+			continue
+		}
+		for hour, hfpb := range hours {
+			value := aggr[fmt.Sprintf("%02d", hour)]
+			value.Hits += hfpb.Hits
+			value.Files += hfpb.Files
+			value.Pages += hfpb.Pages
+			value.Bytes += hfpb.Bytes
+		}
+	}
+
+	return aggr
+}
+
+// HourlyAverages returns a map of the average metrics per day by hour of day for the last month,
+// keyed like HourlyAggregates. The totals are divided by the number of days in the month with hits.
+func (stats *LogStats) HourlyAverages() map[string]*HourlyAverages {
+	days := float64(len(stats.recentKeys()))
+
+	aggr := make(map[string]*HourlyAverages, 24)
+	for key, value := range stats.HourlyAggregates() {
+		if days == 0 {
+			aggr[key] = &HourlyAverages{}
+			continue
+		}
+		aggr[key] = &HourlyAverages{
+			Hits:  float64(value.Hits) / days,
+			Files: float64(value.Files) / days,
+			Pages: float64(value.Pages) / days,
+			Bytes: float64(value.Bytes) / days,
+		}
+	}
+
+	return aggr
+}
+
+// WeekdayAggregates returns a map of average daily metrics by day of week over all days,
+// keyed by ISO weekday string ("1" for Monday through "7" for Sunday).
+func (stats *LogStats) WeekdayAggregates() map[string]*HFPBVSData {
+	totals := make(map[string]*HFPBVSData, 7)
+	days := make(map[string]uint64, 7)
+	for weekday := range 7 {
+		key := strconv.Itoa(weekday + 1)
+		// 2024-01-01 was a Monday.
+		category := time.Date(2024, 1, 1+weekday, 0, 0, 0, 0, time.UTC).Format("Mon")
+		totals[key] = &HFPBVSData{Category: category}
+	}
+
+	for dateStr, hits := range stats.Hits {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			continue
+		}
+		key := strconv.Itoa((int(date.Weekday())+6)%7 + 1)
+		days[key]++
+
+		value := totals[key]
+		value.Hits += hits
+		value.Files += stats.Files[dateStr]
+		value.Pages += stats.Pages[dateStr]
+		value.Bytes += stats.Bytes[dateStr]
+		for _, count := range stats.Visits[dateStr] {
+			value.Visits += count
+		}
+		value.Sites += uint64(len(stats.Sites[dateStr]))
+	}
+
+	// Turn the totals into averages per day.
+	for key, value := range totals {
+		if n := days[key]; n > 0 {
+			value.Hits /= n
+			value.Files /= n
+			value.Pages /= n
+			value.Bytes /= n
+			value.Visits /= n
+			value.Sites /= n
+		}
+	}
+
+	return totals
+}
+
+// RecentVisitMetrics returns a map of completed visit metrics for the last month,
+// keyed by date string in the format "YYYY-MM-DD".
+func (stats *LogStats) RecentVisitMetrics() map[string]*VisitMetrics {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*VisitMetrics, len(daysKeys))
+	for _, dateStr := range daysKeys {
+		value := VisitMetrics{}
+		if vm := stats.VisitMetrics[dateStr]; vm != nil {
+			value = *vm
+		}
+		aggr[dateStr] = &value
+	}
+
+	return aggr
+}
+
+// PagesPerVisitAggregates returns a map of completed visits per pages-per-visit bucket for the last month.
+func (stats *LogStats) PagesPerVisitAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64, len(PagesBuckets))
+	for _, bucket := range PagesBuckets {
+		aggr[bucket] = 0
+	}
+	for _, date := range daysKeys {
+		for bucket, visits := range stats.PagesPerVisit[date] {
+			aggr[bucket] += visits
+		}
+	}
+
+	return aggr
+}
+
 // MethRespAggregates returns maps of aggregated metrics for HTTP methods and response codes.
 func (stats *LogStats) MethRespAggregates() (map[string]uint64, map[uint16]uint64) {
 	daysKeys := stats.recentKeys()
@@ -325,6 +1614,573 @@ func (stats *LogStats) MethRespAggregates() (map[string]uint64, map[uint16]uint6
 	return aggr, aggr2
 }
 
+// Visitor types of DailyVisitorTypeAggregates.
+const (
+	// VisitorNew is a visitor first seen on the day.
+	VisitorNew = "New"
+	// VisitorReturning is a visitor first seen on an earlier day.
+	VisitorReturning = "Returning"
+)
+
+// VisitorTypes are the visitor types of DailyVisitorTypeAggregates.
+var VisitorTypes = []string{VisitorNew, VisitorReturning}
+
+// DailyVisitorTypeAggregates returns a map of unique visitors per day for the last month, keyed by date string
+// in the format "YYYY-MM-DD" and visitor type. Visitors are new on the day of their first visit.
+func (stats *LogStats) DailyVisitorTypeAggregates() map[string]map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]map[string]uint64, len(daysKeys))
+	for _, date := range daysKeys {
+		aggr[date] = make(map[string]uint64, len(VisitorTypes))
+		for visitor := range stats.Visits[date] {
+			first, ok := stats.FirstVisit[visitor]
+			if !ok || first.Format("2006-01-02") >= date {
+				aggr[date][VisitorNew]++
+			} else {
+				aggr[date][VisitorReturning]++
+			}
+		}
+	}
+
+	return aggr
+}
+
+// StatusClasses are the classes of HTTP response codes, in ascending order.
+var StatusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// StatusClass returns the class of an HTTP response code, such as "4xx" for 404,
+// or an empty string for codes outside the 100-599 range.
+func StatusClass(code uint16) string {
+	if code < 100 || code >= 600 {
+		return ""
+	}
+	return StatusClasses[code/100-1]
+}
+
+// DailyStatusClassAggregates returns a map of hits per response code class per day for the last month,
+// keyed by date string in the format "YYYY-MM-DD" and class.
+func (stats *LogStats) DailyStatusClassAggregates() map[string]map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]map[string]uint64, len(daysKeys))
+	for _, date := range daysKeys {
+		aggr[date] = make(map[string]uint64, len(StatusClasses))
+		for code, hits := range stats.RespCodes[date] {
+			if class := StatusClass(code); class != "" {
+				aggr[date][class] += hits
+			}
+		}
+	}
+
+	return aggr
+}
+
+// DailySchemeAggregates returns a map of hits per URL scheme per day for the last month,
+// keyed by date string in the format "YYYY-MM-DD" and scheme. It returns nil if no schemes were recorded.
+func (stats *LogStats) DailySchemeAggregates() map[string]map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	var aggr map[string]map[string]uint64
+	for _, date := range daysKeys {
+		if len(stats.Schemes[date]) > 0 {
+			aggr = make(map[string]map[string]uint64, len(daysKeys))
+			break
+		}
+	}
+	if aggr == nil {
+		return nil
+	}
+	for _, date := range daysKeys {
+		aggr[date] = make(map[string]uint64, 2)
+		mergeCounts(aggr[date], stats.Schemes[date])
+	}
+
+	return aggr
+}
+
+// EntryExitAggregates returns maps of visits per entry page and per exit page for the last month.
+func (stats *LogStats) EntryExitAggregates() (map[string]uint64, map[string]uint64) {
+	daysKeys := stats.recentKeys()
+
+	entries := make(map[string]uint64)
+	exits := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for urlPath, visits := range stats.EntryPages[date] {
+			entries[urlPath] += visits
+		}
+		for urlPath, visits := range stats.ExitPages[date] {
+			exits[urlPath] += visits
+		}
+	}
+
+	return entries, exits
+}
+
+// URLAggregates returns a map of hits and bytes per URL path, over all methods, for the last month.
+func (stats *LogStats) URLAggregates() map[string]*HitsBytes {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytes)
+	for _, date := range daysKeys {
+		for urlPath, methods := range stats.URLPaths[date] {
+			for _, hb := range methods {
+				aggr[urlPath] = addHitsBytes(aggr[urlPath], hb)
+			}
+		}
+	}
+
+	return aggr
+}
+
+// PartialAggregates returns a map of partial responses per URL path for the last month.
+func (stats *LogStats) PartialAggregates() map[string]*PartialContent {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*PartialContent)
+	for _, date := range daysKeys {
+		for urlPath, hb := range stats.Partial[date] {
+			value, ok := aggr[urlPath]
+			if !ok {
+				value = &PartialContent{FullSize: stats.FullSizes[urlPath]}
+				aggr[urlPath] = value
+			}
+			value.Hits += hb.Hits
+			value.Bytes += hb.Bytes
+		}
+	}
+
+	return aggr
+}
+
+// TransitionAggregates returns a map of page-to-page transitions, keyed by TransitionKey, for the last month.
+func (stats *LogStats) TransitionAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for key, count := range stats.Transitions[date] {
+			aggr[key] += count
+		}
+	}
+
+	return aggr
+}
+
+// AcquisitionAggregates returns a map of visits, keyed by AcquisitionKey, for the last month.
+func (stats *LogStats) AcquisitionAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		mergeCounts(aggr, stats.Acquisitions[date])
+	}
+
+	return aggr
+}
+
+// UserAgentAggregates returns a map of visits per user agent, as sent by the clients, for the last month.
+func (stats *LogStats) UserAgentAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for userAgent, hbv := range stats.UserAgents[date] {
+			aggr[userAgent] += hbv.Visits
+		}
+	}
+
+	return aggr
+}
+
+// BrowserAggregates returns maps of visits per browser family and per browser version for the last month.
+func (stats *LogStats) BrowserAggregates() (map[string]uint64, map[string]uint64) {
+	daysKeys := stats.recentKeys()
+
+	families := make(map[string]uint64)
+	versions := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for key, visits := range stats.Browsers[date] {
+			families[BrowserFamily(key)] += visits
+			versions[key] += visits
+		}
+	}
+
+	return families, versions
+}
+
+// OSDeviceAggregates returns maps of visits per operating system and per device type for the last month.
+func (stats *LogStats) OSDeviceAggregates() (map[string]uint64, map[string]uint64) {
+	daysKeys := stats.recentKeys()
+
+	oses := make(map[string]uint64)
+	devices := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for os, visits := range stats.OSes[date] {
+			oses[os] += visits
+		}
+		for device, visits := range stats.Devices[date] {
+			devices[device] += visits
+		}
+	}
+
+	return oses, devices
+}
+
+// NotFoundAggregates returns a map of 404 Not Found hits for the last month, keyed by URL path and referrer.
+func (stats *LogStats) NotFoundAggregates() map[string]map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]map[string]uint64)
+	for _, date := range daysKeys {
+		mergeNestedCounts(aggr, stats.NotFound[date])
+	}
+
+	return aggr
+}
+
+// DailyContentAggregates returns a map of bytes transferred per content category per day for the last month,
+// keyed by date string in the format "YYYY-MM-DD" and content category.
+func (stats *LogStats) DailyContentAggregates() map[string]map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]map[string]uint64, len(daysKeys))
+	for _, date := range daysKeys {
+		aggr[date] = make(map[string]uint64)
+		mergeCounts(aggr[date], stats.ContentBytes[date])
+	}
+
+	return aggr
+}
+
+// DailySizePercentiles returns a map of response size percentiles per day for the last month,
+// keyed by date string in the format "YYYY-MM-DD".
+func (stats *LogStats) DailySizePercentiles() map[string]*SizePercentiles {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*SizePercentiles, len(daysKeys))
+	for _, date := range daysKeys {
+		hist := stats.Sizes[date]
+		aggr[date] = &SizePercentiles{
+			P50: SizePercentile(hist, 50),
+			P90: SizePercentile(hist, 90),
+			P99: SizePercentile(hist, 99),
+		}
+	}
+
+	return aggr
+}
+
+// SizeBucketAggregates returns a map of responses per response size bucket for the last month.
+func (stats *LogStats) SizeBucketAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64, len(SizeBuckets))
+	for _, bucket := range SizeBuckets {
+		aggr[bucket] = 0
+	}
+	for _, date := range daysKeys {
+		for bin, count := range stats.Sizes[date] {
+			aggr[SizeBucket(bin)] += count
+		}
+	}
+
+	return aggr
+}
+
+// ErrorAggregates returns a map of error hits for the last month, keyed by URL path and response code.
+func (stats *LogStats) ErrorAggregates() map[string]map[uint16]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]map[uint16]uint64)
+	for _, date := range daysKeys {
+		mergeNestedCounts(aggr, stats.Errors[date])
+	}
+
+	return aggr
+}
+
+// DailyErrorAggregates returns a map of error hits per day for the last month,
+// keyed by date string in the format "YYYY-MM-DD" and URL path.
+func (stats *LogStats) DailyErrorAggregates() map[string]map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]map[string]uint64, len(daysKeys))
+	for _, date := range daysKeys {
+		aggr[date] = make(map[string]uint64)
+		for urlPath, codes := range stats.Errors[date] {
+			for _, hits := range codes {
+				aggr[date][urlPath] += hits
+			}
+		}
+	}
+
+	return aggr
+}
+
+// CityAggregates returns a map of visits per city for the last month.
+func (stats *LogStats) CityAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		mergeCounts(aggr, stats.CityVisits[date])
+	}
+
+	return aggr
+}
+
+// ASNAggregates returns a map of hits, bytes, and visits per autonomous system for the last month.
+func (stats *LogStats) ASNAggregates() map[string]*HitsBytesVisits {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytesVisits)
+	for _, date := range daysKeys {
+		for system, hbv := range stats.ASNs[date] {
+			aggr[system] = addHitsBytesVisits(aggr[system], hbv)
+		}
+	}
+
+	return aggr
+}
+
+// DatacenterAggregates returns a map of hits, bytes, and visits per cloud or datacenter provider for the last month.
+func (stats *LogStats) DatacenterAggregates() map[string]*HitsBytesVisits {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytesVisits)
+	for _, date := range daysKeys {
+		for provider, hbv := range stats.Datacenters[date] {
+			aggr[provider] = addHitsBytesVisits(aggr[provider], hbv)
+		}
+	}
+
+	return aggr
+}
+
+// FlaggedAggregates returns a map of hits, bytes, and visits per blocklist for the last month.
+func (stats *LogStats) FlaggedAggregates() map[string]*HitsBytesVisits {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytesVisits)
+	for _, date := range daysKeys {
+		for list, hbv := range stats.Flagged[date] {
+			aggr[list] = addHitsBytesVisits(aggr[list], hbv)
+		}
+	}
+
+	return aggr
+}
+
+// FlaggedSiteAggregates returns a map of hits, bytes, and visits per IP address in the IP ranges of the
+// blocklists in ranges for the last month.
+func (stats *LogStats) FlaggedSiteAggregates(ranges *iprange.Set) map[string]*HitsBytesVisits {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytesVisits)
+	for _, date := range daysKeys {
+		for ip, hbv := range stats.IPs[date] {
+			if _, ok := ranges.Lookup(ip); ok {
+				aggr[ip] = addHitsBytesVisits(aggr[ip], hbv)
+			}
+		}
+	}
+
+	return aggr
+}
+
+// TotalAggregates returns the total hits, bytes, and visits for the last month.
+func (stats *LogStats) TotalAggregates() *HitsBytesVisits {
+	daysKeys := stats.recentKeys()
+
+	aggr := &HitsBytesVisits{}
+	for _, date := range daysKeys {
+		aggr.Hits += stats.Hits[date]
+		aggr.Bytes += stats.Bytes[date]
+		for _, visits := range stats.Visits[date] {
+			aggr.Visits += visits
+		}
+	}
+
+	return aggr
+}
+
+// SiteAggregates returns a map of hits per visitor IP address for the last month.
+func (stats *LogStats) SiteAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for visitor, hits := range stats.Sites[date] {
+			aggr[VisitorIP(visitor)] += hits
+		}
+	}
+
+	return aggr
+}
+
+// IPAggregates returns a map of hits, bytes, and visits per visitor IP address for the last month.
+func (stats *LogStats) IPAggregates() map[string]*HitsBytesVisits {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytesVisits)
+	for _, date := range daysKeys {
+		for ip, hbv := range stats.IPs[date] {
+			aggr[ip] = addHitsBytesVisits(aggr[ip], hbv)
+		}
+	}
+
+	return aggr
+}
+
+// ReferrerAggregates returns a map of hits and bytes per referrer for the last month.
+func (stats *LogStats) ReferrerAggregates() map[string]*HitsBytes {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytes)
+	for _, date := range daysKeys {
+		for referrer, hb := range stats.Referrers[date] {
+			aggr[referrer] = addHitsBytes(aggr[referrer], hb)
+		}
+	}
+
+	return aggr
+}
+
+// ReferrerDomainAggregates returns a map of hits per referring domain for the last month.
+func (stats *LogStats) ReferrerDomainAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for domain, hb := range stats.ReferrerDomains[date] {
+			aggr[domain] += hb.Hits
+		}
+	}
+
+	return aggr
+}
+
+// SearchStringAggregates returns a map of hits per search string for the last month.
+func (stats *LogStats) SearchStringAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for query, hits := range stats.SearchStrings[date] {
+			aggr[query] += hits
+		}
+	}
+
+	return aggr
+}
+
+// RobotAggregates returns a map of hits per robot for the last month.
+func (stats *LogStats) RobotAggregates() map[string]uint64 {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]uint64)
+	for _, date := range daysKeys {
+		for robot, hits := range stats.Robots[date] {
+			aggr[robot] += hits
+		}
+	}
+
+	return aggr
+}
+
+// HumansRobots holds the hits and visits of humans and of robots.
+type HumansRobots struct {
+	// HumanHits is the number of hits by humans.
+	HumanHits uint64
+	// HumanVisits is the number of visits by humans.
+	HumanVisits uint64
+	// RobotHits is the number of hits by robots.
+	RobotHits uint64
+	// RobotVisits is the number of visits by robots, zero if robots are left out of the statistics.
+	RobotVisits uint64
+}
+
+// HumansRobotsAggregates returns the hits and visits of humans and robots for the last month.
+// If robots were left out of the other statistics, as selected by excluded, all hits and visits are human.
+func (stats *LogStats) HumansRobotsAggregates(excluded bool) *HumansRobots {
+	return stats.humansRobots(stats.recentKeys(), excluded)
+}
+
+// DailyHumansRobots returns the hits and visits of humans and robots per day for the last month, keyed by
+// date string in the format "YYYY-MM-DD", as HumansRobotsAggregates does.
+func (stats *LogStats) DailyHumansRobots(excluded bool) map[string]*HumansRobots {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HumansRobots, len(daysKeys))
+	for _, date := range daysKeys {
+		aggr[date] = stats.humansRobots([]string{date}, excluded)
+	}
+
+	return aggr
+}
+
+// TotalHumansRobots returns the hits and visits of humans and robots of the whole period of Summary, as
+// HumansRobotsAggregates does. Hits and visits of imported history count as human, as its robots are unknown.
+func (stats *LogStats) TotalHumansRobots(excluded bool) *HumansRobots {
+	aggr := stats.humansRobots(slices.Collect(maps.Keys(stats.Hits)), excluded)
+	for monthStr, data := range stats.History {
+		if !stats.hasMonth(monthStr) {
+			aggr.HumanHits += data.Hits
+			aggr.HumanVisits += data.Visits
+		}
+	}
+	return aggr
+}
+
+// hasMonth reports whether stats holds a day of the month monthStr, in the format "YYYY-MM".
+func (stats *LogStats) hasMonth(monthStr string) bool {
+	for date := range stats.Hits {
+		if strings.HasPrefix(date, monthStr) {
+			return true
+		}
+	}
+	return false
+}
+
+// humansRobots returns the hits and visits of humans and robots on the dates.
+func (stats *LogStats) humansRobots(dates []string, excluded bool) *HumansRobots {
+	aggr := &HumansRobots{}
+	var hits, visits uint64
+	for _, date := range dates {
+		hits += stats.Hits[date]
+		for _, count := range stats.Visits[date] {
+			visits += count
+		}
+		for _, count := range stats.Robots[date] {
+			aggr.RobotHits += count
+		}
+		aggr.RobotVisits += stats.RobotVisits[date]
+	}
+	if excluded {
+		aggr.HumanHits, aggr.HumanVisits = hits, visits
+		return aggr
+	}
+	aggr.HumanHits = hits - min(hits, aggr.RobotHits)
+	aggr.HumanVisits = visits - min(visits, aggr.RobotVisits)
+
+	return aggr
+}
+
+// CountryTrafficAggregates returns a map of the hits, bytes, and visits per country for the last month.
+func (stats *LogStats) CountryTrafficAggregates() map[string]*HitsBytesVisits {
+	daysKeys := stats.recentKeys()
+
+	aggr := make(map[string]*HitsBytesVisits)
+	for _, date := range daysKeys {
+		for country, hbv := range stats.CtrTraffic[date] {
+			aggr[country] = addHitsBytesVisits(aggr[country], hbv)
+		}
+	}
+
+	return aggr
+}
+
 // CountryAggregates returns a map of aggregated metrics for countries.
 func (stats *LogStats) CountryAggregates() map[string]uint64 {
 	daysKeys := stats.recentKeys()
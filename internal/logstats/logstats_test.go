@@ -0,0 +1,147 @@
+package logstats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	const date = "2024-03-01"
+	const limit = 5
+	at := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		limits Limits
+		// add counts a hit for key.
+		add func(stats *LogStats, key string)
+		// kept returns the number of keys kept.
+		kept func(stats *LogStats) int
+		// hits returns the hits counted for key, or 0 for visitors, which are not counted, and whether it is kept.
+		hits func(stats *LogStats, key string) (uint64, bool)
+	}{
+		{
+			"URL paths", Limits{URLPaths: limit},
+			func(stats *LogStats, key string) { stats.UpdateURLStats(date, "/"+key, "GET", 100) },
+			func(stats *LogStats) int { return len(stats.URLPaths[date]) },
+			func(stats *LogStats, key string) (uint64, bool) {
+				hb, ok := stats.URLPaths[date]["/"+key]["GET"]
+				if !ok {
+					return 0, false
+				}
+				return hb.Hits, true
+			},
+		},
+		{
+			"user agents", Limits{UserAgents: limit},
+			func(stats *LogStats, key string) { stats.UpdateUserAgentStats(date, key, 100, false) },
+			func(stats *LogStats) int { return len(stats.UserAgents[date]) },
+			func(stats *LogStats, key string) (uint64, bool) {
+				hbv, ok := stats.UserAgents[date][key]
+				if !ok {
+					return 0, false
+				}
+				return hbv.Hits, true
+			},
+		},
+		{
+			"referrers", Limits{Referrers: limit},
+			func(stats *LogStats, key string) { stats.UpdateReferrerStats(date, "https://"+key+".com/", 100) },
+			func(stats *LogStats) int { return len(stats.Referrers[date]) },
+			func(stats *LogStats, key string) (uint64, bool) {
+				hb, ok := stats.Referrers[date]["https://"+key+".com/"]
+				if !ok {
+					return 0, false
+				}
+				return hb.Hits, true
+			},
+		},
+		{
+			"referring sites", Limits{Referrers: limit},
+			func(stats *LogStats, key string) { stats.UpdateReferrerStats(date, "https://"+key+".com/", 100) },
+			func(stats *LogStats) int { return len(stats.ReferrerDomains[date]) },
+			func(stats *LogStats, key string) (uint64, bool) {
+				hb, ok := stats.ReferrerDomains[date][key+".com"]
+				if !ok {
+					return 0, false
+				}
+				return hb.Hits, true
+			},
+		},
+		{
+			"visitors", Limits{Visitors: limit},
+			func(stats *LogStats, key string) { stats.AddVisitorHit(key, at) },
+			func(stats *LogStats) int { return len(stats.FirstVisit) },
+			func(stats *LogStats, key string) (uint64, bool) {
+				_, ok := stats.FirstVisit[key]
+				_, last := stats.LastVisit[key]
+				return 0, ok && last
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := NewLogStats()
+			stats.SetLimits(tt.limits)
+
+			// Two heavy hitters, each with a third of the hits, among many keys seen once
+			var heavyHits uint64
+			for i := range 300 {
+				tt.add(stats, "heavy")
+				tt.add(stats, "other-heavy")
+				heavyHits++
+				tt.add(stats, fmt.Sprintf("key%d", i))
+				if kept := tt.kept(stats); kept > limit {
+					t.Fatalf("%d keys kept after %d hits, exceeding the limit of %d", kept, 3*(i+1), limit)
+				}
+			}
+
+			for _, key := range []string{"heavy", "other-heavy"} {
+				hits, ok := tt.hits(stats, key)
+				if !ok {
+					t.Errorf("%q was evicted", key)
+				} else if hits != 0 && hits < heavyHits {
+					t.Errorf("%q has %d hits, below its %d hits", key, hits, heavyHits)
+				}
+			}
+		})
+	}
+}
+
+func TestNoLimits(t *testing.T) {
+	stats := NewLogStats()
+	for i := range 100 {
+		stats.UpdateURLStats("2024-03-01", fmt.Sprintf("/page/%d", i), "GET", 100)
+		stats.AddVisitorHit(fmt.Sprintf("192.0.2.%d", i), time.Now())
+	}
+	if got := len(stats.URLPaths["2024-03-01"]); got != 100 {
+		t.Errorf("URL paths kept = %d, want all 100", got)
+	}
+	if got := len(stats.FirstVisit); got != 100 {
+		t.Errorf("visitors kept = %d, want all 100", got)
+	}
+}
+
+func TestPagesBucket(t *testing.T) {
+	tests := []struct {
+		pages uint64
+		want  string
+	}{
+		{0, "1"},
+		{1, "1"},
+		{2, "2-3"},
+		{3, "2-3"},
+		{4, "4-10"},
+		{10, "4-10"},
+		{11, "10+"},
+		{500, "10+"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.pages), func(t *testing.T) {
+			if got := PagesBucket(tt.pages); got != tt.want {
+				t.Errorf("PagesBucket(%d) = %q, want %q", tt.pages, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package logstats
+
+// Merge adds the statistics of other to stats.
+// Counters are summed, FirstVisit keeps the earliest and LastVisit the latest timestamp per visitor,
+// city locations missing from stats are copied, and imported monthly totals for the same month are summed.
+// other is not modified.
+// Visits are summed as well, so a visitor whose requests were spread over several servers
+// is counted once per server.
+// The caps set with SetLimits are neither carried over nor reapplied: stats keeps its own limits for the
+// entries added later, and the merged maps may hold more entries than the limits of either.
+func (stats *LogStats) Merge(other *LogStats) {
+	mergeCounts(stats.Hits, other.Hits)
+	mergeCounts(stats.Files, other.Files)
+	mergeCounts(stats.NotModified, other.NotModified)
+	mergeCounts(stats.Pages, other.Pages)
+	mergeCounts(stats.Bytes, other.Bytes)
+	mergeNestedCounts(stats.Schemes, other.Schemes)
+	for date, hours := range other.Hours {
+		if stats.Hours[date] == nil {
+			stats.Hours[date] = &[24]HFPB{}
+		}
+		for hour, hfpb := range hours {
+			value := &stats.Hours[date][hour]
+			value.Hits += hfpb.Hits
+			value.Files += hfpb.Files
+			value.Pages += hfpb.Pages
+			value.Bytes += hfpb.Bytes
+		}
+	}
+	mergeNestedCounts(stats.Visits, other.Visits)
+	mergeNestedCounts(stats.CtrVisits, other.CtrVisits)
+	mergeNestedCounts(stats.CityVisits, other.CityVisits)
+	mergeNestedCounts(stats.EntryPages, other.EntryPages)
+	mergeNestedCounts(stats.ExitPages, other.ExitPages)
+	mergeNestedCounts(stats.Transitions, other.Transitions)
+	mergeNestedCounts(stats.Acquisitions, other.Acquisitions)
+	mergeNestedCounts(stats.PagesPerVisit, other.PagesPerVisit)
+	mergeNestedCounts(stats.Browsers, other.Browsers)
+	mergeNestedCounts(stats.OSes, other.OSes)
+	mergeNestedCounts(stats.Devices, other.Devices)
+	mergeNestedCounts(stats.Sites, other.Sites)
+	mergeNestedCounts(stats.Methods, other.Methods)
+	mergeNestedCounts(stats.RespCodes, other.RespCodes)
+	mergeNestedCounts(stats.SearchStrings, other.SearchStrings)
+	mergeNestedCounts(stats.Robots, other.Robots)
+	mergeCounts(stats.RobotVisits, other.RobotVisits)
+	mergeNestedCounts(stats.ContentBytes, other.ContentBytes)
+	mergeNestedCounts(stats.Sizes, other.Sizes)
+
+	for date, vm := range other.VisitMetrics {
+		value, ok := stats.VisitMetrics[date]
+		if !ok {
+			value = &VisitMetrics{}
+			stats.VisitMetrics[date] = value
+		}
+		value.Visits += vm.Visits
+		value.Duration += vm.Duration
+		value.Bounces += vm.Bounces
+	}
+
+	for ip, t := range other.FirstVisit {
+		if first, ok := stats.FirstVisit[ip]; !ok || t.Before(first) {
+			stats.FirstVisit[ip] = t
+		}
+	}
+	for ip, t := range other.LastVisit {
+		if last, ok := stats.LastVisit[ip]; !ok || t.After(last) {
+			stats.LastVisit[ip] = t
+		}
+	}
+	for country, code := range other.CountryCodes {
+		if _, ok := stats.CountryCodes[country]; !ok {
+			stats.CountryCodes[country] = code
+		}
+	}
+	for city, location := range other.CityLocations {
+		if _, ok := stats.CityLocations[city]; !ok {
+			stats.CityLocations[city] = location
+		}
+	}
+
+	for date, ips := range other.IPs {
+		if stats.IPs[date] == nil {
+			stats.IPs[date] = make(map[string]*HitsBytesVisits)
+		}
+		for ip, hbv := range ips {
+			stats.IPs[date][ip] = addHitsBytesVisits(stats.IPs[date][ip], hbv)
+		}
+	}
+	for date, countries := range other.CtrTraffic {
+		if stats.CtrTraffic[date] == nil {
+			stats.CtrTraffic[date] = make(map[string]*HitsBytesVisits)
+		}
+		for country, hbv := range countries {
+			stats.CtrTraffic[date][country] = addHitsBytesVisits(stats.CtrTraffic[date][country], hbv)
+		}
+	}
+	for date, systems := range other.ASNs {
+		if stats.ASNs[date] == nil {
+			stats.ASNs[date] = make(map[string]*HitsBytesVisits)
+		}
+		for system, hbv := range systems {
+			stats.ASNs[date][system] = addHitsBytesVisits(stats.ASNs[date][system], hbv)
+		}
+	}
+	for date, providers := range other.Datacenters {
+		if stats.Datacenters[date] == nil {
+			stats.Datacenters[date] = make(map[string]*HitsBytesVisits)
+		}
+		for provider, hbv := range providers {
+			stats.Datacenters[date][provider] = addHitsBytesVisits(stats.Datacenters[date][provider], hbv)
+		}
+	}
+	for date, lists := range other.Flagged {
+		if stats.Flagged[date] == nil {
+			stats.Flagged[date] = make(map[string]*HitsBytesVisits)
+		}
+		for list, hbv := range lists {
+			stats.Flagged[date][list] = addHitsBytesVisits(stats.Flagged[date][list], hbv)
+		}
+	}
+	for date, userAgents := range other.UserAgents {
+		if stats.UserAgents[date] == nil {
+			stats.UserAgents[date] = make(map[string]*HitsBytesVisits)
+		}
+		for userAgent, hbv := range userAgents {
+			stats.UserAgents[date][userAgent] = addHitsBytesVisits(stats.UserAgents[date][userAgent], hbv)
+		}
+	}
+	for date, paths := range other.URLPaths {
+		if stats.URLPaths[date] == nil {
+			stats.URLPaths[date] = make(map[string]map[string]*HitsBytes)
+		}
+		for urlPath, methods := range paths {
+			if stats.URLPaths[date][urlPath] == nil {
+				stats.URLPaths[date][urlPath] = make(map[string]*HitsBytes)
+			}
+			for method, hb := range methods {
+				stats.URLPaths[date][urlPath][method] = addHitsBytes(stats.URLPaths[date][urlPath][method], hb)
+			}
+		}
+	}
+	for date, paths := range other.Partial {
+		if stats.Partial[date] == nil {
+			stats.Partial[date] = make(map[string]*HitsBytes)
+		}
+		for urlPath, hb := range paths {
+			stats.Partial[date][urlPath] = addHitsBytes(stats.Partial[date][urlPath], hb)
+		}
+	}
+	for urlPath, size := range other.FullSizes {
+		stats.AddFullSize(urlPath, size)
+	}
+	for date, referrers := range other.Referrers {
+		if stats.Referrers[date] == nil {
+			stats.Referrers[date] = make(map[string]*HitsBytes)
+		}
+		for referrer, hb := range referrers {
+			stats.Referrers[date][referrer] = addHitsBytes(stats.Referrers[date][referrer], hb)
+		}
+	}
+	for date, paths := range other.NotFound {
+		if stats.NotFound[date] == nil {
+			stats.NotFound[date] = make(map[string]map[string]uint64)
+		}
+		mergeNestedCounts(stats.NotFound[date], paths)
+	}
+	for date, paths := range other.Errors {
+		if stats.Errors[date] == nil {
+			stats.Errors[date] = make(map[string]map[uint16]uint64)
+		}
+		mergeNestedCounts(stats.Errors[date], paths)
+	}
+	for date, domains := range other.ReferrerDomains {
+		if stats.ReferrerDomains[date] == nil {
+			stats.ReferrerDomains[date] = make(map[string]*HitsBytes)
+		}
+		for domain, hb := range domains {
+			stats.ReferrerDomains[date][domain] = addHitsBytes(stats.ReferrerDomains[date][domain], hb)
+		}
+	}
+
+	for month, data := range other.History {
+		value, ok := stats.History[month]
+		if !ok {
+			value = &HFPBVSData{Category: data.Category}
+			stats.History[month] = value
+		}
+		value.Hits += data.Hits
+		value.Files += data.Files
+		value.Pages += data.Pages
+		value.Bytes += data.Bytes
+		value.Visits += data.Visits
+		value.Sites += data.Sites
+	}
+}
+
+// mergeCounts adds the counters of src to dst.
+func mergeCounts[K comparable](dst, src map[K]uint64) {
+	for key, count := range src {
+		dst[key] += count
+	}
+}
+
+// mergeNestedCounts adds the per-date counters of src to dst.
+func mergeNestedCounts[K comparable](dst, src map[string]map[K]uint64) {
+	for date, counts := range src {
+		if dst[date] == nil {
+			dst[date] = make(map[K]uint64, len(counts))
+		}
+		mergeCounts(dst[date], counts)
+	}
+}
+
+// addHitsBytes returns dst with src added, allocating dst if it is nil.
+func addHitsBytes(dst, src *HitsBytes) *HitsBytes {
+	if dst == nil {
+		dst = &HitsBytes{}
+	}
+	dst.Hits += src.Hits
+	dst.Bytes += src.Bytes
+	return dst
+}
+
+// addHitsBytesVisits returns dst with src added, allocating dst if it is nil.
+func addHitsBytesVisits(dst, src *HitsBytesVisits) *HitsBytesVisits {
+	if dst == nil {
+		dst = &HitsBytesVisits{}
+	}
+	dst.Hits += src.Hits
+	dst.Bytes += src.Bytes
+	dst.Visits += src.Visits
+	return dst
+}
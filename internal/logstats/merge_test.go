@@ -0,0 +1,135 @@
+package logstats
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	early := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+	late := time.Date(2024, time.March, 2, 20, 0, 0, 0, time.UTC)
+
+	// hits returns the statistics of n hits by each visitor on date, in a single visit between first and last.
+	hits := func(date string, n uint64, first, last time.Time, visitors ...string) *LogStats {
+		stats := NewLogStats()
+		for _, visitor := range visitors {
+			stats.Hits[date] += n
+			stats.AddVisitMetrics(date, time.Minute, n)
+			if stats.Visits[date] == nil {
+				stats.Visits[date] = make(map[string]uint64)
+			}
+			stats.Visits[date][visitor]++
+			for i := uint64(0); i < n; i++ {
+				stats.UpdateHourStats(date, 10, 100, true, false)
+				stats.UpdateIPStats(date, visitor, 100, i == 0)
+			}
+			stats.FirstVisit[visitor] = first
+			stats.LastVisit[visitor] = last
+		}
+		return stats
+	}
+
+	tests := []struct {
+		name       string
+		stats      *LogStats
+		other      *LogStats
+		wantHits   map[string]uint64
+		wantVisits map[string]uint64
+		wantFirst  time.Time
+		wantLast   time.Time
+	}{
+		{
+			"separate days",
+			hits("2024-03-01", 2, early, early, "192.0.2.1"),
+			hits("2024-03-02", 3, late, late, "192.0.2.1"),
+			map[string]uint64{"2024-03-01": 2, "2024-03-02": 3},
+			map[string]uint64{"2024-03-01": 1, "2024-03-02": 1},
+			early, late,
+		},
+		{
+			"overlapping day",
+			hits("2024-03-01", 2, early, early, "192.0.2.1"),
+			hits("2024-03-01", 3, early, late, "192.0.2.1", "192.0.2.2"),
+			map[string]uint64{"2024-03-01": 8},
+			map[string]uint64{"2024-03-01": 3},
+			early, late,
+		},
+		{
+			"later stats first",
+			hits("2024-03-02", 1, late, late, "192.0.2.1"),
+			hits("2024-03-01", 1, early, early, "192.0.2.1"),
+			map[string]uint64{"2024-03-01": 1, "2024-03-02": 1},
+			map[string]uint64{"2024-03-01": 1, "2024-03-02": 1},
+			early, late,
+		},
+		{
+			"empty other",
+			hits("2024-03-01", 2, early, late, "192.0.2.1"),
+			NewLogStats(),
+			map[string]uint64{"2024-03-01": 2},
+			map[string]uint64{"2024-03-01": 1},
+			early, late,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			otherHits := maps.Clone(tt.other.Hits)
+			tt.stats.Merge(tt.other)
+
+			if !maps.Equal(tt.stats.Hits, tt.wantHits) {
+				t.Errorf("Hits = %v, want %v", tt.stats.Hits, tt.wantHits)
+			}
+			for date, want := range tt.wantHits {
+				if got := tt.stats.Hours[date][10].Hits; got != want {
+					t.Errorf("Hours[%s] hold %d hits, want %d", date, got, want)
+				}
+				var ipHits uint64
+				for _, hbv := range tt.stats.IPs[date] {
+					ipHits += hbv.Hits
+				}
+				if ipHits != want {
+					t.Errorf("IPs[%s] hold %d hits, want %d", date, ipHits, want)
+				}
+			}
+			for date, want := range tt.wantVisits {
+				var visits uint64
+				for _, count := range tt.stats.Visits[date] {
+					visits += count
+				}
+				if visits != want {
+					t.Errorf("Visits[%s] = %d, want %d", date, visits, want)
+				}
+				if got := tt.stats.VisitMetrics[date].Visits; got != want {
+					t.Errorf("VisitMetrics[%s].Visits = %d, want %d", date, got, want)
+				}
+			}
+			if got := tt.stats.FirstVisit["192.0.2.1"]; !got.Equal(tt.wantFirst) {
+				t.Errorf("FirstVisit = %v, want %v", got, tt.wantFirst)
+			}
+			if got := tt.stats.LastVisit["192.0.2.1"]; !got.Equal(tt.wantLast) {
+				t.Errorf("LastVisit = %v, want %v", got, tt.wantLast)
+			}
+			if !maps.Equal(tt.other.Hits, otherHits) {
+				t.Errorf("Merge modified other: Hits = %v, want %v", tt.other.Hits, otherHits)
+			}
+		})
+	}
+}
+
+func TestMergeKeepsLimits(t *testing.T) {
+	stats := NewLogStats()
+	stats.SetLimits(Limits{URLPaths: 2})
+	other := NewLogStats()
+	for _, urlPath := range []string{"/a", "/b", "/c"} {
+		other.UpdateURLStats("2024-03-01", urlPath, "GET", 100)
+	}
+
+	stats.Merge(other)
+	if got := len(stats.URLPaths["2024-03-01"]); got != 3 {
+		t.Errorf("merged URL paths = %d, want all 3", got)
+	}
+	if stats.limits.URLPaths != 2 {
+		t.Errorf("limits = %+v, want the limits of stats", stats.limits)
+	}
+}
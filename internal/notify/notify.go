@@ -0,0 +1,179 @@
+// Package notify posts a summary of the last day of web server log statistics to a webhook,
+// such as a Slack or Discord incoming webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// Formats of the webhook payload.
+const (
+	// Slack posts the message as the text of a Slack incoming webhook.
+	Slack = "slack"
+	// Discord posts the message as the content of a Discord webhook.
+	Discord = "discord"
+	// JSON posts the Summary as a JSON object.
+	JSON = "json"
+)
+
+// Formats are the supported payload formats.
+var Formats = []string{Slack, Discord, JSON}
+
+// DetectFormat returns the payload format of the webhook at url: Slack or Discord for their
+// webhook hosts, and JSON otherwise.
+func DetectFormat(url string) string {
+	switch {
+	case strings.Contains(url, "://hooks.slack.com/"):
+		return Slack
+	case strings.Contains(url, "://discord.com/api/webhooks/"), strings.Contains(url, "://discordapp.com/api/webhooks/"):
+		return Discord
+	}
+	return JSON
+}
+
+// Summary is the summary of the last day of the statistics, compared with the day before.
+type Summary struct {
+	// Site is the name of the site, if any.
+	Site string `json:"site,omitempty"`
+	// Date is the last day in the format "YYYY-MM-DD".
+	Date string `json:"date"`
+	// Hits is the number of hits on the day.
+	Hits uint64 `json:"hits"`
+	// Visits is the number of visits on the day.
+	Visits uint64 `json:"visits"`
+	// Bytes is the number of bytes sent on the day.
+	Bytes uint64 `json:"bytes"`
+	// ErrorRate is the percentage of hits with a 4xx or 5xx response code.
+	ErrorRate float64 `json:"error_rate"`
+	// TopURL is the most requested URL path of the day.
+	TopURL string `json:"top_url,omitempty"`
+	// TopURLHits is the number of hits on TopURL.
+	TopURLHits uint64 `json:"top_url_hits,omitempty"`
+	// Previous is the summary of the day before, if it is in the statistics.
+	Previous *Summary `json:"previous,omitempty"`
+}
+
+// NewSummary returns the summary of the last day of stats for the site, or nil if stats has no days.
+func NewSummary(stats *logstats.LogStats, site string) *Summary {
+	days := stats.DailyAggregates()
+	if len(days) == 0 {
+		return nil
+	}
+	last := slices.Max(slices.Collect(maps.Keys(days)))
+	summary := newDaySummary(stats, days, site, last)
+
+	if t, err := time.Parse(time.DateOnly, last); err == nil {
+		if previous := t.AddDate(0, 0, -1).Format(time.DateOnly); days[previous] != nil {
+			summary.Previous = newDaySummary(stats, days, site, previous)
+		}
+	}
+	return summary
+}
+
+// newDaySummary returns the summary of a day without the day before.
+func newDaySummary(stats *logstats.LogStats, days map[string]*logstats.HFPBVSData, site string, date string) *Summary {
+	data := days[date]
+	s := &Summary{Site: site, Date: date, Hits: data.Hits, Visits: data.Visits, Bytes: data.Bytes}
+
+	var errors uint64
+	for code, hits := range stats.RespCodes[date] {
+		if code >= 400 {
+			errors += hits
+		}
+	}
+	if data.Hits > 0 {
+		s.ErrorRate = float64(errors) / float64(data.Hits) * 100
+	}
+
+	for urlPath, methods := range stats.URLPaths[date] {
+		var hits uint64
+		for _, hb := range methods {
+			hits += hb.Hits
+		}
+		if hits > s.TopURLHits || (hits == s.TopURLHits && urlPath < s.TopURL) {
+			s.TopURL, s.TopURLHits = urlPath, hits
+		}
+	}
+	return s
+}
+
+// Message returns the summary as a one-line message, with the changes since the day before.
+func (s *Summary) Message() string {
+	var b strings.Builder
+	if s.Site != "" {
+		fmt.Fprintf(&b, "%s ", s.Site)
+	}
+	fmt.Fprintf(&b, "%s: %d hits%s, %d visits%s, %d KBytes%s, %.1f%% errors",
+		s.Date, s.Hits, s.change(func(d *Summary) uint64 { return d.Hits }),
+		s.Visits, s.change(func(d *Summary) uint64 { return d.Visits }),
+		s.Bytes/1024, s.change(func(d *Summary) uint64 { return d.Bytes }), s.ErrorRate)
+	if s.Previous != nil {
+		fmt.Fprintf(&b, " (%+.1f pts)", s.ErrorRate-s.Previous.ErrorRate)
+	}
+	if s.TopURL != "" {
+		fmt.Fprintf(&b, ", top URL %s (%d hits)", s.TopURL, s.TopURLHits)
+	}
+	return b.String()
+}
+
+// change returns the percentage change of a value since the day before, formatted as " (+12%)",
+// or an empty string if there is no day before or it had no value.
+func (s *Summary) change(value func(*Summary) uint64) string {
+	if s.Previous == nil || value(s.Previous) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%+.0f%%)", (float64(value(s))/float64(value(s.Previous))-1)*100)
+}
+
+// payload returns the body posted to a webhook in the format.
+func (s *Summary) payload(format string) ([]byte, error) {
+	switch format {
+	case Slack:
+		return json.Marshal(struct {
+			Text string `json:"text"`
+		}{s.Message()})
+	case Discord:
+		return json.Marshal(struct {
+			Content string `json:"content"`
+		}{s.Message()})
+	case JSON:
+		return json.Marshal(s)
+	}
+	return nil, fmt.Errorf("unsupported webhook format %q", format)
+}
+
+// Post posts the summary to the webhook at url in the format.
+func (s *Summary) Post(ctx context.Context, url string, format string) error {
+	body, err := s.payload(format)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("posting summary: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
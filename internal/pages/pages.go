@@ -0,0 +1,107 @@
+// Package pages decides which requested URL paths count as "pages".
+package pages
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultExtensions are the extensions of URL paths counted as pages if no extensions are configured.
+var DefaultExtensions = []string{
+	"htm", "html", "php", "php3", "php4", "asp", "aspx", "jsp", "js", "py", "shtml", "xhtml", "cgi", "pl",
+	"rb", "erb", "ejs", "phtml", "dhtml", "cfm", "do", "action", "axd", "ashx", "asmx", "svc", "faces", "jspx",
+	"xsp", "md", "markdown", "liquid", "mustache", "hbs", "wsdl", "wadl", "swagger",
+}
+
+// defaultExtensions is the set of DefaultExtensions.
+var defaultExtensions = extensionSet(DefaultExtensions)
+
+// Rules decide which URL paths are pages.
+// The zero value counts URL paths with one of the DefaultExtensions as pages.
+type Rules struct {
+	// extensions is the set of lowercase page extensions, without the leading dot, if configured.
+	extensions map[string]bool
+	// DirectoryIndex counts URL paths ending in "/", such as "/", as pages.
+	DirectoryIndex bool
+	// Extensionless counts URL paths whose last segment has no extension as pages,
+	// as is common for the routes of modern web applications.
+	Extensionless bool
+	// Include holds the patterns of URL paths that are always pages.
+	Include []*regexp.Regexp
+	// Exclude holds the patterns of URL paths that are never pages. Exclude takes precedence over Include.
+	Exclude []*regexp.Regexp
+}
+
+// AddExtensions adds page extensions, with or without the leading dot.
+// Once any extension is added, the DefaultExtensions no longer apply.
+func (r *Rules) AddExtensions(exts ...string) {
+	if r.extensions == nil {
+		r.extensions = make(map[string]bool)
+	}
+	for ext := range extensionSet(exts) {
+		r.extensions[ext] = true
+	}
+}
+
+// AddInclude adds a regular expression of URL paths that are always pages.
+func (r *Rules) AddInclude(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	r.Include = append(r.Include, re)
+	return nil
+}
+
+// AddExclude adds a regular expression of URL paths that are never pages.
+func (r *Rules) AddExclude(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	r.Exclude = append(r.Exclude, re)
+	return nil
+}
+
+// IsPage returns whether urlPath is a page. The query string, if any, is ignored for
+// matching extensions, but not for matching the Include and Exclude patterns.
+func (r *Rules) IsPage(urlPath string) bool {
+	for _, re := range r.Exclude {
+		if re.MatchString(urlPath) {
+			return false
+		}
+	}
+	for _, re := range r.Include {
+		if re.MatchString(urlPath) {
+			return true
+		}
+	}
+
+	urlPath, _, _ = strings.Cut(urlPath, "?")
+	if strings.HasSuffix(urlPath, "/") {
+		return r.DirectoryIndex
+	}
+	ext := path.Ext(urlPath)
+	if ext == "" {
+		return r.Extensionless
+	}
+
+	extensions := r.extensions
+	if extensions == nil {
+		extensions = defaultExtensions
+	}
+	return extensions[strings.ToLower(ext[1:])]
+}
+
+// extensionSet returns the set of lowercase extensions without the leading dot.
+func extensionSet(exts []string) map[string]bool {
+	set := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			set[ext] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Position is where a log file was read up to.
+type Position struct {
+	// File is the name of the log file when it was read.
+	File string
+	// Offset is the number of bytes read, up to the end of the last complete line.
+	Offset int64
+}
+
+// Positions holds where log files were read up to, keyed by the fingerprint of each file: the hash of its
+// first line. Log files are recognized by their first line rather than their name, so a log renamed by log
+// rotation is not read again, and a log truncated and written again from the start is read from the start.
+type Positions map[string]Position
+
+// lineScanner scans the lines of several log files in turn, as if they were one log.
+type lineScanner struct {
+	// fileNames are the log files still to be scanned after the current one.
+	fileNames []string
+	// fileName is the log file being scanned.
+	fileName string
+	// lineNr is the number of the current line in the log file being scanned, counted from where the
+	// scan of the file started.
+	lineNr int
+	// file is the log file being scanned, or nil before the first and after the last one.
+	file *os.File
+	// scanner scans the lines of file.
+	scanner *bufio.Scanner
+	// err is the first error opening or reading a log file.
+	err error
+
+	// positions, when set, is where the log files were read up to before, and receives where they are
+	// read up to now.
+	positions Positions
+	// fingerprint identifies the log file being scanned in positions, or is empty if it has no complete
+	// line yet.
+	fingerprint string
+	// offset is the position in the log file being scanned after the current line.
+	offset int64
+	// advance is the length of the current line, including its line ending.
+	advance int
+	// complete is set if the current line ends in a line ending, rather than at the end of the file.
+	complete bool
+}
+
+// newLineScanner returns a lineScanner of the log files, resuming each file from its position in
+// positions, if set.
+func newLineScanner(fileNames []string, positions Positions) *lineScanner {
+	return &lineScanner{fileNames: fileNames, positions: positions}
+}
+
+// Scan advances to the next line, opening the next log file when a file ends. It returns false once
+// all files were scanned, or if a file could not be opened or read.
+func (s *lineScanner) Scan() bool {
+	for s.err == nil {
+		if s.scanner != nil && s.scanner.Scan() {
+			if s.positions != nil && !s.complete {
+				// The last line may still be written, so it is left for the next run
+				continue
+			}
+			s.offset += int64(s.advance)
+			s.lineNr++
+			return true
+		}
+		if !s.next() {
+			return false
+		}
+	}
+	return false
+}
+
+// next closes the log file being scanned and opens the next one. It returns false if there is
+// none, or the file being scanned failed.
+func (s *lineScanner) next() bool {
+	if s.scanner != nil {
+		if err := s.scanner.Err(); err != nil {
+			s.err = fmt.Errorf("error reading %s: %v", s.fileName, err)
+		} else if s.positions != nil && s.fingerprint != "" {
+			s.positions[s.fingerprint] = Position{File: s.fileName, Offset: s.offset}
+		}
+	}
+	s.Close()
+	if s.err != nil || len(s.fileNames) == 0 {
+		return false
+	}
+	s.fileName, s.fileNames = s.fileNames[0], s.fileNames[1:]
+	s.lineNr, s.offset = 0, 0
+	s.file, s.err = os.Open(s.fileName)
+	if s.err != nil {
+		return false
+	}
+	if s.positions != nil {
+		if s.err = s.resume(); s.err != nil {
+			return false
+		}
+	}
+	s.scanner = bufio.NewScanner(s.file)
+	s.scanner.Split(s.splitLines)
+	return true
+}
+
+// resume moves to where the log file being scanned was read up to before, if it was.
+func (s *lineScanner) resume() error {
+	s.fingerprint = fingerprint(s.file)
+	pos, ok := s.positions[s.fingerprint]
+	if s.fingerprint == "" || !ok {
+		return nil
+	}
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	if pos.Offset > info.Size() {
+		// The file was truncated and written again from the same first line
+		return nil
+	}
+	if _, err := s.file.Seek(pos.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	s.offset = pos.Offset
+	slog.Debug("resuming log", "file", s.fileName, "read", pos.File, "offset", pos.Offset)
+	return nil
+}
+
+// fingerprintSize is the number of bytes of a long first line that its fingerprint is taken of.
+const fingerprintSize = 4096
+
+// fingerprint returns the hash of the first line of f, or "" if f has no complete line yet.
+func fingerprint(f *os.File) string {
+	buf := make([]byte, fingerprintSize)
+	n, _ := f.ReadAt(buf, 0)
+	line, _, ok := bytes.Cut(buf[:n], []byte("\n"))
+	if !ok && n < len(buf) {
+		return ""
+	}
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// splitLines splits lines as bufio.ScanLines does, and records the length of each line and whether
+// it is complete.
+func (s *lineScanner) splitLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if token != nil {
+		s.advance = advance
+		s.complete = data[advance-1] == '\n'
+	}
+	return advance, token, err
+}
+
+// Bytes returns the current line.
+func (s *lineScanner) Bytes() []byte {
+	return s.scanner.Bytes()
+}
+
+// Err returns the first error opening or reading a log file.
+func (s *lineScanner) Err() error {
+	return s.err
+}
+
+// Close closes the log file being scanned, if any.
+func (s *lineScanner) Close() {
+	if s.file != nil {
+		s.file.Close()
+		s.file, s.scanner = nil, nil
+	}
+}
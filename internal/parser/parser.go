@@ -1,25 +1,138 @@
 package parser
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
-	"os"
-	"regexp"
+	"path"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/rbscholtus/go-webalizer/internal/enrich"
+	"github.com/rbscholtus/go-webalizer/internal/group"
+	"github.com/rbscholtus/go-webalizer/internal/http"
 	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/pages"
+	"github.com/rbscholtus/go-webalizer/internal/search"
+	"github.com/rbscholtus/go-webalizer/internal/uaclass"
 )
 
 // the datetime format of the log timestamp
 const dateFormat = "02/Jan/2006:15:04:05 -0700"
 
-// 10-minute session timeout for a "new visit"
-const visitTimeout = 600 * time.Second
+// DefaultVisitTimeout is the time without hits after which the next hit of a visitor starts a new visit.
+const DefaultVisitTimeout = 600 * time.Second
 
-// extensions of files that resemble a "page"
-const fileExts = `\.(htm|html|php|php3|php4|asp|aspx|jsp|js|py|shtml|xhtml|cgi|pl|rb|erb|ejs|phtml|dhtml|cfm|do|action|axd|ashx|asmx|svc|faces|jspx|xsp|md|markdown|liquid|mustache|hbs|wsdl|wadl|swagger)`
+// Log formats supported by ProcessLog.
+const (
+	// FormatCombined is the NCSA combined log format, which also accepts lines in the common log format.
+	FormatCombined = "combined"
+	// FormatVHostCombined is the combined log format preceded by the virtual host and port ("%v:%p"),
+	// as written by Apache's vhost_combined format.
+	FormatVHostCombined = "vhost_combined"
+)
+
+// DaySink receives per-day statistics that can be evicted from memory.
+type DaySink interface {
+	// WriteDays stores the statistics of the given dates.
+	WriteDays(stats *logstats.LogStats, dates []string) error
+}
+
+// Entry is a parsed log entry with what the parser derived from it, as handed to an EntrySink.
+type Entry struct {
+	*LogEntry
+	// VHost is the virtual host, empty if the log format has none.
+	VHost string
+	// Client is the classification of the user agent.
+	Client uaclass.Client
+	// Geo is what the Enricher of the Options knows of the IP address, if any.
+	Geo enrich.Geo
+	// Page is whether the URL path is counted as a page.
+	Page bool
+	// NewVisit is whether the entry started a new visit, which is never the case for excluded methods.
+	NewVisit bool
+}
+
+// EntrySink receives every parsed log entry that is not ignored, such as a loader into an OLAP database.
+type EntrySink interface {
+	// WriteEntry receives an entry, which is reused for the next line once WriteEntry returns.
+	WriteEntry(entry *Entry) error
+	// Flush writes any buffered entries, once the whole log was parsed.
+	Flush() error
+}
+
+// Options controls how a log file is processed.
+type Options struct {
+	// Sink, when set, receives the statistics of each day once a line for a later day is seen.
+	// The written days are evicted from memory, keeping memory use bounded for long logs.
+	Sink DaySink
+	// Entries receive every parsed log entry that is not ignored.
+	Entries []EntrySink
+	// Limits caps the number of URL paths, referrers, and user agents kept per day.
+	Limits logstats.Limits
+	// VisitorByUserAgent identifies visitors by the pair of IP address and user agent instead of the
+	// IP address alone, which separates users behind NAT but splits users whose user agent changes.
+	VisitorByUserAgent bool
+	// Ignore selects log lines that are left out of all statistics.
+	Ignore group.Filters
+	// Format is the log format, FormatCombined if empty.
+	Format string
+	// Pages decides which URL paths are counted as pages.
+	Pages pages.Rules
+	// FileCodes are the response codes counted as files, DefaultFileCodes if empty.
+	FileCodes []uint16
+	// ExcludeMethods are the request methods that are only counted in the Methods breakdown,
+	// and left out of all other statistics, such as OPTIONS for CORS preflight requests.
+	ExcludeMethods []string
+	// Counts, when set, receives the number of lines read, parsed, invalid, and ignored.
+	Counts *Counts
+	// ExcludeRobots leaves hits by robots, as classified from their user agent, out of all statistics
+	// but the robots breakdown, so the report only counts humans.
+	ExcludeRobots bool
+	// Enricher, when set, looks up the country, city, and autonomous system of the IP address of the entries
+	// handed to the entry sinks, such as an enrich.Pipeline.
+	Enricher enrich.Enricher
+	// Anonymize, when set, replaces the IP address of each line that is not ignored before it is counted
+	// or handed to the entry sinks, such as a function returned by anonymize.New.
+	Anonymize func(string) string
+	// HostName, when set, is the host name of the site. Referrers on the site, with or without "www.", are
+	// internal: they are not counted as referrers, and visits entering from them count as direct.
+	HostName string
+	// Location, when set, is the time zone the timestamps are converted to before they are counted by day
+	// and hour. Otherwise, the timestamps keep the time zone they were logged in.
+	Location *time.Location
+	// VisitTimeout is the time without hits after which the next hit of a visitor starts a new visit,
+	// DefaultVisitTimeout if 0.
+	VisitTimeout time.Duration
+	// Positions, when set, is where the log files were read up to by earlier runs. The lines read before
+	// are skipped, a last line without a line ending is left for a later run, and Positions is updated
+	// with where the files are read up to, so the next run only reads the lines added since.
+	Positions Positions
+}
+
+// errUnexpectedFormat is the error of lines that do not match the log format.
+var errUnexpectedFormat = errors.New("unexpected format")
+
+// Counts holds the number of lines of a log file by how they were processed.
+type Counts struct {
+	// Lines is the number of lines read.
+	Lines int `json:"lines"`
+	// Parsed is the number of lines parsed and counted in the statistics.
+	Parsed int `json:"parsed"`
+	// Invalid is the number of lines that could not be parsed.
+	Invalid int `json:"invalid"`
+	// Ignored is the number of lines left out by the Ignore rules.
+	Ignored int `json:"ignored"`
+}
+
+// DefaultFileCodes are the response codes of requests that sent a file, as counted by classic Webalizer.
+var DefaultFileCodes = []uint16{200, 206}
 
 // unmarshalIP converts a IP/DNS string from a log entry.
 func (p *LogEntry) unmarshalIP(value []byte) (string, error) {
@@ -32,11 +145,13 @@ func (p *LogEntry) unmarshalTimestamp(value []byte) (time.Time, error) {
 	return time.Parse(dateFormat, string(value))
 }
 
-// unmarshalURLPath unescapes a URL path from a log entry.
+// unmarshalURLPath unescapes a URL path from a log entry, or warns and keeps it as is if it is escaped invalidly.
 func (p *LogEntry) unmarshalURLPath(value []byte) (string, error) {
 	unescapedPath, err := url.PathUnescape(string(value))
 	if err != nil {
-		return string(value), err
+		// The generated Extract prints returned errors to stderr, so warn here to go through slog.
+		slog.Warn("invalid URL path escape", "value", string(value), "error", err)
+		return string(value), nil
 	}
 	return unescapedPath, nil
 }
@@ -50,11 +165,13 @@ func (p *LogEntry) unmarshalSize(value []byte) (uint64, error) {
 	return strconv.ParseUint(string(value), 10, 64)
 }
 
-// unmarshalReferrer unescapes a referrer URL from a log entry.
+// unmarshalReferrer unescapes a referrer URL from a log entry, or warns and keeps it as is if it is escaped invalidly.
 func (p *LogEntry) unmarshalReferrer(value []byte) (string, error) {
 	unescapedRef, err := url.PathUnescape(string(value))
 	if err != nil {
-		return string(value), err
+		// The generated Extract prints returned errors to stderr, so warn here to go through slog.
+		slog.Warn("invalid referrer escape", "value", string(value), "error", err)
+		return string(value), nil
 	}
 	return unescapedRef, nil
 }
@@ -64,53 +181,279 @@ func (p *LogEntry) unmarshalUserAgent(value []byte) (string, error) {
 	return string(value), nil
 }
 
-// processLog parses the log file line-by-line and accumulates stats.
-func ProcessLog(fileName string) (*logstats.LogStats, error) {
-	// Open the access log file
-	file, err := os.Open(fileName)
+// splitVHost splits the leading "host:port" field of a vhost_combined log line from the rest of the line.
+func splitVHost(data []byte) (host string, port string, rest []byte, ok bool) {
+	field, rest, ok := bytes.Cut(data, []byte(" "))
+	if !ok {
+		return "", "", nil, false
+	}
+	i := bytes.LastIndexByte(field, ':')
+	if i < 0 {
+		return string(field), "", rest, true
+	}
+	return string(field[:i]), string(field[i+1:]), rest, true
+}
+
+// schemeForPort returns the URL scheme usually served on a port.
+func schemeForPort(port string) string {
+	switch port {
+	case "443", "8443":
+		return "https"
+	default:
+		return "http"
+	}
+}
+
+// isInternalReferrer reports whether referrer is a page on the site with the host name, with or without "www.".
+func isInternalReferrer(referrer string, hostName string) bool {
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return host != "" && host == strings.TrimPrefix(strings.ToLower(hostName), "www.")
+}
+
+// contentCategory classifies a URL path into a content category by its extension.
+// Paths without an extension are taken to be HTML pages, unless they are under an "/api/" directory.
+func contentCategory(urlPath string) string {
+	urlPath, _, _ = strings.Cut(urlPath, "?")
+	ext := strings.ToLower(path.Ext(urlPath))
+	if ext == "" {
+		if strings.Contains(urlPath+"/", "/api/") {
+			return http.CategoryAPI
+		}
+		return http.CategoryHTML
+	}
+	if contentType, ok := http.ExtContentTypes[ext]; ok {
+		return http.ContentCategory(contentType)
+	}
+	return http.CategoryOther
+}
+
+// flushDays writes all days except keep to the sink and evicts them from stats.
+func flushDays(sink DaySink, stats *logstats.LogStats, keep string) error {
+	dates := stats.Dates()
+	flushed := make([]string, 0, len(dates))
+	for _, date := range dates {
+		if date != keep {
+			flushed = append(flushed, date)
+		}
+	}
+	if len(flushed) == 0 {
+		return nil
+	}
+	if err := sink.WriteDays(stats, flushed); err != nil {
+		return err
+	}
+	for _, date := range flushed {
+		stats.Evict(date)
+	}
+	return nil
+}
+
+// writeEntry hands the entry to the sinks.
+func writeEntry(sinks []EntrySink, entry *Entry) error {
+	for _, sink := range sinks {
+		if err := sink.WriteEntry(entry); err != nil {
+			return fmt.Errorf("error writing log entry: %v", err)
+		}
+	}
+	return nil
+}
+
+// ProcessLog parses the log files line-by-line, in turn, and accumulates stats. ctx is passed to the Enricher of opts.
+func ProcessLog(ctx context.Context, fileNames []string, opts Options) (*logstats.LogStats, error) {
+	byHost, err := processLog(ctx, fileNames, opts, false)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
 		return nil, err
 	}
-	defer file.Close()
+	return byHost[""], nil
+}
+
+// ProcessLogByHost parses vhost_combined log files line-by-line, in turn, and accumulates separate stats
+// for every virtual host, keyed by host name.
+func ProcessLogByHost(ctx context.Context, fileNames []string, opts Options) (map[string]*logstats.LogStats, error) {
+	if opts.Format != FormatVHostCombined {
+		return nil, fmt.Errorf("splitting by virtual host requires the %q log format", FormatVHostCombined)
+	}
+	if opts.Sink != nil {
+		return nil, fmt.Errorf("splitting by virtual host is not supported with a statistics sink")
+	}
+	return processLog(ctx, fileNames, opts, true)
+}
 
-	lineNr := 0
+// logState is the state of the stats accumulated for one report.
+type logState struct {
+	stats    *logstats.LogStats
+	visits   sessions
+	lastDate string
+}
+
+// newLogState returns a new empty state.
+func newLogState(opts Options) *logState {
 	stats := logstats.NewLogStats()
+	stats.SetLimits(opts.Limits)
+	return &logState{stats: stats, visits: make(sessions)}
+}
+
+// processLog parses the log files line-by-line and accumulates stats, either for all lines
+// under the empty key, or by virtual host. Visits continue from one file into the next.
+func processLog(ctx context.Context, fileNames []string, opts Options, byHost bool) (map[string]*logstats.LogStats, error) {
+	switch opts.Format {
+	case "", FormatCombined, FormatVHostCombined:
+	default:
+		return nil, fmt.Errorf("unsupported log format %q", opts.Format)
+	}
+
+	// Open the access log files in turn
+	scanner := newLineScanner(fileNames, opts.Positions)
+	defer scanner.Close()
+
+	lineNr, invalid, ignored := 0, 0, 0
+	states := make(map[string]*logState)
+	if !byHost {
+		states[""] = newLogState(opts)
+	}
+	classifier := uaclass.NewClassifier()
+	visitTimeout := cmp.Or(opts.VisitTimeout, DefaultVisitTimeout)
 	line := LogEntry{}
+	fileCodes := opts.FileCodes
+	if len(fileCodes) == 0 {
+		fileCodes = DefaultFileCodes
+	}
+	excludedMethods := make(map[string]bool, len(opts.ExcludeMethods))
+	for _, method := range opts.ExcludeMethods {
+		excludedMethods[strings.ToUpper(method)] = true
+	}
 
-	fileExtRE := regexp.MustCompile(fileExts)
 	// var dumper = godump.Dumper{Theme: godump.DefaultTheme}
 
-	// Scan the log line-by-line
-	scanner := bufio.NewScanner(file)
+	// Scan the logs line-by-line
 	for scanner.Scan() {
 		// scan and parse a line
 		lineNr++
-		ok, err := line.Extract(scanner.Bytes())
+		data := scanner.Bytes()
+		host, vhost, scheme := "", "", ""
+		if opts.Format == FormatVHostCombined {
+			v, port, rest, ok := splitVHost(data)
+			if !ok {
+				slog.Warn("invalid line", "file", scanner.fileName, "line", scanner.lineNr, "error", "missing virtual host")
+				invalid++
+				continue
+			}
+			vhost = strings.ToLower(v)
+			if byHost {
+				host = vhost
+			}
+			scheme = schemeForPort(port)
+			data = rest
+		}
+		ok, err := line.Extract(data)
 		if !ok {
-			fmt.Fprintln(os.Stderr, "Invalid line", lineNr, ":", err)
+			if err == nil {
+				err = errUnexpectedFormat
+			}
+			slog.Warn("invalid line", "file", scanner.fileName, "line", scanner.lineNr, "error", err)
 			// dumper.Fprintln(os.Stderr, line)
+			invalid++
 			continue
 		}
 
 		// dumper.Fprintln(os.Stderr, line)
 		// break
 
+		// Skip lines matching an Ignore rule
+		if opts.Ignore.Match(line.IP, line.URLPath, line.Referrer, line.UserAgent) {
+			ignored++
+			continue
+		}
+		if opts.Anonymize != nil {
+			line.IP = opts.Anonymize(line.IP)
+		}
+		if opts.Location != nil {
+			line.Timestamp = line.Timestamp.In(opts.Location)
+		}
+
+		// Classify the user agent, which is cached, to tell robots from humans
+		client := classifier.Classify(line.UserAgent)
+		isRobot := client.Device == uaclass.Bot
+
+		// The entry handed to the entry sink once its statistics are counted
+		entry := Entry{LogEntry: &line, VHost: vhost, Client: client}
+		if opts.Enricher != nil && len(opts.Entries) > 0 {
+			opts.Enricher.Enrich(ctx, line.IP, &entry.Geo)
+		}
+
+		// Select the stats of the virtual host, or of the whole log
+		state, ok := states[host]
+		if !ok {
+			state = newLogState(opts)
+			states[host] = state
+		}
+		stats, visits := state.stats, state.visits
+
 		// If Visits was incremented for this log line
 		incVisits := false
 
 		date := line.Timestamp.Format("2006-01-02")
 
+		// Hand completed days to the sink when a new day starts
+		if opts.Sink != nil && date != state.lastDate {
+			if err := flushDays(opts.Sink, stats, date); err != nil {
+				return nil, fmt.Errorf("error writing statistics: %v", err)
+			}
+		}
+		state.lastDate = date
+
+		// ROBOTS: Count hits by robots only, leaving them out of all other statistics if excluded
+		if isRobot && opts.ExcludeRobots {
+			stats.AddRobot(date, client.Browser, false)
+			if err := writeEntry(opts.Entries, &entry); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// METHOD: count hits by method, including excluded methods
+		if _, ok := stats.Methods[date]; !ok {
+			stats.Methods[date] = make(map[string]uint64)
+		}
+		stats.Methods[date][line.Method]++
+
+		// Leave excluded methods, such as CORS preflights, out of all other statistics
+		if excludedMethods[strings.ToUpper(line.Method)] {
+			if err := writeEntry(opts.Entries, &entry); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		// HITS: Every successfully parsed line is a hit
 		stats.Hits[date]++
 
-		// FILES: Increment files for successful responses (HTTP 200)
-		if line.RespCode == 200 {
+		// FILES: Increment files for responses that sent a file (HTTP 200 and 206 by default)
+		isFile := slices.Contains(fileCodes, line.RespCode)
+		if isFile {
 			stats.Files[date]++
 		}
 
-		// PAGES: Classify as a "page" by extension
-		if fileExtRE.FindStringIndex(line.URLPath) != nil {
+		// NOT MODIFIED: Count cache hits separately, as they did not send a file
+		if line.RespCode == 304 {
+			stats.NotModified[date]++
+		}
+
+		// PARTIAL CONTENT: Attribute range request bytes to the URL path, and track the full size of each URL path
+		switch line.RespCode {
+		case 206:
+			stats.AddPartial(date, line.URLPath, line.Size)
+		case 200:
+			stats.AddFullSize(line.URLPath, line.Size)
+		}
+
+		// PAGES: Classify as a "page" by the page rules
+		isPage := opts.Pages.IsPage(line.URLPath)
+		if isPage {
 			stats.Pages[date]++
 		}
 		// else {
@@ -125,32 +468,61 @@ func ProcessLog(fileName string) (*logstats.LogStats, error) {
 		// BYTES: Track total bytes sent (if numeric)
 		stats.Bytes[date] += line.Size
 
+		// SCHEMES: Track secure and plaintext hits if the log format includes the port
+		if scheme != "" {
+			stats.AddScheme(date, scheme)
+		}
+
+		// SIZES: Track the response size distribution
+		stats.AddSize(date, line.Size)
+
+		// CONTENT: Track bytes by content category
+		stats.AddContentBytes(date, contentCategory(line.URLPath), line.Size)
+
+		// HOURS: Track hits, files, pages, and bytes by hour of day
+		stats.UpdateHourStats(date, line.Timestamp.Hour(), line.Size, isFile, isPage)
+
+		// Identify the visitor by IP address, or by IP address and user agent
+		visitor := line.IP
+		if opts.VisitorByUserAgent {
+			visitor = logstats.VisitorKey(line.IP, line.UserAgent)
+		}
+
 		// VISITS: Determine if this is a new "visit" based on timeout
-		if line.Timestamp.Sub(stats.LastVisit[line.IP]) > visitTimeout {
+		if line.Timestamp.Sub(stats.LastVisit[visitor]) > visitTimeout {
 			if _, ok := stats.Visits[date]; !ok {
 				stats.Visits[date] = make(map[string]uint64)
 			}
-			stats.Visits[date][line.IP]++
+			stats.Visits[date][visitor]++
+			visits.start(stats, visitor, date, line.Timestamp)
 			incVisits = true
+
+			// BROWSERS, OSES, DEVICES: Count the classified user agent of the visit
+			stats.AddClient(date, client.Browser, client.Version, client.OS, client.Device)
 		}
 
-		// Track first and last hit time
-		if _, ok := stats.FirstVisit[line.IP]; !ok {
-			stats.FirstVisit[line.IP] = line.Timestamp
+		// ROBOTS: Count hits and visits by robots
+		if isRobot {
+			stats.AddRobot(date, client.Browser, incVisits)
 		}
-		stats.LastVisit[line.IP] = line.Timestamp
 
-		// SITES: Count hits by IP
-		if _, ok := stats.Sites[date]; !ok {
-			stats.Sites[date] = make(map[string]uint64)
+		// ENTRY/EXIT PAGES, DURATION, BOUNCES: Track the hit in the ongoing visit
+		// Referrers on the site itself are not where visitors came from
+		referrer := line.Referrer
+		internal := opts.HostName != "" && isInternalReferrer(referrer, opts.HostName)
+		if internal {
+			referrer = "-"
 		}
-		stats.Sites[date][line.IP]++
+		visits.addHit(stats, visitor, date, line.Timestamp, line.URLPath, referrer, isPage)
 
-		// METHOD: count hits by method
-		if _, ok := stats.Methods[date]; !ok {
-			stats.Methods[date] = make(map[string]uint64)
+		// Track first and last hit time
+		stats.AddVisitorHit(visitor, line.Timestamp)
+
+		// SITES: Count hits by visitor
+		if _, ok := stats.Sites[date]; !ok {
+			stats.Sites[date] = make(map[string]uint64)
 		}
-		stats.Methods[date][line.Method]++
+		stats.Sites[date][visitor]++
 
 		// METHOD: count hits by response code
 		if _, ok := stats.RespCodes[date]; !ok {
@@ -158,6 +530,16 @@ func ProcessLog(fileName string) (*logstats.LogStats, error) {
 		}
 		stats.RespCodes[date][line.RespCode]++
 
+		// ERRORS: Count 4xx and 5xx responses by URL path and response code
+		if line.RespCode >= 400 {
+			stats.AddError(date, line.URLPath, line.RespCode)
+		}
+
+		// NOT FOUND: Count broken links by URL path and referrer
+		if line.RespCode == 404 {
+			stats.AddNotFound(date, line.URLPath, line.Referrer)
+		}
+
 		// IPs: Reports hits, bytes, and visits by IP
 		stats.UpdateIPStats(date, line.IP, line.Size, incVisits)
 
@@ -167,15 +549,52 @@ func ProcessLog(fileName string) (*logstats.LogStats, error) {
 		// URLPaths: Report hits and bytes by URLPath and Method
 		stats.UpdateURLStats(date, line.URLPath, line.Method, line.Size)
 
-		// REFERRERS: Reports hits and bytes by Referrer
-		stats.UpdateReferrerStats(date, line.Referrer, line.Size)
+		// REFERRERS: Reports hits and bytes by Referrer, leaving out internal referrers
+		if !internal {
+			stats.UpdateReferrerStats(date, line.Referrer, line.Size)
+		}
+
+		// SEARCH STRINGS: Count hits referred by search engines by search string
+		if query, ok := search.Parse(line.Referrer); ok {
+			stats.AddSearchString(date, query)
+		}
+
+		// ENTRIES: Hand the entry to the entry sink
+		entry.Page, entry.NewVisit = isPage, incVisits
+		if err := writeEntry(opts.Entries, &entry); err != nil {
+			return nil, err
+		}
 	}
 
 	// Report any errors from scanning
 	if err := scanner.Err(); err != nil {
-		msg := fmt.Errorf("error reading file: %v", err)
-		return nil, msg
+		return nil, err
+	}
+	slog.Debug("parsed log", "files", fileNames, "lines", lineNr, "invalid", invalid, "ignored", ignored)
+	if opts.Counts != nil {
+		*opts.Counts = Counts{Lines: lineNr, Parsed: lineNr - invalid - ignored, Invalid: invalid, Ignored: ignored}
+	}
+
+	// Write the entries still buffered by the entry sinks
+	for _, sink := range opts.Entries {
+		if err := sink.Flush(); err != nil {
+			return nil, fmt.Errorf("error writing log entry: %v", err)
+		}
+	}
+
+	byHostStats := make(map[string]*logstats.LogStats, len(states))
+	for host, state := range states {
+		// End all ongoing visits to record their exit pages
+		state.visits.endAll(state.stats)
+
+		// Hand the remaining days to the sink
+		if opts.Sink != nil {
+			if err := flushDays(opts.Sink, state.stats, ""); err != nil {
+				return nil, fmt.Errorf("error writing statistics: %v", err)
+			}
+		}
+		byHostStats[host] = state.stats
 	}
 
-	return stats, nil
+	return byHostStats, nil
 }
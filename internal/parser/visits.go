@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// session tracks an ongoing visit.
+type session struct {
+	// startDate is the date of the first hit of the visit, in the format "YYYY-MM-DD".
+	startDate string
+	// start is the timestamp of the first hit of the visit.
+	start time.Time
+	// last is the timestamp of the latest hit of the visit.
+	last time.Time
+	// pages is the number of page views in the visit.
+	pages uint64
+	// entry is the first page of the visit, or empty if no page was requested yet.
+	entry string
+	// exit is the last page of the visit so far.
+	exit string
+	// exitDate is the date of the last page of the visit, in the format "YYYY-MM-DD".
+	exitDate string
+	// referrer is the referring domain of the entry page, or logstats.DirectReferrer.
+	referrer string
+}
+
+// sessions tracks the ongoing visit of every visitor, keyed by visitor.
+type sessions map[string]*session
+
+// start ends the ongoing visit of visitor, if any, and begins a new one.
+func (ss sessions) start(stats *logstats.LogStats, visitor string, date string, timestamp time.Time) {
+	if s, ok := ss[visitor]; ok {
+		s.end(stats)
+	}
+	ss[visitor] = &session{startDate: date, start: timestamp, last: timestamp}
+}
+
+// addHit records a hit in the ongoing visit of visitor, referred by referrer.
+// The first page of a visit is counted as its entry page, and each later page as a transition
+// from the previous page. Reloads of the same page are not counted as transitions.
+func (ss sessions) addHit(stats *logstats.LogStats, visitor string, date string, timestamp time.Time, urlPath string, referrer string, isPage bool) {
+	s, ok := ss[visitor]
+	if !ok {
+		return
+	}
+	if timestamp.After(s.last) {
+		s.last = timestamp
+	}
+	if !isPage {
+		return
+	}
+
+	s.pages++
+	if s.entry == "" {
+		s.entry = urlPath
+		s.referrer = logstats.DirectReferrer
+		if domain, ok := logstats.ReferrerDomain(referrer); ok {
+			s.referrer = domain
+		}
+		stats.AddEntryPage(date, urlPath)
+	} else if s.exit != urlPath {
+		stats.AddTransition(date, s.exit, urlPath)
+	}
+	s.exit = urlPath
+	s.exitDate = date
+}
+
+// endAll ends all ongoing visits.
+func (ss sessions) endAll(stats *logstats.LogStats) {
+	for visitor, s := range ss {
+		s.end(stats)
+		delete(ss, visitor)
+	}
+}
+
+// end records the exit page, acquisition, duration, and page count of the visit.
+func (s *session) end(stats *logstats.LogStats) {
+	if s.exit != "" {
+		stats.AddExitPage(s.exitDate, s.exit)
+		stats.AddAcquisition(s.startDate, s.referrer, s.entry, s.exit)
+	}
+	stats.AddVisitMetrics(s.startDate, s.last.Sub(s.start), s.pages)
+}
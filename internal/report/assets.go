@@ -0,0 +1,47 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+)
+
+//go:generate curl -sSfL --create-dirs -o assets/echarts.min.js https://go-echarts.github.io/go-echarts-assets/assets/echarts.min.js
+//go:generate curl -sSfL --create-dirs -o assets/maps/world.js https://go-echarts.github.io/go-echarts-assets/assets/maps/world.js
+
+// assetsFS holds the echarts assets inlined into self-contained pages, named like the assets of the charts
+// relative to assetsHost. They are downloaded by go generate.
+//
+//go:embed assets
+var assetsFS embed.FS
+
+// readAsset returns the content of an embedded asset.
+func readAsset(name string) (string, error) {
+	content, err := fs.ReadFile(assetsFS, "assets/"+name)
+	if err != nil {
+		return "", fmt.Errorf("asset %s is not embedded, run go generate ./internal/report and rebuild: %v", name, err)
+	}
+	return string(content), nil
+}
+
+// inlineAssets returns the content of the embedded JavaScript and CSS assets with the given names.
+func inlineAssets(jsNames []string, cssNames []string) ([]template.JS, []template.CSS, error) {
+	js := make([]template.JS, 0, len(jsNames))
+	for _, name := range jsNames {
+		content, err := readAsset(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		js = append(js, template.JS(content))
+	}
+	css := make([]template.CSS, 0, len(cssNames))
+	for _, name := range cssNames {
+		content, err := readAsset(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		css = append(css, template.CSS(content))
+	}
+	return js, css, nil
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// Card is a key figure shown in large type at the top of a page.
+type Card struct {
+	// Label names the figure, such as "Visits".
+	Label string
+	// Value is the formatted figure.
+	Value string
+}
+
+// AddCards adds cards to the top of the page, shown below the header and above all charts and tables.
+func (page *Page) AddCards(cards ...Card) *Page {
+	page.cards = append(page.cards, cards...)
+	return page
+}
+
+// SummaryCards returns the cards of the key figures of summary: the hits, visits, unique sites, bytes,
+// error rate, and the period covered.
+func SummaryCards(summary *logstats.Summary) []Card {
+	period := "none"
+	if summary.First != "" {
+		first, _ := time.Parse("2006-01-02", summary.First)
+		last, _ := time.Parse("2006-01-02", summary.Last)
+		period = first.Format("Jan 2 2006") + " - " + last.Format("Jan 2 2006")
+	}
+	return []Card{
+		{Label: "Hits", Value: formatCount(summary.Hits)},
+		{Label: "Visits", Value: formatCount(summary.Visits)},
+		{Label: "Unique Sites", Value: formatCount(summary.Sites)},
+		{Label: "Transferred", Value: formatBytes(summary.Bytes)},
+		{Label: "Error Rate", Value: fmt.Sprintf("%.1f%%", summary.ErrorRate())},
+		{Label: "Period", Value: period},
+	}
+}
+
+// formatCount formats n with thousands separators, such as "12,345".
+func formatCount(n uint64) string {
+	s := strconv.FormatUint(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatBytes formats a number of bytes in binary units, such as "1.5 MiB".
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
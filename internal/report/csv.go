@@ -0,0 +1,89 @@
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/rbscholtus/go-webalizer/internal/staticchart"
+)
+
+// chartCSV returns the data of a bar, line, pie, or map chart as a CSV data URL, with a row per category and a
+// column per series, or "" for other charts.
+func chartCSV(chart Chart) template.URL {
+	var records [][]string
+	if c, ok := chart.(*charts.Map); ok {
+		records = mapRecords(c.MultiSeries)
+	} else if static, ok := newStaticChart(chart); ok {
+		records = staticRecords(static)
+	}
+	if len(records) < 2 {
+		return ""
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.WriteAll(records); err != nil {
+		return ""
+	}
+	return template.URL("data:text/csv;base64," + base64.StdEncoding.EncodeToString(b.Bytes()))
+}
+
+// staticRecords returns the header and rows of the CSV data of a static chart.
+func staticRecords(static *staticchart.Chart) [][]string {
+	header := []string{"Category"}
+	for _, s := range static.Series {
+		header = append(header, s.Name)
+	}
+	records := [][]string{header}
+	for i, category := range static.Categories {
+		row := []string{csvLabel(category)}
+		for _, s := range static.Series {
+			// Pie charts hold a single series with a value per category.
+			if i < len(s.Values) {
+				row = append(row, strconv.FormatFloat(s.Values[i], 'f', -1, 64))
+			} else {
+				row = append(row, "")
+			}
+		}
+		records = append(records, row)
+	}
+	return records
+}
+
+// mapRecords returns the header and rows of the CSV data of the series of a map chart, with a row per region.
+func mapRecords(series []charts.SingleSeries) [][]string {
+	header := []string{"Region"}
+	var regions []string
+	values := make(map[string][]string)
+	for i, s := range series {
+		header = append(header, s.Name)
+		data, _ := s.Data.([]opts.MapData)
+		for _, d := range data {
+			if _, ok := values[d.Name]; !ok {
+				regions = append(regions, d.Name)
+				values[d.Name] = make([]string, len(series))
+			}
+			values[d.Name][i] = strconv.FormatFloat(toFloat(d.Value), 'f', -1, 64)
+		}
+	}
+	records := [][]string{header}
+	for _, region := range regions {
+		records = append(records, append([]string{csvLabel(region)}, values[region]...))
+	}
+	return records
+}
+
+// csvLabel returns a category label that spreadsheets do not evaluate as a formula, as labels such as
+// referrers and URLs come from the log files.
+func csvLabel(label string) string {
+	if label != "" && strings.ContainsRune("=+-@\t\r", rune(label[0])) {
+		return "'" + label
+	}
+	return label
+}
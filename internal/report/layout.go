@@ -0,0 +1,107 @@
+package report
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Layout selects the charts of a page and arranges them in a grid.
+type Layout struct {
+	// Columns is the number of charts placed side by side on wide screens, or 0 or 1 to stack them.
+	// Tables always span the full width.
+	Columns int
+	// Hidden holds the titles of the charts left out of the page.
+	Hidden []string
+	// Spans holds the number of columns a chart spans by title. Charts span one column by default.
+	Spans map[string]int
+}
+
+// SetLayout sets the layout of the page. It applies to the charts added afterwards.
+func (page *Page) SetLayout(layout Layout) *Page {
+	page.layout = layout
+	return page
+}
+
+// hidden reports whether the chart with the title is left out of the page.
+func (layout Layout) hidden(title string) bool {
+	for _, hidden := range layout.Hidden {
+		if matchTitle(title, hidden) {
+			return true
+		}
+	}
+	return false
+}
+
+// span returns the number of columns the chart with the title spans, or 0 for the default of one column.
+func (layout Layout) span(title string) int {
+	if layout.Columns < 2 {
+		return 0
+	}
+	for name, span := range layout.Spans {
+		if matchTitle(title, name) {
+			return min(span, layout.Columns)
+		}
+	}
+	return 0
+}
+
+// matchTitle reports whether a chart title matches name, ignoring case. A name also matches the titles
+// it is the first words of, so "Daily usage" matches "Daily usage for January 2024".
+func matchTitle(title, name string) bool {
+	title, name = strings.ToLower(title), strings.ToLower(name)
+	return title == name || strings.HasPrefix(title, name+" ")
+}
+
+// anchors returns the sections of the page with an HTML id each, and the navigation linking to the first
+// section of each title. Tables keep their own id, if any.
+func (page *Page) anchors() ([]section, []Link) {
+	sections := make([]section, len(page.sections))
+	used := make(map[string]bool)
+	listed := make(map[string]bool)
+	var nav []Link
+	for i, s := range page.sections {
+		id := ""
+		if s.Table != nil && s.Table.ID != "" {
+			id = s.Table.ID
+		} else if slug := slugify(s.Title); slug != "" {
+			id = slug
+			for n := 2; used[id]; n++ {
+				id = slug + "-" + strconv.Itoa(n)
+			}
+		}
+		used[id] = true
+		if s.Table != nil {
+			table := *s.Table
+			table.ID = id
+			s.Table = &table
+		} else {
+			s.ID = id
+		}
+		sections[i] = s
+
+		if id != "" && !listed[s.Title] {
+			listed[s.Title] = true
+			nav = append(nav, Link{Text: s.Title, URL: "#" + id})
+		}
+	}
+	return sections, nav
+}
+
+// slugify returns title in lower case with every run of characters other than letters and digits
+// replaced by a hyphen, for use as an HTML id.
+func slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if 'a' <= r && r <= 'z' || '0' <= r && r <= '9' {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
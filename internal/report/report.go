@@ -0,0 +1,288 @@
+// Package report assembles charts and tables into HTML report pages.
+package report
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/render"
+)
+
+// generator identifies the pages written by this package, in the generator meta tag.
+const generator = "go-webalizer"
+
+// assetsHost is the host the echarts JavaScript assets are loaded from.
+const assetsHost = "https://go-echarts.github.io/go-echarts-assets/assets/"
+
+// Chart is a go-echarts chart that can be rendered as a section of a page.
+type Chart interface {
+	GetAssets() opts.Assets
+	Validate()
+	RenderSnippet() render.ChartSnippet
+	Accept(visitor charts.ConfigurationVisitor)
+}
+
+// Table is a titled table of pre-formatted cells.
+// All columns but the first are right-aligned, as they usually hold numbers.
+type Table struct {
+	// Title is shown above the table.
+	Title string
+	// Headers are the column headers.
+	Headers []string
+	// Rows are the table rows, each holding one cell per header.
+	Rows [][]string
+	// Links are the URLs the first cell of each row links to. Rows without a link have an empty URL.
+	Links []string
+	// ID is the HTML id of the table, so it can be linked to as "#ID", or empty for none.
+	ID string
+}
+
+// Link is a link in the page header to a related page.
+type Link struct {
+	// Text is the link text.
+	Text string
+	// URL is the address of the related page, usually relative to the page.
+	URL string
+}
+
+// section is a chart or a table on a page.
+type section struct {
+	// Element is the HTML element of a chart.
+	Element template.HTML
+	// Script is the script that initializes a chart.
+	Script template.HTML
+	// Table is set for table sections.
+	Table *Table
+	// Span is the number of grid columns a chart spans, or 0 for one column.
+	Span int
+	// Title is the title of the chart or table, listed in the page navigation.
+	Title string
+	// ID is the HTML id of a chart section, so the navigation can link to it.
+	ID string
+	// CSV is a data URL holding the data of a chart as CSV, or empty if it cannot be exported.
+	CSV template.URL
+}
+
+// Page is an HTML report page made of charts and tables, in the order they were added.
+type Page struct {
+	// Title is the HTML page title.
+	Title string
+	// assets are the JavaScript and CSS assets required by the charts.
+	assets opts.Assets
+	// notes are shown in the page header, explaining how the numbers were computed.
+	notes []string
+	// links are shown in the page header, linking to related pages.
+	links []Link
+	// cards are the key figures shown below the page header.
+	cards []Card
+	// feeds are the Atom feeds announced in the page head.
+	feeds []Link
+	// sections are the charts and tables on the page.
+	sections []section
+	// templates are the templates the page is rendered with, or nil for the default templates.
+	templates *template.Template
+	// theme is the theme of the page and the charts added after it was set.
+	theme Theme
+	// selfContained inlines the echarts assets instead of loading them from assetsHost.
+	selfContained bool
+	// staticCharts is the format of the static images charts are rendered as, or empty for echarts.
+	staticCharts string
+	// layout selects the charts added to the page and arranges them.
+	layout Layout
+}
+
+// NewPage returns a new empty page.
+func NewPage(title string) *Page {
+	page := &Page{Title: title, theme: DefaultTheme}
+	page.assets.InitAssets()
+	return page
+}
+
+// AddCharts adds charts to the page and merges their assets, or adds them as static images.
+// Charts hidden by the layout of the page are skipped.
+func (page *Page) AddCharts(charts ...Chart) *Page {
+	for _, chart := range charts {
+		title := chartTitle(chart)
+		if page.layout.hidden(title) {
+			continue
+		}
+		if page.staticCharts != "" {
+			page.addStaticChart(chart)
+			continue
+		}
+		assets := chart.GetAssets()
+		for _, v := range assets.JSAssets.Values {
+			page.assets.JSAssets.Add(v)
+		}
+		for _, v := range assets.CSSAssets.Values {
+			page.assets.CSSAssets.Add(v)
+		}
+		for _, v := range assets.CustomizedJSAssets.Values {
+			page.assets.CustomizedJSAssets.Add(v)
+		}
+		for _, v := range assets.CustomizedCSSAssets.Values {
+			page.assets.CustomizedCSSAssets.Add(v)
+		}
+
+		if page.theme.Colors != nil || len(page.theme.SeriesColors) > 0 {
+			chart.Accept(paletteVisitor{colors: page.theme.Colors, seriesColors: page.theme.SeriesColors})
+		}
+		chart.Validate()
+		snippet := chart.RenderSnippet()
+		page.sections = append(page.sections, section{
+			Element: template.HTML(snippet.Element),
+			Script:  template.HTML(snippet.Script),
+			Span:    page.layout.span(title),
+			Title:   title,
+			CSV:     chartCSV(chart),
+		})
+	}
+	return page
+}
+
+// AddTables adds tables to the page. Nil tables are skipped.
+func (page *Page) AddTables(tables ...*Table) *Page {
+	for _, table := range tables {
+		if table == nil {
+			continue
+		}
+		page.sections = append(page.sections, section{Table: table, Title: table.Title})
+	}
+	return page
+}
+
+// AddNotes adds notes to the page header.
+func (page *Page) AddNotes(notes ...string) *Page {
+	page.notes = append(page.notes, notes...)
+	return page
+}
+
+// AddLinks adds links to related pages to the page header.
+func (page *Page) AddLinks(links ...Link) *Page {
+	page.links = append(page.links, links...)
+	return page
+}
+
+// AddFeeds announces Atom feeds in the page head, so browsers and feed readers can discover them.
+func (page *Page) AddFeeds(feeds ...Link) *Page {
+	page.feeds = append(page.feeds, feeds...)
+	return page
+}
+
+// templatesFS holds the default templates.
+//
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// defaultTemplates are the default templates of a report page.
+var defaultTemplates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// LoadTemplates returns the default templates, overridden by the *.html files in dir.
+// Each file defines the template named after it, so a directory may override just "style.html".
+// The page is rendered by "page.html", with the fields Generator, Title, Theme, Layout, JSAssets, CSSAssets, JSInline, CSSInline, Notes,
+// Links, Feeds, Cards, Nav, and Sections, where JSInline and CSSInline hold the content of the assets of self-contained pages,
+// and Nav links to the sections by title.
+// The cards are rendered by "cards.html".
+// Pages must keep the generator meta tag of the default "page.html", or they cannot be overwritten later.
+// Each section has either a Table, rendered by "table.html", or the Element, Script, grid column Span, ID, and CSV data URL of a chart.
+func LoadTemplates(dir string) (*template.Template, error) {
+	tpl, err := defaultTemplates.Clone()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.html templates in %s", dir)
+	}
+	return tpl.ParseFiles(files...)
+}
+
+// SetTemplates sets the templates the page is rendered with, as returned by LoadTemplates.
+// A nil tpl selects the default templates.
+func (page *Page) SetTemplates(tpl *template.Template) *Page {
+	page.templates = tpl
+	return page
+}
+
+// SetTheme sets the theme of the page. It applies to the charts added afterwards.
+func (page *Page) SetTheme(theme Theme) *Page {
+	page.theme = theme
+	return page
+}
+
+// SetSelfContained selects whether the echarts assets are inlined into the page, so it works offline,
+// instead of being loaded from the web. The assets must be embedded with go generate.
+func (page *Page) SetSelfContained(selfContained bool) *Page {
+	page.selfContained = selfContained
+	return page
+}
+
+// Render writes the page as an HTML document to w.
+func (page *Page) Render(w io.Writer) error {
+	var jsAssets, cssAssets []string
+	var jsInline []template.JS
+	var cssInline []template.CSS
+	if page.staticCharts != "" {
+		// Static charts need no assets.
+	} else if page.selfContained {
+		var err error
+		jsInline, cssInline, err = inlineAssets(page.assets.JSAssets.Values, page.assets.CSSAssets.Values)
+		if err != nil {
+			return err
+		}
+		jsAssets = page.assets.CustomizedJSAssets.Values
+		cssAssets = page.assets.CustomizedCSSAssets.Values
+	} else {
+		page.assets.Validate(assetsHost)
+		jsAssets = slices.Concat(page.assets.JSAssets.Values, page.assets.CustomizedJSAssets.Values)
+		cssAssets = slices.Concat(page.assets.CSSAssets.Values, page.assets.CustomizedCSSAssets.Values)
+	}
+
+	sections, nav := page.anchors()
+
+	tpl := page.templates
+	if tpl == nil {
+		tpl = defaultTemplates
+	}
+	return tpl.ExecuteTemplate(w, "page.html", struct {
+		Generator string
+		Title     string
+		Theme     Theme
+		Layout    Layout
+		JSAssets  []string
+		CSSAssets []string
+		JSInline  []template.JS
+		CSSInline []template.CSS
+		Notes     []string
+		Links     []Link
+		Feeds     []Link
+		Cards     []Card
+		Nav       []Link
+		Sections  []section
+	}{generator, page.Title, page.theme, page.layout, jsAssets, cssAssets, jsInline, cssInline, page.notes, page.links, page.feeds, page.cards,
+		nav, sections})
+}
+
+// generatorTag is the meta tag that marks a page as written by this package.
+var generatorTag = []byte(`<meta name="generator" content="` + generator + `">`)
+
+// IsReport reports whether r holds a page written by this package, by looking for
+// the generator meta tag in its head.
+func IsReport(r io.Reader) (bool, error) {
+	head := make([]byte, 4096)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	return bytes.Contains(head[:n], generatorTag), nil
+}
@@ -0,0 +1,172 @@
+package report
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"html/template"
+	"strconv"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/rbscholtus/go-webalizer/internal/staticchart"
+)
+
+// Static chart formats.
+const (
+	// StaticSVG renders charts as inline SVG elements.
+	StaticSVG = "svg"
+	// StaticPNG renders charts as PNG images embedded in data URLs.
+	StaticPNG = "png"
+)
+
+// SetStaticCharts selects whether the charts added afterwards are rendered as static images in the
+// format StaticSVG or StaticPNG, without JavaScript, instead of as echarts. An empty format selects echarts.
+func (page *Page) SetStaticCharts(format string) *Page {
+	page.staticCharts = format
+	return page
+}
+
+// addStaticChart adds chart to the page as a static image. Charts other than bar, line, and pie charts
+// cannot be rendered statically, and are left out with a note in the page header.
+func (page *Page) addStaticChart(chart Chart) {
+	chart.Validate()
+	static, ok := newStaticChart(chart)
+	if !ok {
+		page.AddNotes(fmt.Sprintf("The chart %q is left out, as it cannot be drawn as a static image.", chartTitle(chart)))
+		return
+	}
+	if page.theme.Colors != nil {
+		static.Colors = page.theme.Colors
+	}
+	for i, s := range static.Series {
+		if color, ok := page.theme.SeriesColors[s.Name]; ok {
+			static.Series[i].Color = color
+		}
+	}
+
+	var element string
+	switch page.staticCharts {
+	case StaticPNG:
+		img, err := static.PNG()
+		if err != nil {
+			page.AddNotes(fmt.Sprintf("The chart %q is left out: %v", static.Title, err))
+			return
+		}
+		element = fmt.Sprintf(`<img src="data:image/png;base64,%s" width="%d" height="%d" alt="%s">`,
+			base64.StdEncoding.EncodeToString(img), staticchart.Width, staticchart.Height, html.EscapeString(static.Title))
+	default:
+		element = static.SVG()
+	}
+	page.sections = append(page.sections, section{
+		Element: template.HTML(`<div class="container"><div class="item">` + element + `</div></div>`),
+		Span:    page.layout.span(static.Title),
+		Title:   static.Title,
+		CSV:     chartCSV(chart),
+	})
+}
+
+// chartTitle returns the title of a go-echarts chart.
+func chartTitle(chart Chart) string {
+	switch c := chart.(type) {
+	case *charts.Bar:
+		return c.Title.Title
+	case *charts.Line:
+		return c.Title.Title
+	case *charts.Pie:
+		return c.Title.Title
+	case *charts.Map:
+		return c.Title.Title
+	case *charts.Geo:
+		return c.Title.Title
+	case *charts.TreeMap:
+		return c.Title.Title
+	case *charts.Sankey:
+		return c.Title.Title
+	}
+	return "untitled"
+}
+
+// newStaticChart converts a go-echarts bar, line, or pie chart to a static chart.
+func newStaticChart(chart Chart) (*staticchart.Chart, bool) {
+	var static staticchart.Chart
+	var series []charts.SingleSeries
+	var categories interface{}
+	switch c := chart.(type) {
+	case *charts.Bar:
+		static = staticchart.Chart{Title: c.Title.Title, Colors: c.Colors}
+		series, categories = c.MultiSeries, c.XAxisList[0].Data
+		if categories == nil && len(c.YAxisList) > 0 {
+			// Horizontal bar charts hold their categories on the Y axis. They are drawn vertically.
+			categories = c.YAxisList[0].Data
+		}
+	case *charts.Line:
+		static = staticchart.Chart{Title: c.Title.Title, Colors: c.Colors}
+		series, categories = c.MultiSeries, c.XAxisList[0].Data
+	case *charts.Pie:
+		static = staticchart.Chart{Title: c.Title.Title, Colors: c.Colors, Pie: true}
+		series = c.MultiSeries
+	default:
+		return nil, false
+	}
+
+	switch categories := categories.(type) {
+	case []string:
+		static.Categories = categories
+	case []interface{}:
+		for _, category := range categories {
+			static.Categories = append(static.Categories, fmt.Sprint(category))
+		}
+	}
+
+	for _, s := range series {
+		ss := staticchart.Series{Name: s.Name}
+		if s.ItemStyle != nil {
+			ss.Color = s.ItemStyle.Color
+		}
+		switch data := s.Data.(type) {
+		case []opts.BarData:
+			for _, d := range data {
+				ss.Values = append(ss.Values, toFloat(d.Value))
+			}
+			static.Stacked = static.Stacked || s.Stack != ""
+		case []opts.LineData:
+			ss.Line = true
+			for _, d := range data {
+				ss.Values = append(ss.Values, toFloat(d.Value))
+			}
+		case []opts.PieData:
+			for _, d := range data {
+				static.Categories = append(static.Categories, d.Name)
+				ss.Values = append(ss.Values, toFloat(d.Value))
+			}
+		default:
+			return nil, false
+		}
+		static.Series = append(static.Series, ss)
+	}
+	return &static, true
+}
+
+// toFloat converts a numeric chart value to a float64, returning 0 for other values.
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case string:
+		// Line charts hold values formatted to a fixed number of decimals.
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
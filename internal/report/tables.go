@@ -0,0 +1,588 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+)
+
+// HourlyTable returns a table of the average per day and the total hits, files, pages, and kilobytes by hour of day.
+func HourlyTable(aggr map[string]*logstats.HFPBVSData, averages map[string]*logstats.HourlyAverages) *Table {
+	table := &Table{
+		Title: "Hourly Statistics",
+		Headers: []string{"Hour", "Avg Hits", "Hits", "Avg Files", "Files", "Avg Pages", "Pages",
+			"Avg KBytes", "KBytes"},
+	}
+
+	keys := slices.Sorted(maps.Keys(aggr))
+	for _, key := range keys {
+		data := aggr[key]
+		avg := averages[key]
+		if avg == nil {
+			avg = &logstats.HourlyAverages{}
+		}
+		table.Rows = append(table.Rows, []string{
+			data.Category,
+			fmt.Sprintf("%.1f", avg.Hits),
+			strconv.FormatUint(data.Hits, 10),
+			fmt.Sprintf("%.1f", avg.Files),
+			strconv.FormatUint(data.Files, 10),
+			fmt.Sprintf("%.1f", avg.Pages),
+			strconv.FormatUint(data.Pages, 10),
+			fmt.Sprintf("%.1f", avg.Bytes/1024),
+			strconv.FormatUint(data.Bytes/1024, 10),
+		})
+	}
+
+	return table
+}
+
+// TopTable returns a table of the n keys with the highest counts and their share of the total.
+func TopTable(title string, keyHeader string, countHeader string, counts map[string]uint64, n int) *Table {
+	if n == 0 {
+		return nil
+	}
+	table := &Table{
+		Title:   title,
+		Headers: []string{keyHeader, countHeader, "%"},
+	}
+
+	total := uint64(0)
+	for _, count := range counts {
+		total += count
+	}
+
+	for _, key := range topKeys(counts, n) {
+		table.Rows = append(table.Rows, []string{
+			key,
+			strconv.FormatUint(counts[key], 10),
+			fmt.Sprintf("%.2f%%", float64(counts[key])*100/float64(total)),
+		})
+	}
+
+	return table
+}
+
+// HitsBytesVisitsTable returns a table of the n keys with the most hits, with their bytes and visits
+// and their share of the total hits.
+func HitsBytesVisitsTable(title string, keyHeader string, aggr map[string]*logstats.HitsBytesVisits, n int) *Table {
+	table := &Table{
+		Title:   title,
+		Headers: []string{keyHeader, "Hits", "%", "KBytes", "Visits"},
+	}
+
+	hits := make(map[string]uint64, len(aggr))
+	total := uint64(0)
+	for key, hbv := range aggr {
+		hits[key] = hbv.Hits
+		total += hbv.Hits
+	}
+
+	for _, key := range topKeys(hits, n) {
+		hbv := aggr[key]
+		table.Rows = append(table.Rows, []string{
+			key,
+			strconv.FormatUint(hbv.Hits, 10),
+			fmt.Sprintf("%.2f%%", float64(hbv.Hits)*100/float64(total)),
+			strconv.FormatUint(hbv.Bytes/1024, 10),
+			strconv.FormatUint(hbv.Visits, 10),
+		})
+	}
+
+	return table
+}
+
+// VHostTable returns a table of the hits, bytes, and visits of each virtual host, most hits first,
+// linking each host to its own report in the sub-directory named by dirs.
+func VHostTable(aggr map[string]*logstats.HitsBytesVisits, dirs map[string]string) *Table {
+	table := HitsBytesVisitsTable("Virtual Hosts", "Host", aggr, len(aggr))
+	for _, row := range table.Rows {
+		table.Links = append(table.Links, dirs[row[0]]+"/index.html")
+	}
+	return table
+}
+
+// URLBytesTable returns a table of the n URL paths that transferred the most bytes,
+// with their hits and average response size.
+func URLBytesTable(aggr map[string]*logstats.HitsBytes, n int) *Table {
+	table := &Table{
+		Title:   "Top URLs by KBytes",
+		Headers: []string{"URL", "KBytes", "%", "Hits", "Avg KBytes"},
+	}
+
+	bytes := make(map[string]uint64, len(aggr))
+	total := uint64(0)
+	for key, hb := range aggr {
+		bytes[key] = hb.Bytes
+		total += hb.Bytes
+	}
+
+	for _, key := range topKeys(bytes, n) {
+		hb := aggr[key]
+		avg := 0.0
+		if hb.Hits > 0 {
+			avg = float64(hb.Bytes) / float64(hb.Hits) / 1024
+		}
+		table.Rows = append(table.Rows, []string{
+			key,
+			strconv.FormatUint(hb.Bytes/1024, 10),
+			fmt.Sprintf("%.2f%%", float64(hb.Bytes)*100/float64(total)),
+			strconv.FormatUint(hb.Hits, 10),
+			fmt.Sprintf("%.1f", avg),
+		})
+	}
+
+	return table
+}
+
+// ListingTables returns tables listing all keys of aggr with their hits and bytes, most hits first,
+// split into tables of at most pageSize rows. It returns at least one table, which may be empty.
+func ListingTables(title string, keyHeader string, aggr map[string]*logstats.HitsBytes, pageSize int) []*Table {
+	hits := make(map[string]uint64, len(aggr))
+	totalHits, totalBytes := uint64(0), uint64(0)
+	for key, hb := range aggr {
+		hits[key] = hb.Hits
+		totalHits += hb.Hits
+		totalBytes += hb.Bytes
+	}
+	keys := topKeys(hits, len(hits))
+
+	var tables []*Table
+	for first := 0; first == 0 || first < len(keys); first += pageSize {
+		last := min(first+pageSize, len(keys))
+		table := &Table{
+			Title:   fmt.Sprintf("%s (%d-%d of %d)", title, min(first+1, last), last, len(keys)),
+			Headers: []string{keyHeader, "Hits", "%", "KBytes", "%"},
+		}
+		for _, key := range keys[first:last] {
+			hb := aggr[key]
+			table.Rows = append(table.Rows, []string{
+				key,
+				strconv.FormatUint(hb.Hits, 10),
+				fmt.Sprintf("%.2f%%", float64(hb.Hits)*100/float64(totalHits)),
+				strconv.FormatUint(hb.Bytes/1024, 10),
+				fmt.Sprintf("%.2f%%", float64(hb.Bytes)*100/float64(max(totalBytes, 1))),
+			})
+		}
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+// PartialContentTable returns a table of the n URL paths that transferred the most bytes in
+// 206 Partial Content responses, with the estimated number of full downloads they add up to.
+func PartialContentTable(aggr map[string]*logstats.PartialContent, n int) *Table {
+	table := &Table{
+		Title:   "Partial Content (206) by URL",
+		Headers: []string{"URL", "Responses", "KBytes", "Full Size KBytes", "Full Downloads (est.)"},
+	}
+
+	bytes := make(map[string]uint64, len(aggr))
+	for key, pc := range aggr {
+		bytes[key] = pc.Bytes
+	}
+
+	for _, key := range topKeys(bytes, n) {
+		pc := aggr[key]
+		fullSize, downloads := "n/a", "n/a"
+		if d, ok := pc.FullDownloads(); ok {
+			fullSize = strconv.FormatUint(pc.FullSize/1024, 10)
+			downloads = fmt.Sprintf("%.1f", d)
+		}
+		table.Rows = append(table.Rows, []string{
+			key,
+			strconv.FormatUint(pc.Hits, 10),
+			strconv.FormatUint(pc.Bytes/1024, 10),
+			fullSize,
+			downloads,
+		})
+	}
+
+	return table
+}
+
+// TransitionTable returns a table of the n most common page-to-page transitions within visits,
+// keyed by logstats.TransitionKey.
+func TransitionTable(aggr map[string]uint64, n int) *Table {
+	table := &Table{
+		Title:   "Top Paths Through Site",
+		Headers: []string{"From", "To", "Count", "%"},
+	}
+
+	total := uint64(0)
+	for _, count := range aggr {
+		total += count
+	}
+
+	for _, key := range topKeys(aggr, n) {
+		from, to := logstats.TransitionPages(key)
+		table.Rows = append(table.Rows, []string{
+			from,
+			to,
+			strconv.FormatUint(aggr[key], 10),
+			fmt.Sprintf("%.2f%%", float64(aggr[key])*100/float64(total)),
+		})
+	}
+
+	return table
+}
+
+// SummaryTable returns a table of the grand totals, titled with the period they cover, with the hits and
+// visits of humans.
+func SummaryTable(summary *logstats.Summary, humans *logstats.HumansRobots) *Table {
+	period := "none"
+	if summary.First != "" {
+		first, _ := time.Parse("2006-01-02", summary.First)
+		last, _ := time.Parse("2006-01-02", summary.Last)
+		period = first.Format("Jan 2 2006") + " - " + last.Format("Jan 2 2006")
+	}
+	return &Table{
+		Title: "Summary Period: " + period,
+		Headers: []string{"Total Hits", "Human Hits", "Total Files", "Total Pages", "Total KBytes", "Total Visits",
+			"Human Visits", "Total Unique Sites"},
+		Rows: [][]string{{
+			strconv.FormatUint(summary.Hits, 10),
+			strconv.FormatUint(humans.HumanHits, 10),
+			strconv.FormatUint(summary.Files, 10),
+			strconv.FormatUint(summary.Pages, 10),
+			strconv.FormatUint(summary.Bytes/1024, 10),
+			strconv.FormatUint(summary.Visits, 10),
+			strconv.FormatUint(humans.HumanVisits, 10),
+			strconv.FormatUint(summary.Sites, 10),
+		}},
+	}
+}
+
+// MonthlySummaryTable returns a table of the daily averages and totals per month, most recent month first,
+// like the yearly summary of classic Webalizer. Months are keyed by month string in the format "YYYY-MM",
+// and link to the page in links, if any.
+func MonthlySummaryTable(aggr map[string]*logstats.HFPBVSData, links map[string]string) *Table {
+	table := &Table{
+		Title: "Summary by Month",
+		Headers: []string{"Month", "Hits/Day", "Files/Day", "Pages/Day", "Visits/Day",
+			"Sites", "KBytes", "Visits", "Pages", "Files", "Hits"},
+	}
+
+	keys := slices.Sorted(maps.Keys(aggr))
+	slices.Reverse(keys)
+	for _, key := range keys {
+		data := aggr[key]
+		month, _ := time.Parse("2006-01", key)
+		days := uint64(month.AddDate(0, 1, -1).Day())
+		table.Rows = append(table.Rows, []string{
+			month.Format("Jan 2006"),
+			strconv.FormatUint(data.Hits/days, 10),
+			strconv.FormatUint(data.Files/days, 10),
+			strconv.FormatUint(data.Pages/days, 10),
+			strconv.FormatUint(data.Visits/days, 10),
+			strconv.FormatUint(data.Sites, 10),
+			strconv.FormatUint(data.Bytes/1024, 10),
+			strconv.FormatUint(data.Visits, 10),
+			strconv.FormatUint(data.Pages, 10),
+			strconv.FormatUint(data.Files, 10),
+			strconv.FormatUint(data.Hits, 10),
+		})
+		table.Links = append(table.Links, links[key])
+	}
+
+	return table
+}
+
+// ComparisonTable returns a table comparing the totals of two periods, with the change in percent.
+func ComparisonTable(title string, cmp *logstats.Comparison) *Table {
+	table := &Table{
+		Title:   title,
+		Headers: []string{"Metric", cmp.Current, cmp.Previous, "Change"},
+	}
+
+	cur, prev := cmp.CurrentTotals, cmp.PreviousTotals
+	metrics := []struct {
+		name              string
+		current, previous uint64
+	}{
+		{"Hits", cur.Hits, prev.Hits},
+		{"Files", cur.Files, prev.Files},
+		{"Pages", cur.Pages, prev.Pages},
+		{"KBytes", cur.Bytes / 1024, prev.Bytes / 1024},
+		{"Visits", cur.Visits, prev.Visits},
+		{"Sites", cur.Sites, prev.Sites},
+	}
+	for _, m := range metrics {
+		change := "n/a"
+		if pct, ok := logstats.PercentChange(m.current, m.previous); ok {
+			change = fmt.Sprintf("%+.1f%%", pct)
+		}
+		table.Rows = append(table.Rows, []string{
+			m.name,
+			strconv.FormatUint(m.current, 10),
+			strconv.FormatUint(m.previous, 10),
+			change,
+		})
+	}
+
+	return table
+}
+
+// HumansRobotsTable returns a table of the hits and visits of humans and robots, with their share of the total.
+func HumansRobotsTable(aggr *logstats.HumansRobots) *Table {
+	table := &Table{
+		Title:   "Humans and Robots",
+		Headers: []string{"Client", "Hits", "%", "Visits", "%"},
+	}
+
+	share := func(count, total uint64) string {
+		if total == 0 {
+			return "0.00%"
+		}
+		return fmt.Sprintf("%.2f%%", float64(count)*100/float64(total))
+	}
+	hits, visits := aggr.HumanHits+aggr.RobotHits, aggr.HumanVisits+aggr.RobotVisits
+	for _, row := range []struct {
+		name         string
+		hits, visits uint64
+	}{
+		{"Humans", aggr.HumanHits, aggr.HumanVisits},
+		{"Robots", aggr.RobotHits, aggr.RobotVisits},
+		{"Total", hits, visits},
+	} {
+		table.Rows = append(table.Rows, []string{
+			row.name,
+			strconv.FormatUint(row.hits, 10),
+			share(row.hits, hits),
+			strconv.FormatUint(row.visits, 10),
+			share(row.visits, visits),
+		})
+	}
+
+	return table
+}
+
+// DatacenterTable returns a table of the hits, bytes, and visits from the IP ranges of each cloud or datacenter
+// provider, most hits first, with their share of the total, and their sum.
+func DatacenterTable(aggr map[string]*logstats.HitsBytesVisits, total *logstats.HitsBytesVisits) *Table {
+	return rangeTable("Datacenter Traffic", "Provider", "All datacenters", aggr, total)
+}
+
+// FlaggedTable returns a table of the hits, bytes, and visits from the IP ranges of each blocklist, most hits
+// first, with their share of the total, and their sum.
+func FlaggedTable(aggr map[string]*logstats.HitsBytesVisits, total *logstats.HitsBytesVisits) *Table {
+	return rangeTable("Flagged Traffic", "Blocklist", "All blocklists", aggr, total)
+}
+
+// rangeTable returns a table of the hits, bytes, and visits from the IP ranges of each list, most hits first,
+// with their share of the total, and their sum in the row named sumName.
+func rangeTable(title string, keyHeader string, sumName string, aggr map[string]*logstats.HitsBytesVisits, total *logstats.HitsBytesVisits) *Table {
+	table := &Table{
+		Title:   title,
+		Headers: []string{keyHeader, "Hits", "%", "KBytes", "%", "Visits", "%"},
+	}
+
+	share := func(count, total uint64) string {
+		if total == 0 {
+			return "0.00%"
+		}
+		return fmt.Sprintf("%.2f%%", float64(count)*100/float64(total))
+	}
+	hits := make(map[string]uint64, len(aggr))
+	sum := &logstats.HitsBytesVisits{}
+	for key, hbv := range aggr {
+		hits[key] = hbv.Hits
+		sum.Hits += hbv.Hits
+		sum.Bytes += hbv.Bytes
+		sum.Visits += hbv.Visits
+	}
+	row := func(name string, hbv *logstats.HitsBytesVisits) []string {
+		return []string{
+			name,
+			strconv.FormatUint(hbv.Hits, 10),
+			share(hbv.Hits, total.Hits),
+			strconv.FormatUint(hbv.Bytes/1024, 10),
+			share(hbv.Bytes, total.Bytes),
+			strconv.FormatUint(hbv.Visits, 10),
+			share(hbv.Visits, total.Visits),
+		}
+	}
+	for _, key := range topKeys(hits, len(hits)) {
+		table.Rows = append(table.Rows, row(key, aggr[key]))
+	}
+	table.Rows = append(table.Rows, row(sumName, sum))
+
+	return table
+}
+
+// ChangeTable returns a table of the n URL paths, or other keys, whose count grew the most, or with
+// gains false, shrank the most, with their counts before and after and the change. Keys whose count
+// did not change in that direction are left out.
+func ChangeTable(title string, keyHeader string, changes map[string]logstats.Change, n int, gains bool) *Table {
+	table := &Table{
+		Title:   title,
+		Headers: []string{keyHeader, "Before", "After", "Change", "%"},
+	}
+
+	keys := make([]string, 0, len(changes))
+	for key, change := range changes {
+		if (gains && change.Delta() > 0) || (!gains && change.Delta() < 0) {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		da, db := changes[a].Delta(), changes[b].Delta()
+		if !gains {
+			da, db = -da, -db
+		}
+		return cmp.Or(cmp.Compare(db, da), strings.Compare(a, b))
+	})
+
+	for _, key := range keys[:min(len(keys), n)] {
+		change := changes[key]
+		pct := "new"
+		if p, ok := logstats.PercentChange(change.After, change.Before); ok {
+			pct = fmt.Sprintf("%+.1f%%", p)
+		}
+		table.Rows = append(table.Rows, []string{
+			key,
+			strconv.FormatUint(change.Before, 10),
+			strconv.FormatUint(change.After, 10),
+			fmt.Sprintf("%+d", change.Delta()),
+			pct,
+		})
+	}
+
+	return table
+}
+
+// NotFoundTable returns a table of the n URL paths with the most 404 Not Found hits,
+// listing the referrers that linked to each of them, most frequent first.
+func NotFoundTable(aggr map[string]map[string]uint64, n int) *Table {
+	table := &Table{
+		Title:   "Broken Links (404 Not Found)",
+		Headers: []string{"URL", "Hits", "Referrers"},
+	}
+
+	hits := make(map[string]uint64, len(aggr))
+	for urlPath, referrers := range aggr {
+		for _, count := range referrers {
+			hits[urlPath] += count
+		}
+	}
+
+	for _, urlPath := range topKeys(hits, n) {
+		referrers := aggr[urlPath]
+		refs := topKeys(referrers, 5)
+		for i, ref := range refs {
+			if ref == "-" {
+				ref = "(direct)"
+			}
+			refs[i] = fmt.Sprintf("%s (%d)", ref, referrers[refs[i]])
+		}
+		if len(referrers) > len(refs) {
+			refs = append(refs, fmt.Sprintf("and %d more", len(referrers)-len(refs)))
+		}
+		table.Rows = append(table.Rows, []string{
+			urlPath,
+			strconv.FormatUint(hits[urlPath], 10),
+			strings.Join(refs, ", "),
+		})
+	}
+
+	return table
+}
+
+// ErrorTable returns a table of the n URL paths with the most 4xx and 5xx responses,
+// with a column for each response code returned by any of them.
+func ErrorTable(aggr map[string]map[uint16]uint64, n int) *Table {
+	hits := make(map[string]uint64, len(aggr))
+	for urlPath, codes := range aggr {
+		for _, count := range codes {
+			hits[urlPath] += count
+		}
+	}
+	urlPaths := topKeys(hits, n)
+
+	codeSet := make(map[uint16]struct{})
+	for _, urlPath := range urlPaths {
+		for code := range aggr[urlPath] {
+			codeSet[code] = struct{}{}
+		}
+	}
+	codes := slices.Sorted(maps.Keys(codeSet))
+
+	table := &Table{
+		Title:   "Errors by URL",
+		Headers: []string{"URL", "Errors"},
+	}
+	for _, code := range codes {
+		table.Headers = append(table.Headers, strconv.Itoa(int(code)))
+	}
+
+	for _, urlPath := range urlPaths {
+		row := []string{urlPath, strconv.FormatUint(hits[urlPath], 10)}
+		for _, code := range codes {
+			row = append(row, strconv.FormatUint(aggr[urlPath][code], 10))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table
+}
+
+// topKeys returns the n keys with the highest counts, sorted by descending count, then by key for a stable order.
+func topKeys(counts map[string]uint64, n int) []string {
+	keys := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// DailyTable returns a table of the daily usage and visit metrics, including 304 Not Modified responses and
+// the hits and visits of humans.
+func DailyTable(aggr map[string]*logstats.HFPBVSData, visits map[string]*logstats.VisitMetrics, notModified map[string]uint64,
+	humans map[string]*logstats.HumansRobots) *Table {
+	table := &Table{
+		Title: "Daily Statistics",
+		Headers: []string{"Day", "Hits", "Human Hits", "Files", "Not Modified", "Pages", "KBytes", "Visits", "Human Visits",
+			"Sites", "Avg Visit", "Bounce Rate"},
+	}
+
+	keys := slices.Sorted(maps.Keys(aggr))
+	for _, key := range keys {
+		data := aggr[key]
+		vm := visits[key]
+		if vm == nil {
+			vm = &logstats.VisitMetrics{}
+		}
+		hr := humans[key]
+		if hr == nil {
+			hr = &logstats.HumansRobots{}
+		}
+		table.Rows = append(table.Rows, []string{
+			data.Category,
+			strconv.FormatUint(data.Hits, 10),
+			strconv.FormatUint(hr.HumanHits, 10),
+			strconv.FormatUint(data.Files, 10),
+			strconv.FormatUint(notModified[key], 10),
+			strconv.FormatUint(data.Pages, 10),
+			strconv.FormatUint(data.Bytes/1024, 10),
+			strconv.FormatUint(data.Visits, 10),
+			strconv.FormatUint(hr.HumanVisits, 10),
+			strconv.FormatUint(data.Sites, 10),
+			vm.AvgDuration().String(),
+			fmt.Sprintf("%.1f%%", vm.BounceRate()),
+		})
+	}
+
+	return table
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// Theme selects the colors of a report page and its charts.
+type Theme struct {
+	// Name identifies the theme. The page body has the CSS class "theme-" followed by the name.
+	Name string
+	// Dark selects light text and chart axes on a dark background.
+	Dark bool
+	// Colors is the palette of the chart series, replacing the colors chosen per chart, or nil to keep them.
+	Colors []string
+	// SeriesColors holds the colors of chart series by series name, such as "Hits", taking precedence over
+	// Colors and the colors chosen per chart.
+	SeriesColors map[string]string
+}
+
+// DefaultTheme is the light theme with the classic Webalizer chart colors.
+var DefaultTheme = Themes["light"]
+
+// Themes are the available themes by name.
+var Themes = map[string]Theme{
+	"light": {Name: "light"},
+	"dark":  {Name: "dark", Dark: true},
+	"pastel": {Name: "pastel", Colors: []string{
+		"#8dd3c7", "#bebada", "#fb8072", "#80b1d3", "#fdb462", "#b3de69", "#fccde5", "#bc80bd",
+	}},
+	// The Okabe-Ito palette remains distinguishable with the common forms of color blindness.
+	"colorblind": {Name: "colorblind", Colors: []string{
+		"#0072b2", "#e69f00", "#009e73", "#d55e00", "#56b4e9", "#cc79a7", "#f0e442", "#000000",
+	}},
+}
+
+// ThemeNames returns the names of the available themes, sorted.
+func ThemeNames() []string {
+	return slices.Sorted(maps.Keys(Themes))
+}
+
+// paletteVisitor replaces the series colors of a chart with a palette and the colors by series name
+// when the chart is rendered.
+type paletteVisitor struct {
+	charts.BaseConfigurationVisitor
+	colors       []string
+	seriesColors map[string]string
+}
+
+// Visit sets the palette in the chart options.
+func (v paletteVisitor) Visit(chart map[string]interface{}) {
+	if v.colors != nil {
+		chart["color"] = v.colors
+	}
+}
+
+// VisitSeriesOpt returns a copy of series with the item and line colors of the named series replaced.
+func (v paletteVisitor) VisitSeriesOpt(series charts.MultiSeries) interface{} {
+	if len(v.seriesColors) == 0 {
+		return series
+	}
+	colored := slices.Clone(series)
+	for i, s := range colored {
+		color, ok := v.seriesColors[s.Name]
+		if !ok {
+			continue
+		}
+		var itemStyle opts.ItemStyle
+		if s.ItemStyle != nil {
+			itemStyle = *s.ItemStyle
+		}
+		itemStyle.Color = color
+		colored[i].ItemStyle = &itemStyle
+		if s.LineStyle != nil {
+			lineStyle := *s.LineStyle
+			lineStyle.Color = color
+			colored[i].LineStyle = &lineStyle
+		}
+	}
+	return colored
+}
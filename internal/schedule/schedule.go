@@ -0,0 +1,173 @@
+// Package schedule parses cron-style schedules and computes the times they run at.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the times a job runs at.
+type Schedule interface {
+	// Next returns the first time the job runs after t.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a schedule: a cron expression of five fields, minute, hour, day of month, month, and day of
+// week, such as "0 * * * *" for every hour, or one of @hourly, @daily (or @midnight), @weekly, @monthly, and
+// @every DURATION, such as "@every 30m". The fields hold "*", numbers, ranges such as "1-5", lists such
+// as "0,30", and steps such as "*/15". Like in cron, if both the day of month and the day of week are
+// restricted, either one matching is enough. Times are in the local time zone.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q, expected a positive duration after @every", spec)
+		}
+		return every(interval), nil
+	}
+	if expr, ok := shorthands[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(fieldRanges) {
+		return nil, fmt.Errorf("invalid schedule %q, expected 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+	var c cron
+	sets := []*uint64{&c.minutes, &c.hours, &c.days, &c.months, &c.weekdays}
+	for i, field := range fields {
+		set, err := parseField(field, fieldRanges[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+		}
+		*sets[i] = set
+	}
+	// Sunday is both 0 and 7.
+	if c.weekdays&(1<<7) != 0 {
+		c.weekdays |= 1
+	}
+	c.anyDay = strings.HasPrefix(fields[2], "*")
+	c.anyWeekday = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// shorthands holds the cron expressions of the named schedules.
+var shorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// fieldRange is the range of the values of a field of a cron expression.
+type fieldRange struct {
+	name     string
+	min, max int
+}
+
+// fieldRanges are the ranges of the fields of a cron expression, in order.
+var fieldRanges = []fieldRange{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseField returns the set of the values of a field of a cron expression, as bits.
+func parseField(field string, r fieldRange) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q of the %s", stepText, r.name)
+			}
+		}
+
+		first, last := r.min, r.max
+		if expr != "*" {
+			from, to, isRange := strings.Cut(expr, "-")
+			var err error
+			if first, err = parseValue(from, r); err != nil {
+				return 0, err
+			}
+			last = first
+			if isRange {
+				if last, err = parseValue(to, r); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				last = r.max
+			}
+			if first > last {
+				return 0, fmt.Errorf("invalid range %q of the %s", expr, r.name)
+			}
+		}
+		for v := first; v <= last; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a value of a field of a cron expression.
+func parseValue(text string, r fieldRange) (int, error) {
+	v, err := strconv.Atoi(text)
+	if err != nil || v < r.min || v > r.max {
+		return 0, fmt.Errorf("invalid %s %q, expected %d to %d", r.name, text, r.min, r.max)
+	}
+	return v, nil
+}
+
+// cron is a schedule of a cron expression, holding the values of each field as bits.
+type cron struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday are set if the day of month or the day of week is not restricted.
+	anyDay, anyWeekday bool
+}
+
+// Next returns the first minute after t that matches the expression, or the zero time if none does
+// within five years, such as for February 30.
+func (c cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case c.months&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hours&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minutes&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day of month and day of week of the expression.
+func (c cron) dayMatches(t time.Time) bool {
+	day := c.days&(1<<t.Day()) != 0
+	weekday := c.weekdays&(1<<int(t.Weekday())) != 0
+	if c.anyDay || c.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// every is a schedule running at a fixed interval.
+type every time.Duration
+
+// Next returns t plus the interval.
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// Sunday, March 3 2024, 10:17:30 UTC
+	now := time.Date(2024, time.March, 3, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.March, 3, 10, 18, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, time.March, 3, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.March, 3, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.March, 3, 10, 30, 0, 0, time.UTC)},
+		{"0,45 * * * *", time.Date(2024, time.March, 3, 10, 45, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, time.March, 4, 2, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"@midnight", time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Either the day of month or the day of week matching is enough
+		{"0 0 15 * 3", time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		{"@every 30m", now.Add(30 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			sched, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			if got := sched.Next(now); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"x * * * *",
+		"@every",
+		"@every -1m",
+		"@yearly-ish",
+	}
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := Parse(spec); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", spec)
+			}
+		})
+	}
+}
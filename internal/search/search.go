@@ -0,0 +1,72 @@
+// Package search detects search engine referrers and extracts the search strings they carry.
+package search
+
+import (
+	"net/url"
+	"strings"
+)
+
+// engine describes how to recognize a search engine and where it keeps the search string.
+type engine struct {
+	// host is matched against the labels of the referrer host, such as "google" for www.google.co.uk.
+	host string
+	// params are the query parameters that may hold the search string, in order of preference.
+	params []string
+}
+
+// engines are the known search engines.
+var engines = []engine{
+	{"google", []string{"q", "as_q"}},
+	{"bing", []string{"q"}},
+	{"yahoo", []string{"p", "q"}},
+	{"duckduckgo", []string{"q"}},
+	{"yandex", []string{"text"}},
+	{"baidu", []string{"wd", "word"}},
+	{"ecosia", []string{"q"}},
+	{"qwant", []string{"q"}},
+	{"startpage", []string{"query", "q"}},
+	{"brave", []string{"q"}},
+	{"ask", []string{"q"}},
+	{"aol", []string{"q", "query"}},
+	{"naver", []string{"query"}},
+	{"seznam", []string{"q"}},
+	{"sogou", []string{"query"}},
+}
+
+// Parse returns the search string of a search engine referrer, lower-cased and with whitespace collapsed.
+// ok is false if the referrer is not a known search engine or carries no search string,
+// which is common as most search engines no longer pass it on.
+func Parse(referrer string) (query string, ok bool) {
+	if !strings.HasPrefix(referrer, "http") {
+		return "", false
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.RawQuery == "" {
+		return "", false
+	}
+
+	e := lookup(u.Hostname())
+	if e == nil {
+		return "", false
+	}
+	values := u.Query()
+	for _, param := range e.params {
+		if query = strings.Join(strings.Fields(strings.ToLower(values.Get(param))), " "); query != "" {
+			return query, true
+		}
+	}
+	return "", false
+}
+
+// lookup returns the search engine of a host, or nil if it is not a known search engine.
+func lookup(host string) *engine {
+	labels := strings.Split(strings.ToLower(host), ".")
+	for i := range engines {
+		for _, label := range labels {
+			if label == engines[i].host {
+				return &engines[i]
+			}
+		}
+	}
+	return nil
+}
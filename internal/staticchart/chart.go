@@ -0,0 +1,293 @@
+// Package staticchart renders simple bar, line, and pie charts as static SVG or PNG images, for
+// reports that are viewed without JavaScript, such as reports embedded in emails.
+package staticchart
+
+import (
+	"math"
+	"strconv"
+)
+
+// Chart size in pixels.
+const (
+	Width  = 900
+	Height = 360
+)
+
+// Chart is a bar and line chart over categories, or a pie chart.
+type Chart struct {
+	// Title is shown above the chart.
+	Title string
+	// Categories are the labels of the x axis, or the names of the slices of a pie chart.
+	Categories []string
+	// Series hold one value per category. A pie chart only shows the first series.
+	Series []Series
+	// Stacked stacks the bar series on top of each other instead of placing them side by side.
+	Stacked bool
+	// Pie selects a pie chart.
+	Pie bool
+	// Colors is the palette of series without a color, and of the slices of a pie chart.
+	Colors []string
+}
+
+// Series is a named series of values, drawn as bars or as a line.
+type Series struct {
+	// Name is shown in the legend.
+	Name string
+	// Color is the color of the series, or empty to pick the next color of the palette.
+	Color string
+	// Values hold one value per category.
+	Values []float64
+	// Line draws the series as a line instead of bars.
+	Line bool
+}
+
+// defaultColors is the palette used when a chart has none.
+var defaultColors = []string{"#5470c6", "#91cc75", "#fac858", "#ee6666", "#73c0de", "#3ba272", "#fc8452", "#9a60b4", "#ea7ccc"}
+
+// Layout of the plot area within the chart.
+const (
+	marginLeft   = 60
+	marginRight  = 20
+	marginTop    = 56
+	marginBottom = 36
+	// charWidth is the width of a label character in PNG images, and about that in SVG images,
+	// used to avoid overlapping labels.
+	charWidth = 8
+	// gridLines is the approximate number of horizontal grid lines above the x axis.
+	gridLines = 5
+	// gridColor is the color of the grid lines and axes.
+	gridColor = "#bbbbbb"
+)
+
+// shape is a primitive drawn by the SVG and PNG renderers.
+type shape interface{}
+
+// rect is a filled rectangle.
+type rect struct {
+	x, y, w, h float64
+	color      string
+}
+
+// line is a straight line of the given width.
+type line struct {
+	x1, y1, x2, y2 float64
+	width          float64
+	color          string
+}
+
+// polyline is a line through points, given as x and y pairs.
+type polyline struct {
+	points []float64
+	color  string
+}
+
+// wedge is a slice of a pie between two angles in radians, clockwise from 12 o'clock.
+type wedge struct {
+	cx, cy, r float64
+	from, to  float64
+	color     string
+}
+
+// text is a label in the foreground color, anchored at its start, middle, or end.
+type text struct {
+	x, y   float64
+	s      string
+	anchor string
+	bold   bool
+}
+
+// color returns the color of the i-th series or slice.
+func (c *Chart) color(i int, s Series) string {
+	if s.Color != "" {
+		return s.Color
+	}
+	colors := c.Colors
+	if len(colors) == 0 {
+		colors = defaultColors
+	}
+	return colors[i%len(colors)]
+}
+
+// shapes returns the shapes of the chart, in drawing order.
+func (c *Chart) shapes() []shape {
+	shapes := []shape{text{x: 10, y: 20, s: c.Title, anchor: "start", bold: true}}
+	if c.Pie {
+		return append(shapes, c.pieShapes()...)
+	}
+	return append(shapes, c.barLineShapes()...)
+}
+
+// legendShapes returns the legend of the series, right-aligned below the title.
+func (c *Chart) legendShapes() []shape {
+	var shapes []shape
+	x := float64(Width - marginRight)
+	for i := len(c.Series) - 1; i >= 0; i-- {
+		s := c.Series[i]
+		x -= float64(len(s.Name) * charWidth)
+		shapes = append(shapes,
+			text{x: x, y: 40, s: s.Name, anchor: "start"},
+			rect{x: x - 16, y: 31, w: 12, h: 10, color: c.color(i, s)},
+		)
+		x -= 32
+	}
+	return shapes
+}
+
+// barLineShapes returns the axes, grid, bars, and lines of a bar and line chart.
+func (c *Chart) barLineShapes() []shape {
+	shapes := c.legendShapes()
+	n := len(c.Categories)
+	if n == 0 {
+		return shapes
+	}
+
+	// Scale the y axis to the largest value, or the largest stack of bars.
+	maxValue := 0.0
+	for i := range n {
+		stack := 0.0
+		for _, s := range c.Series {
+			v := value(s, i)
+			if c.Stacked && !s.Line {
+				stack += v
+			}
+			maxValue = max(maxValue, v, stack)
+		}
+	}
+	step := niceStep(maxValue / gridLines)
+	lines := max(1, int(math.Ceil(maxValue/step)))
+	top := step * float64(lines)
+	plotW := float64(Width - marginLeft - marginRight)
+	plotH := float64(Height - marginTop - marginBottom)
+	bottom := float64(Height - marginBottom)
+	y := func(v float64) float64 { return bottom - v/top*plotH }
+
+	for i := 0; i <= lines; i++ {
+		v := step * float64(i)
+		shapes = append(shapes,
+			line{x1: marginLeft, y1: y(v), x2: Width - marginRight, y2: y(v), width: 1, color: gridColor},
+			text{x: marginLeft - 6, y: y(v) + 4, s: formatValue(v), anchor: "end"},
+		)
+	}
+
+	// Label every step-th category so labels do not overlap.
+	slot := plotW / float64(n)
+	longest := 1
+	for _, label := range c.Categories {
+		longest = max(longest, len(label))
+	}
+	every := max(1, int(math.Ceil(float64(longest*charWidth+8)/slot)))
+	for i, label := range c.Categories {
+		if i%every == 0 {
+			shapes = append(shapes, text{x: marginLeft + slot*(float64(i)+0.5), y: bottom + 16, s: label, anchor: "middle"})
+		}
+	}
+
+	var bars []int
+	for i, s := range c.Series {
+		if !s.Line {
+			bars = append(bars, i)
+		}
+	}
+	if len(bars) > 0 {
+		groups := len(bars)
+		if c.Stacked {
+			groups = 1
+		}
+		barW := slot * 0.8 / float64(groups)
+		for i := range n {
+			stack := 0.0
+			for j, k := range bars {
+				v := value(c.Series[k], i)
+				x := marginLeft + slot*float64(i) + slot*0.1
+				if c.Stacked {
+					shapes = append(shapes, rect{x: x, y: y(stack + v), w: barW, h: y(stack) - y(stack+v), color: c.color(k, c.Series[k])})
+					stack += v
+					continue
+				}
+				x += barW * float64(j)
+				shapes = append(shapes, rect{x: x, y: y(v), w: barW, h: bottom - y(v), color: c.color(k, c.Series[k])})
+			}
+		}
+	}
+
+	for k, s := range c.Series {
+		if !s.Line {
+			continue
+		}
+		points := make([]float64, 0, 2*n)
+		for i := range n {
+			points = append(points, marginLeft+slot*(float64(i)+0.5), y(value(s, i)))
+		}
+		shapes = append(shapes, polyline{points: points, color: c.color(k, s)})
+	}
+
+	return append(shapes, line{x1: marginLeft, y1: bottom, x2: Width - marginRight, y2: bottom, width: 1, color: gridColor})
+}
+
+// pieShapes returns the slices of a pie chart and a legend with their shares.
+func (c *Chart) pieShapes() []shape {
+	if len(c.Series) == 0 {
+		return nil
+	}
+	s := c.Series[0]
+	total := 0.0
+	for i := range c.Categories {
+		total += value(s, i)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var shapes []shape
+	cx, cy, r := float64(Width)/3, float64(Height+marginTop-marginBottom)/2, float64(Height-marginTop-marginBottom)/2
+	angle := 0.0
+	for i, name := range c.Categories {
+		share := value(s, i) / total
+		color := c.color(i, Series{})
+		shapes = append(shapes, wedge{cx: cx, cy: cy, r: r, from: angle, to: angle + share*2*math.Pi, color: color})
+		angle += share * 2 * math.Pi
+
+		if ly := float64(marginTop + 16*i); ly < Height-10 {
+			shapes = append(shapes,
+				rect{x: 2 * Width / 3, y: ly, w: 12, h: 10, color: color},
+				text{x: 2*Width/3 + 18, y: ly + 9, s: name + " " + strconv.FormatFloat(share*100, 'f', 1, 64) + "%", anchor: "start"},
+			)
+		}
+	}
+	return shapes
+}
+
+// value returns the i-th value of s, or 0 if it has none.
+func value(s Series, i int) float64 {
+	if i < len(s.Values) {
+		return s.Values[i]
+	}
+	return 0
+}
+
+// niceStep rounds v up to 1, 2, or 5 times a power of 10, so the grid lines fall on round numbers.
+func niceStep(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5} {
+		if v <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+// formatValue formats an axis value compactly, such as 1.5K or 20M.
+func formatValue(v float64) string {
+	for _, unit := range []struct {
+		size   float64
+		suffix string
+	}{{1e12, "T"}, {1e9, "G"}, {1e6, "M"}, {1e3, "K"}} {
+		if math.Abs(v) >= unit.size {
+			return strconv.FormatFloat(math.Round(v/unit.size*10)/10, 'f', -1, 64) + unit.suffix
+		}
+	}
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
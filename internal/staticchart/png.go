@@ -0,0 +1,120 @@
+package staticchart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+// fontScale is the factor the 3x5 pixel glyphs are scaled by.
+const fontScale = 2
+
+// PNG returns the chart as a PNG image on a white background.
+func (c *Chart) PNG() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	foreground := color.RGBA{0x33, 0x33, 0x33, 0xff}
+
+	for _, s := range c.shapes() {
+		switch s := s.(type) {
+		case rect:
+			r := image.Rect(int(math.Round(s.x)), int(math.Round(s.y)), int(math.Round(s.x+s.w)), int(math.Round(s.y+s.h)))
+			draw.Draw(img, r, image.NewUniform(parseColor(s.color)), image.Point{}, draw.Over)
+		case line:
+			drawLine(img, s.x1, s.y1, s.x2, s.y2, s.width, parseColor(s.color))
+		case polyline:
+			for i := 0; i+3 < len(s.points); i += 2 {
+				drawLine(img, s.points[i], s.points[i+1], s.points[i+2], s.points[i+3], 2, parseColor(s.color))
+			}
+		case wedge:
+			drawWedge(img, s)
+		case text:
+			drawText(img, s, foreground)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseColor parses a color in the form "#rrggbb", returning gray for other forms.
+func parseColor(s string) color.RGBA {
+	if len(s) == 7 && s[0] == '#' {
+		if v, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+		}
+	}
+	return color.RGBA{0x88, 0x88, 0x88, 0xff}
+}
+
+// drawLine draws a line of the given width by stamping squares along it.
+func drawLine(img *image.RGBA, x1, y1, x2, y2, width float64, c color.RGBA) {
+	steps := int(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))) + 1
+	half := int(width / 2)
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		x := int(math.Round(x1 + (x2-x1)*t))
+		y := int(math.Round(y1 + (y2-y1)*t))
+		draw.Draw(img, image.Rect(x-half, y-half, x-half+int(math.Max(width, 1)), y-half+int(math.Max(width, 1))), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+}
+
+// drawWedge fills the pixels of a pie slice.
+func drawWedge(img *image.RGBA, w wedge) {
+	c := parseColor(w.color)
+	for y := int(w.cy - w.r); y <= int(w.cy+w.r); y++ {
+		for x := int(w.cx - w.r); x <= int(w.cx+w.r); x++ {
+			dx, dy := float64(x)-w.cx, float64(y)-w.cy
+			if dx*dx+dy*dy > w.r*w.r {
+				continue
+			}
+			angle := math.Atan2(dx, -dy)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			if angle >= w.from && angle < w.to {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// drawText draws a label with the pixel font, with its baseline at y.
+func drawText(img *image.RGBA, t text, c color.RGBA) {
+	scale := fontScale
+	if t.bold {
+		scale++
+	}
+	runes := []rune(t.s)
+	width := len(runes) * 4 * scale
+	x := int(t.x)
+	switch t.anchor {
+	case "middle":
+		x -= width / 2
+	case "end":
+		x -= width
+	}
+	top := int(t.y) - 5*scale
+	for _, r := range runes {
+		glyph, ok := glyphs[unicode.ToUpper(r)]
+		if ok {
+			for row, bits := range glyph {
+				for col, bit := range bits {
+					if bit == '#' {
+						px, py := x+col*scale, top+row*scale
+						draw.Draw(img, image.Rect(px, py, px+scale, py+scale), image.NewUniform(c), image.Point{}, draw.Src)
+					}
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
@@ -0,0 +1,55 @@
+package staticchart
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// SVG returns the chart as an SVG element, with its labels in the current text color of the page.
+func (c *Chart) SVG() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11" fill="currentColor">`,
+		Width, Height, Width, Height)
+	fmt.Fprintf(&b, "<title>%s</title>", html.EscapeString(c.Title))
+	for _, s := range c.shapes() {
+		switch s := s.(type) {
+		case rect:
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, s.x, s.y, s.w, s.h, s.color)
+		case line:
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%.1f"/>`, s.x1, s.y1, s.x2, s.y2, s.color, s.width)
+		case polyline:
+			points := make([]string, 0, len(s.points)/2)
+			for i := 0; i+1 < len(s.points); i += 2 {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", s.points[i], s.points[i+1]))
+			}
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), s.color)
+		case wedge:
+			b.WriteString(svgWedge(s))
+		case text:
+			weight := ""
+			if s.bold {
+				weight = ` font-weight="bold" font-size="15"`
+			}
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="%s"%s>%s</text>`, s.x, s.y, s.anchor, weight, html.EscapeString(s.s))
+		}
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+// svgWedge returns a pie slice as an SVG path, or a circle for a full pie.
+func svgWedge(w wedge) string {
+	if w.to-w.from >= 2*math.Pi-1e-9 {
+		return fmt.Sprintf(`<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"/>`, w.cx, w.cy, w.r, w.color)
+	}
+	x1, y1 := w.cx+w.r*math.Sin(w.from), w.cy-w.r*math.Cos(w.from)
+	x2, y2 := w.cx+w.r*math.Sin(w.to), w.cy-w.r*math.Cos(w.to)
+	large := 0
+	if w.to-w.from > math.Pi {
+		large = 1
+	}
+	return fmt.Sprintf(`<path d="M%.1f,%.1f L%.1f,%.1f A%.1f,%.1f 0 %d,1 %.1f,%.1f Z" fill="%s"/>`,
+		w.cx, w.cy, x1, y1, w.r, w.r, large, x2, y2, w.color)
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/rbscholtus/go-webalizer/internal/parser"
+)
+
+// parquetBatch is the number of entries buffered before they are handed to the Parquet writer.
+const parquetBatch = 1024
+
+// parquetEntry is a log entry as a row of the Parquet file, enriched with the country, city, and network of the visitor,
+// the classification of the user agent, and whether the entry started a visit.
+type parquetEntry struct {
+	Timestamp      time.Time `parquet:"timestamp,timestamp(millisecond)"`
+	VHost          string    `parquet:"vhost,dict"`
+	IP             string    `parquet:"ip"`
+	User           string    `parquet:"user,dict"`
+	Method         string    `parquet:"method,dict"`
+	Path           string    `parquet:"path"`
+	Protocol       string    `parquet:"protocol,dict"`
+	Status         int32     `parquet:"status"`
+	Bytes          int64     `parquet:"bytes"`
+	Referrer       string    `parquet:"referrer"`
+	UserAgent      string    `parquet:"user_agent"`
+	Country        string    `parquet:"country,dict"`
+	CountryCode    string    `parquet:"country_code,dict"`
+	City           string    `parquet:"city,dict"`
+	ASN            string    `parquet:"asn,dict"`
+	Browser        string    `parquet:"browser,dict"`
+	BrowserVersion string    `parquet:"browser_version,dict"`
+	OS             string    `parquet:"os,dict"`
+	Device         string    `parquet:"device,dict"`
+	Page           bool      `parquet:"page"`
+	NewVisit       bool      `parquet:"new_visit"`
+}
+
+// Parquet is a parser.EntrySink that writes every log entry, enriched with what the parser.Enricher knows of its
+// IP address and with its user agent class, to a Parquet file for analysis with tools such as DuckDB or Spark.
+type Parquet struct {
+	// file is the Parquet file.
+	file *os.File
+	// w writes the rows of the file, compressed with ZSTD.
+	w *parquet.GenericWriter[parquetEntry]
+	// batch holds the entries not yet handed to w.
+	batch []parquetEntry
+}
+
+// CreateParquet creates the Parquet file, replacing it if it exists.
+func CreateParquet(fileName string) (*Parquet, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &Parquet{
+		file:  file,
+		w:     parquet.NewGenericWriter[parquetEntry](file, parquet.Compression(&parquet.Zstd)),
+		batch: make([]parquetEntry, 0, parquetBatch),
+	}, nil
+}
+
+// WriteEntry buffers the entry, handing the batch to the writer once it is full.
+func (p *Parquet) WriteEntry(entry *parser.Entry) error {
+	p.batch = append(p.batch, parquetEntry{
+		Timestamp:      entry.Timestamp,
+		VHost:          entry.VHost,
+		IP:             entry.IP,
+		User:           string(entry.User),
+		Method:         entry.Method,
+		Path:           entry.URLPath,
+		Protocol:       string(entry.Version),
+		Status:         int32(entry.RespCode),
+		Bytes:          int64(entry.Size),
+		Referrer:       entry.Referrer,
+		UserAgent:      entry.UserAgent,
+		Country:        entry.Geo.Country,
+		CountryCode:    entry.Geo.CountryCode,
+		City:           entry.Geo.City,
+		ASN:            entry.Geo.ASN,
+		Browser:        entry.Client.Browser,
+		BrowserVersion: entry.Client.Version,
+		OS:             entry.Client.OS,
+		Device:         entry.Client.Device,
+		Page:           entry.Page,
+		NewVisit:       entry.NewVisit,
+	})
+	if len(p.batch) == parquetBatch {
+		return p.writeBatch()
+	}
+	return nil
+}
+
+// Flush writes the buffered entries and the footer of the Parquet file, and closes it.
+func (p *Parquet) Flush() error {
+	if err := p.writeBatch(); err != nil {
+		p.file.Close()
+		return err
+	}
+	if err := p.w.Close(); err != nil {
+		p.file.Close()
+		return err
+	}
+	return p.file.Close()
+}
+
+// writeBatch hands the buffered entries to the writer.
+func (p *Parquet) writeBatch() error {
+	if _, err := p.w.Write(p.batch); err != nil {
+		return fmt.Errorf("writing %s: %v", p.file.Name(), err)
+	}
+	p.batch = p.batch[:0]
+	return nil
+}
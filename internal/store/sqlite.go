@@ -0,0 +1,1197 @@
+// Package store persists web server log statistics in a SQLite, PostgreSQL, or MySQL database, and
+// loads parsed log entries into ClickHouse or Parquet files.
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/parser"
+
+	// Register the pure-Go SQLite driver.
+	_ "modernc.org/sqlite"
+)
+
+// schema creates one table per dimension of logstats.LogStats.
+// Dates are strings in the format "YYYY-MM-DD", months in the format "YYYY-MM".
+const schema = `
+CREATE TABLE IF NOT EXISTS totals (
+	date TEXT PRIMARY KEY,
+	hits INTEGER NOT NULL, files INTEGER NOT NULL, pages INTEGER NOT NULL, bytes INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS not_modified (
+	date TEXT PRIMARY KEY, hits INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS schemes (
+	date TEXT NOT NULL, scheme TEXT NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, scheme)
+);
+CREATE TABLE IF NOT EXISTS hours (
+	date TEXT NOT NULL, hour INTEGER NOT NULL,
+	hits INTEGER NOT NULL, files INTEGER NOT NULL, pages INTEGER NOT NULL, bytes INTEGER NOT NULL,
+	PRIMARY KEY (date, hour)
+);
+CREATE TABLE IF NOT EXISTS visits (
+	date TEXT NOT NULL, ip TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, ip)
+);
+CREATE TABLE IF NOT EXISTS country_visits (
+	date TEXT NOT NULL, country TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, country)
+);
+CREATE TABLE IF NOT EXISTS sites (
+	date TEXT NOT NULL, ip TEXT NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, ip)
+);
+CREATE TABLE IF NOT EXISTS methods (
+	date TEXT NOT NULL, method TEXT NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, method)
+);
+CREATE TABLE IF NOT EXISTS resp_codes (
+	date TEXT NOT NULL, code INTEGER NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, code)
+);
+CREATE TABLE IF NOT EXISTS entry_pages (
+	date TEXT NOT NULL, url_path TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, url_path)
+);
+CREATE TABLE IF NOT EXISTS exit_pages (
+	date TEXT NOT NULL, url_path TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, url_path)
+);
+CREATE TABLE IF NOT EXISTS transitions (
+	date TEXT NOT NULL, transition TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, transition)
+);
+CREATE TABLE IF NOT EXISTS acquisitions (
+	date TEXT NOT NULL, acquisition TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, acquisition)
+);
+CREATE TABLE IF NOT EXISTS pages_per_visit (
+	date TEXT NOT NULL, bucket TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, bucket)
+);
+CREATE TABLE IF NOT EXISTS browsers (
+	date TEXT NOT NULL, browser TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, browser)
+);
+CREATE TABLE IF NOT EXISTS oses (
+	date TEXT NOT NULL, os TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, os)
+);
+CREATE TABLE IF NOT EXISTS devices (
+	date TEXT NOT NULL, device TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, device)
+);
+CREATE TABLE IF NOT EXISTS visit_metrics (
+	date TEXT PRIMARY KEY,
+	visits INTEGER NOT NULL, duration INTEGER NOT NULL, bounces INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ips (
+	date TEXT NOT NULL, ip TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, ip)
+);
+CREATE TABLE IF NOT EXISTS city_visits (
+	date TEXT NOT NULL, city TEXT NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, city)
+);
+CREATE TABLE IF NOT EXISTS country_traffic (
+	date TEXT NOT NULL, country TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, country)
+);
+CREATE TABLE IF NOT EXISTS country_codes (
+	country TEXT NOT NULL PRIMARY KEY, code TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS city_locations (
+	city TEXT NOT NULL PRIMARY KEY, lat REAL NOT NULL, lon REAL NOT NULL
+);
+CREATE TABLE IF NOT EXISTS asns (
+	date TEXT NOT NULL, asn TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, asn)
+);
+CREATE TABLE IF NOT EXISTS datacenters (
+	date TEXT NOT NULL, provider TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, provider)
+);
+CREATE TABLE IF NOT EXISTS flagged (
+	date TEXT NOT NULL, blocklist TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, blocklist)
+);
+CREATE TABLE IF NOT EXISTS user_agents (
+	date TEXT NOT NULL, user_agent TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL, visits INTEGER NOT NULL,
+	PRIMARY KEY (date, user_agent)
+);
+CREATE TABLE IF NOT EXISTS url_paths (
+	date TEXT NOT NULL, url_path TEXT NOT NULL, method TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL,
+	PRIMARY KEY (date, url_path, method)
+);
+CREATE TABLE IF NOT EXISTS partial_content (
+	date TEXT NOT NULL, url_path TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL,
+	PRIMARY KEY (date, url_path)
+);
+CREATE TABLE IF NOT EXISTS full_sizes (
+	url_path TEXT PRIMARY KEY, bytes INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS referrers (
+	date TEXT NOT NULL, referrer TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL,
+	PRIMARY KEY (date, referrer)
+);
+CREATE TABLE IF NOT EXISTS not_found (
+	date TEXT NOT NULL, url_path TEXT NOT NULL, referrer TEXT NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, url_path, referrer)
+);
+CREATE TABLE IF NOT EXISTS content_bytes (
+	date TEXT NOT NULL, category TEXT NOT NULL, bytes INTEGER NOT NULL,
+	PRIMARY KEY (date, category)
+);
+CREATE TABLE IF NOT EXISTS response_sizes (
+	date TEXT NOT NULL, bin INTEGER NOT NULL, responses INTEGER NOT NULL,
+	PRIMARY KEY (date, bin)
+);
+CREATE TABLE IF NOT EXISTS errors (
+	date TEXT NOT NULL, url_path TEXT NOT NULL, code INTEGER NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, url_path, code)
+);
+CREATE TABLE IF NOT EXISTS referrer_domains (
+	date TEXT NOT NULL, domain TEXT NOT NULL, hits INTEGER NOT NULL, bytes INTEGER NOT NULL,
+	PRIMARY KEY (date, domain)
+);
+CREATE TABLE IF NOT EXISTS search_strings (
+	date TEXT NOT NULL, search TEXT NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, search)
+);
+CREATE TABLE IF NOT EXISTS robots (
+	date TEXT NOT NULL, robot TEXT NOT NULL, hits INTEGER NOT NULL,
+	PRIMARY KEY (date, robot)
+);
+CREATE TABLE IF NOT EXISTS robot_visits (
+	date TEXT PRIMARY KEY, visits INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS visitors (
+	ip TEXT PRIMARY KEY, first_visit INTEGER NOT NULL, last_visit INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS log_positions (
+	fingerprint TEXT PRIMARY KEY, file TEXT NOT NULL, position INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS history (
+	month TEXT PRIMARY KEY, category TEXT NOT NULL,
+	hits INTEGER NOT NULL, files INTEGER NOT NULL, pages INTEGER NOT NULL, bytes INTEGER NOT NULL,
+	visits INTEGER NOT NULL, sites INTEGER NOT NULL
+);
+`
+
+// SQLite is a statistics store backed by a SQLite database file.
+type SQLite struct {
+	// db is the underlying database handle.
+	db *sql.DB
+	// tx is the transaction begun by Begin, which all writes and queries go through until it ends.
+	tx *sql.Tx
+}
+
+// OpenSQLite opens or creates the SQLite database at path and ensures the schema exists.
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; serialize access through one connection.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema: %v", err)
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+// Close closes the underlying database, rolling back the transaction begun by Begin, if any.
+func (s *SQLite) Close() error {
+	s.Rollback()
+	return s.db.Close()
+}
+
+// Begin begins a transaction that all writes and queries go through until Commit or Rollback, so the
+// statistics of a run and the positions its logs were read up to are stored together or not at all.
+func (s *SQLite) Begin() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	s.tx = tx
+	return nil
+}
+
+// Commit commits the transaction begun by Begin.
+func (s *SQLite) Commit() error {
+	tx := s.tx
+	s.tx = nil
+	return tx.Commit()
+}
+
+// Rollback rolls back the transaction begun by Begin, if any.
+func (s *SQLite) Rollback() {
+	if s.tx != nil {
+		s.tx.Rollback()
+		s.tx = nil
+	}
+}
+
+// WriteDays adds the per-day statistics for the given dates to the database.
+// Counters are added to existing rows, so a day may be written more than once, such as when a log continues a
+// day that an earlier run stored. Logs are not counted twice if their positions are stored with WritePositions,
+// and read from there by the next run.
+func (s *SQLite) WriteDays(stats *logstats.LogStats, dates []string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, date := range dates {
+			if err := writeDay(tx, stats, date); err != nil {
+				return fmt.Errorf("writing %s: %v", date, err)
+			}
+		}
+		return nil
+	})
+}
+
+// writeDay adds the statistics of a single day.
+func writeDay(tx *sql.Tx, stats *logstats.LogStats, date string) error {
+	if _, err := tx.Exec(`INSERT INTO totals (date, hits, files, pages, bytes) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (date) DO UPDATE SET hits = hits + excluded.hits, files = files + excluded.files,
+		pages = pages + excluded.pages, bytes = bytes + excluded.bytes`,
+		date, stats.Hits[date], stats.Files[date], stats.Pages[date], stats.Bytes[date]); err != nil {
+		return err
+	}
+
+	if hits := stats.NotModified[date]; hits > 0 {
+		if _, err := tx.Exec(`INSERT INTO not_modified (date, hits) VALUES (?, ?)
+			ON CONFLICT (date) DO UPDATE SET hits = hits + excluded.hits`, date, hits); err != nil {
+			return err
+		}
+	}
+
+	if visits := stats.RobotVisits[date]; visits > 0 {
+		if _, err := tx.Exec(`INSERT INTO robot_visits (date, visits) VALUES (?, ?)
+			ON CONFLICT (date) DO UPDATE SET visits = visits + excluded.visits`, date, visits); err != nil {
+			return err
+		}
+	}
+
+	if hours := stats.Hours[date]; hours != nil {
+		for hour, hfpb := range hours {
+			if hfpb.Hits == 0 {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO hours (date, hour, hits, files, pages, bytes) VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (date, hour) DO UPDATE SET hits = hits + excluded.hits, files = files + excluded.files,
+				pages = pages + excluded.pages, bytes = bytes + excluded.bytes`,
+				date, hour, hfpb.Hits, hfpb.Files, hfpb.Pages, hfpb.Bytes); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range countTables(stats) {
+		if err := t.write(tx, date); err != nil {
+			return err
+		}
+	}
+
+	if vm := stats.VisitMetrics[date]; vm != nil {
+		if _, err := tx.Exec(`INSERT INTO visit_metrics (date, visits, duration, bounces) VALUES (?, ?, ?, ?)
+			ON CONFLICT (date) DO UPDATE SET visits = visits + excluded.visits, duration = duration + excluded.duration,
+			bounces = bounces + excluded.bounces`,
+			date, vm.Visits, vm.Duration, vm.Bounces); err != nil {
+			return err
+		}
+	}
+
+	for ip, hbv := range stats.IPs[date] {
+		if _, err := tx.Exec(`INSERT INTO ips (date, ip, hits, bytes, visits) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (date, ip) DO UPDATE SET hits = hits + excluded.hits, bytes = bytes + excluded.bytes,
+			visits = visits + excluded.visits`,
+			date, ip, hbv.Hits, hbv.Bytes, hbv.Visits); err != nil {
+			return err
+		}
+	}
+	for userAgent, hbv := range stats.UserAgents[date] {
+		if _, err := tx.Exec(`INSERT INTO user_agents (date, user_agent, hits, bytes, visits) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (date, user_agent) DO UPDATE SET hits = hits + excluded.hits, bytes = bytes + excluded.bytes,
+			visits = visits + excluded.visits`,
+			date, userAgent, hbv.Hits, hbv.Bytes, hbv.Visits); err != nil {
+			return err
+		}
+	}
+	for urlPath, methods := range stats.URLPaths[date] {
+		for method, hb := range methods {
+			if _, err := tx.Exec(`INSERT INTO url_paths (date, url_path, method, hits, bytes) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (date, url_path, method) DO UPDATE SET hits = hits + excluded.hits, bytes = bytes + excluded.bytes`,
+				date, urlPath, method, hb.Hits, hb.Bytes); err != nil {
+				return err
+			}
+		}
+	}
+	for referrer, hb := range stats.Referrers[date] {
+		if _, err := tx.Exec(`INSERT INTO referrers (date, referrer, hits, bytes) VALUES (?, ?, ?, ?)
+			ON CONFLICT (date, referrer) DO UPDATE SET hits = hits + excluded.hits, bytes = bytes + excluded.bytes`,
+			date, referrer, hb.Hits, hb.Bytes); err != nil {
+			return err
+		}
+	}
+	for urlPath, referrers := range stats.NotFound[date] {
+		for referrer, hits := range referrers {
+			if _, err := tx.Exec(`INSERT INTO not_found (date, url_path, referrer, hits) VALUES (?, ?, ?, ?)
+				ON CONFLICT (date, url_path, referrer) DO UPDATE SET hits = hits + excluded.hits`,
+				date, urlPath, referrer, hits); err != nil {
+				return err
+			}
+		}
+	}
+	for urlPath, codes := range stats.Errors[date] {
+		for code, hits := range codes {
+			if _, err := tx.Exec(`INSERT INTO errors (date, url_path, code, hits) VALUES (?, ?, ?, ?)
+				ON CONFLICT (date, url_path, code) DO UPDATE SET hits = hits + excluded.hits`,
+				date, urlPath, code, hits); err != nil {
+				return err
+			}
+		}
+	}
+	for urlPath, hb := range stats.Partial[date] {
+		if _, err := tx.Exec(`INSERT INTO partial_content (date, url_path, hits, bytes) VALUES (?, ?, ?, ?)
+			ON CONFLICT (date, url_path) DO UPDATE SET hits = hits + excluded.hits, bytes = bytes + excluded.bytes`,
+			date, urlPath, hb.Hits, hb.Bytes); err != nil {
+			return err
+		}
+	}
+	for domain, hb := range stats.ReferrerDomains[date] {
+		if _, err := tx.Exec(`INSERT INTO referrer_domains (date, domain, hits, bytes) VALUES (?, ?, ?, ?)
+			ON CONFLICT (date, domain) DO UPDATE SET hits = hits + excluded.hits, bytes = bytes + excluded.bytes`,
+			date, domain, hb.Hits, hb.Bytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// countTable maps a per-day counter map of LogStats onto a table with columns (date, key, value).
+type countTable struct {
+	// write adds the counters of one day to the table.
+	write func(tx *sql.Tx, date string) error
+	// load reads the rows of the days from first on into the counter map.
+	load func(s *SQLite, first string) error
+}
+
+// newCountTable returns a countTable for the counter map m.
+func newCountTable[K comparable](table, keyCol, valueCol string, m map[string]map[K]uint64) countTable {
+	insert := fmt.Sprintf(`INSERT INTO %[1]s (date, %[2]s, %[3]s) VALUES (?, ?, ?)
+		ON CONFLICT (date, %[2]s) DO UPDATE SET %[3]s = %[3]s + excluded.%[3]s`, table, keyCol, valueCol)
+	query := fmt.Sprintf(`SELECT date, %s, %s FROM %s WHERE date >= ?`, keyCol, valueCol, table)
+
+	return countTable{
+		write: func(tx *sql.Tx, date string) error {
+			for key, count := range m[date] {
+				if _, err := tx.Exec(insert, date, key, count); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		load: func(s *SQLite, first string) error {
+			return s.query(query, func(rows *sql.Rows) error {
+				var date string
+				var key K
+				var count uint64
+				if err := rows.Scan(&date, &key, &count); err != nil {
+					return err
+				}
+				if m[date] == nil {
+					m[date] = make(map[K]uint64)
+				}
+				m[date][key] = count
+				return nil
+			}, first)
+		},
+	}
+}
+
+// countTables returns the tables of all per-day counter maps of stats.
+func countTables(stats *logstats.LogStats) []countTable {
+	return []countTable{
+		newCountTable("schemes", "scheme", "hits", stats.Schemes),
+		newCountTable("visits", "ip", "visits", stats.Visits),
+		newCountTable("country_visits", "country", "visits", stats.CtrVisits),
+		newCountTable("city_visits", "city", "visits", stats.CityVisits),
+		newCountTable("sites", "ip", "hits", stats.Sites),
+		newCountTable("methods", "method", "hits", stats.Methods),
+		newCountTable("resp_codes", "code", "hits", stats.RespCodes),
+		newCountTable("entry_pages", "url_path", "visits", stats.EntryPages),
+		newCountTable("exit_pages", "url_path", "visits", stats.ExitPages),
+		newCountTable("transitions", "transition", "visits", stats.Transitions),
+		newCountTable("acquisitions", "acquisition", "visits", stats.Acquisitions),
+		newCountTable("pages_per_visit", "bucket", "visits", stats.PagesPerVisit),
+		newCountTable("browsers", "browser", "visits", stats.Browsers),
+		newCountTable("oses", "os", "visits", stats.OSes),
+		newCountTable("devices", "device", "visits", stats.Devices),
+		newCountTable("search_strings", "search", "hits", stats.SearchStrings),
+		newCountTable("robots", "robot", "hits", stats.Robots),
+		newCountTable("content_bytes", "category", "bytes", stats.ContentBytes),
+		newCountTable("response_sizes", "bin", "responses", stats.Sizes),
+	}
+}
+
+// WriteVisitors stores the first and last visit of every visitor, keeping the earliest and latest timestamps.
+func (s *SQLite) WriteVisitors(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for ip, first := range stats.FirstVisit {
+			last := stats.LastVisit[ip]
+			if _, err := tx.Exec(`INSERT INTO visitors (ip, first_visit, last_visit) VALUES (?, ?, ?)
+				ON CONFLICT (ip) DO UPDATE SET first_visit = min(first_visit, excluded.first_visit),
+				last_visit = max(last_visit, excluded.last_visit)`,
+				ip, first.Unix(), last.Unix()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteFullSizes stores the complete response size of every URL path, keeping the largest size.
+func (s *SQLite) WriteFullSizes(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for urlPath, size := range stats.FullSizes {
+			if _, err := tx.Exec(`INSERT INTO full_sizes (url_path, bytes) VALUES (?, ?)
+				ON CONFLICT (url_path) DO UPDATE SET bytes = max(bytes, excluded.bytes)`,
+				urlPath, size); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteHistory stores the imported monthly totals, replacing earlier imports of the same months.
+func (s *SQLite) WriteHistory(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for month, data := range stats.History {
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO history
+				(month, category, hits, files, pages, bytes, visits, sites) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				month, data.Category, data.Hits, data.Files, data.Pages, data.Bytes, data.Visits, data.Sites); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteCountries replaces the visits and traffic per country of the days held in stats, and adds the ISO codes
+// of the countries.
+// Country statistics are derived from the visits and ips tables, so they are recomputed rather than added.
+func (s *SQLite) WriteCountries(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if err := deleteDays(tx, "country_visits", stats); err != nil {
+			return err
+		}
+		for date, countries := range stats.CtrVisits {
+			for country, visits := range countries {
+				if _, err := tx.Exec(`INSERT INTO country_visits (date, country, visits) VALUES (?, ?, ?)`,
+					date, country, visits); err != nil {
+					return err
+				}
+			}
+		}
+		if err := deleteDays(tx, "country_traffic", stats); err != nil {
+			return err
+		}
+		for date, countries := range stats.CtrTraffic {
+			for country, hbv := range countries {
+				if _, err := tx.Exec(`INSERT INTO country_traffic (date, country, hits, bytes, visits) VALUES (?, ?, ?, ?, ?)`,
+					date, country, hbv.Hits, hbv.Bytes, hbv.Visits); err != nil {
+					return err
+				}
+			}
+		}
+		for country, code := range stats.CountryCodes {
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO country_codes (country, code) VALUES (?, ?)`,
+				country, code); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteCities replaces the visits per city of the days held in stats, and adds the locations of the cities.
+// City visits are derived from the visits table, so they are recomputed rather than added.
+func (s *SQLite) WriteCities(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if err := deleteDays(tx, "city_visits", stats); err != nil {
+			return err
+		}
+		for date, cities := range stats.CityVisits {
+			for city, visits := range cities {
+				if _, err := tx.Exec(`INSERT INTO city_visits (date, city, visits) VALUES (?, ?, ?)`,
+					date, city, visits); err != nil {
+					return err
+				}
+			}
+		}
+		for city, location := range stats.CityLocations {
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO city_locations (city, lat, lon) VALUES (?, ?, ?)`,
+				city, location.Lat, location.Lon); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteASNs replaces the autonomous system statistics of the days held in stats.
+// Autonomous system statistics are derived from the ips table, so they are recomputed rather than added.
+func (s *SQLite) WriteASNs(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if err := deleteDays(tx, "asns", stats); err != nil {
+			return err
+		}
+		for date, systems := range stats.ASNs {
+			for system, hbv := range systems {
+				if _, err := tx.Exec(`INSERT INTO asns (date, asn, hits, bytes, visits) VALUES (?, ?, ?, ?, ?)`,
+					date, system, hbv.Hits, hbv.Bytes, hbv.Visits); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// WriteDatacenters replaces the statistics of cloud and datacenter providers of the days held in stats.
+// Datacenter statistics are derived from the ips table, so they are recomputed rather than added.
+func (s *SQLite) WriteDatacenters(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if err := deleteDays(tx, "datacenters", stats); err != nil {
+			return err
+		}
+		for date, providers := range stats.Datacenters {
+			for provider, hbv := range providers {
+				if _, err := tx.Exec(`INSERT INTO datacenters (date, provider, hits, bytes, visits) VALUES (?, ?, ?, ?, ?)`,
+					date, provider, hbv.Hits, hbv.Bytes, hbv.Visits); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// WriteFlagged replaces the statistics of the blocklists of the days held in stats.
+// Flagged statistics are derived from the ips table, so they are recomputed rather than added.
+func (s *SQLite) WriteFlagged(stats *logstats.LogStats) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if err := deleteDays(tx, "flagged", stats); err != nil {
+			return err
+		}
+		for date, lists := range stats.Flagged {
+			for list, hbv := range lists {
+				if _, err := tx.Exec(`INSERT INTO flagged (date, blocklist, hits, bytes, visits) VALUES (?, ?, ?, ?, ?)`,
+					date, list, hbv.Hits, hbv.Bytes, hbv.Visits); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Load reads all statistics from the database into a new LogStats instance.
+func (s *SQLite) Load() (*logstats.LogStats, error) {
+	stats := logstats.NewLogStats()
+	if err := s.loadDays(stats, ""); err != nil {
+		return nil, err
+	}
+	if err := s.loadLocations(stats); err != nil {
+		return nil, err
+	}
+
+	err := s.query(`SELECT url_path, bytes FROM full_sizes`, func(rows *sql.Rows) error {
+		var urlPath string
+		var size uint64
+		if err := rows.Scan(&urlPath, &size); err != nil {
+			return err
+		}
+		stats.FullSizes[urlPath] = size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.query(`SELECT ip, first_visit, last_visit FROM visitors`, func(rows *sql.Rows) error {
+		var ip string
+		var first, last int64
+		if err := rows.Scan(&ip, &first, &last); err != nil {
+			return err
+		}
+		stats.FirstVisit[ip] = time.Unix(first, 0)
+		stats.LastVisit[ip] = time.Unix(last, 0)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.query(`SELECT month, category, hits, files, pages, bytes, visits, sites FROM history`, func(rows *sql.Rows) error {
+		var month string
+		data := &logstats.HFPBVSData{}
+		if err := rows.Scan(&month, &data.Category, &data.Hits, &data.Files, &data.Pages, &data.Bytes,
+			&data.Visits, &data.Sites); err != nil {
+			return err
+		}
+		stats.History[month] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// LoadRecent reads the statistics of the last months from the database, up to the month of the last day, into
+// a new LogStats instance, for a report that only holds the days of the last months in memory rather than all
+// of them. The database sums the totals of the months before into the imported history, which also holds the
+// months imported with WriteHistory that are not in the database otherwise. First and last visits are read for
+// the visitors of the last months only, and complete response sizes for the URL paths with partial responses
+// in the last months only.
+func (s *SQLite) LoadRecent(months int) (*logstats.LogStats, error) {
+	stats := logstats.NewLogStats()
+
+	var lastDate sql.NullString
+	if err := s.queryRow(`SELECT max(date) FROM totals`).Scan(&lastDate); err != nil {
+		return nil, err
+	}
+	first := ""
+	if last, err := time.Parse("2006-01-02", lastDate.String); err == nil {
+		first = time.Date(last.Year(), last.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	}
+	if err := s.loadDays(stats, first); err != nil {
+		return nil, err
+	}
+	if err := s.loadLocations(stats); err != nil {
+		return nil, err
+	}
+
+	err := s.query(`SELECT url_path, bytes FROM full_sizes
+		WHERE url_path IN (SELECT url_path FROM partial_content WHERE date >= ?)`, func(rows *sql.Rows) error {
+		var urlPath string
+		var size uint64
+		if err := rows.Scan(&urlPath, &size); err != nil {
+			return err
+		}
+		stats.FullSizes[urlPath] = size
+		return nil
+	}, first)
+	if err != nil {
+		return nil, err
+	}
+
+	firstTime, _ := time.Parse("2006-01-02", first)
+	err = s.query(`SELECT ip, first_visit, last_visit FROM visitors WHERE last_visit >= ?`, func(rows *sql.Rows) error {
+		var ip string
+		var first, last int64
+		if err := rows.Scan(&ip, &first, &last); err != nil {
+			return err
+		}
+		stats.FirstVisit[ip] = time.Unix(first, 0)
+		stats.LastVisit[ip] = time.Unix(last, 0)
+		return nil
+	}, firstTime.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.query(`SELECT month, category, hits, files, pages, bytes, visits, sites FROM history`, func(rows *sql.Rows) error {
+		var month string
+		data := &logstats.HFPBVSData{}
+		if err := rows.Scan(&month, &data.Category, &data.Hits, &data.Files, &data.Pages, &data.Bytes,
+			&data.Visits, &data.Sites); err != nil {
+			return err
+		}
+		stats.History[month] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadMonths(stats, first); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// loadMonths sums the statistics of the months before first by month, into the imported history of stats.
+// The sites of a month are summed per day, as in LogStats.AggregatesByMonth.
+func (s *SQLite) loadMonths(stats *logstats.LogStats, first string) error {
+	months := make(map[string]*logstats.HFPBVSData)
+	month := func(key string) *logstats.HFPBVSData {
+		data, ok := months[key]
+		if !ok {
+			t, _ := time.Parse("2006-01", key)
+			data = &logstats.HFPBVSData{Category: t.Format("Jan")}
+			months[key] = data
+		}
+		return data
+	}
+
+	err := s.query(`SELECT substr(date, 1, 7), sum(hits), sum(files), sum(pages), sum(bytes) FROM totals
+		WHERE date < ? GROUP BY 1`, func(rows *sql.Rows) error {
+		var key string
+		var hits, files, pages, bytes uint64
+		if err := rows.Scan(&key, &hits, &files, &pages, &bytes); err != nil {
+			return err
+		}
+		data := month(key)
+		data.Hits, data.Files, data.Pages, data.Bytes = hits, files, pages, bytes
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT substr(date, 1, 7), sum(visits) FROM visits WHERE date < ? GROUP BY 1`, func(rows *sql.Rows) error {
+		var key string
+		var visits uint64
+		if err := rows.Scan(&key, &visits); err != nil {
+			return err
+		}
+		month(key).Visits = visits
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT substr(date, 1, 7), count(*) FROM sites WHERE date < ? GROUP BY 1`, func(rows *sql.Rows) error {
+		var key string
+		var sites uint64
+		if err := rows.Scan(&key, &sites); err != nil {
+			return err
+		}
+		month(key).Sites = sites
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	stats.ImportHistory(months)
+	return nil
+}
+
+// loadDays reads the per-day statistics of the days from first on into stats.
+func (s *SQLite) loadDays(stats *logstats.LogStats, first string) error {
+	err := s.query(`SELECT date, hits, files, pages, bytes FROM totals WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date string
+		var hits, files, pages, bytes uint64
+		if err := rows.Scan(&date, &hits, &files, &pages, &bytes); err != nil {
+			return err
+		}
+		stats.Hits[date] = hits
+		stats.Files[date] = files
+		stats.Pages[date] = pages
+		stats.Bytes[date] = bytes
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, hits FROM not_modified WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date string
+		var hits uint64
+		if err := rows.Scan(&date, &hits); err != nil {
+			return err
+		}
+		stats.NotModified[date] = hits
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, visits FROM robot_visits WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date string
+		var visits uint64
+		if err := rows.Scan(&date, &visits); err != nil {
+			return err
+		}
+		stats.RobotVisits[date] = visits
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, hour, hits, files, pages, bytes FROM hours WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date string
+		var hour int
+		var hfpb logstats.HFPB
+		if err := rows.Scan(&date, &hour, &hfpb.Hits, &hfpb.Files, &hfpb.Pages, &hfpb.Bytes); err != nil {
+			return err
+		}
+		if hour < 0 || hour > 23 {
+			return fmt.Errorf("invalid hour %d for %s", hour, date)
+		}
+		if stats.Hours[date] == nil {
+			stats.Hours[date] = &[24]logstats.HFPB{}
+		}
+		stats.Hours[date][hour] = hfpb
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range countTables(stats) {
+		if err := t.load(s, first); err != nil {
+			return err
+		}
+	}
+
+	err = s.query(`SELECT date, visits, duration, bounces FROM visit_metrics WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date string
+		vm := &logstats.VisitMetrics{}
+		if err := rows.Scan(&date, &vm.Visits, &vm.Duration, &vm.Bounces); err != nil {
+			return err
+		}
+		stats.VisitMetrics[date] = vm
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, ip, hits, bytes, visits FROM ips WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, ip string
+		hbv := &logstats.HitsBytesVisits{}
+		if err := rows.Scan(&date, &ip, &hbv.Hits, &hbv.Bytes, &hbv.Visits); err != nil {
+			return err
+		}
+		if stats.IPs[date] == nil {
+			stats.IPs[date] = make(map[string]*logstats.HitsBytesVisits)
+		}
+		stats.IPs[date][ip] = hbv
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, country, hits, bytes, visits FROM country_traffic WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, country string
+		hbv := &logstats.HitsBytesVisits{}
+		if err := rows.Scan(&date, &country, &hbv.Hits, &hbv.Bytes, &hbv.Visits); err != nil {
+			return err
+		}
+		if stats.CtrTraffic[date] == nil {
+			stats.CtrTraffic[date] = make(map[string]*logstats.HitsBytesVisits)
+		}
+		stats.CtrTraffic[date][country] = hbv
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, asn, hits, bytes, visits FROM asns WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, system string
+		hbv := &logstats.HitsBytesVisits{}
+		if err := rows.Scan(&date, &system, &hbv.Hits, &hbv.Bytes, &hbv.Visits); err != nil {
+			return err
+		}
+		if stats.ASNs[date] == nil {
+			stats.ASNs[date] = make(map[string]*logstats.HitsBytesVisits)
+		}
+		stats.ASNs[date][system] = hbv
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, provider, hits, bytes, visits FROM datacenters WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, provider string
+		hbv := &logstats.HitsBytesVisits{}
+		if err := rows.Scan(&date, &provider, &hbv.Hits, &hbv.Bytes, &hbv.Visits); err != nil {
+			return err
+		}
+		if stats.Datacenters[date] == nil {
+			stats.Datacenters[date] = make(map[string]*logstats.HitsBytesVisits)
+		}
+		stats.Datacenters[date][provider] = hbv
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, blocklist, hits, bytes, visits FROM flagged WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, list string
+		hbv := &logstats.HitsBytesVisits{}
+		if err := rows.Scan(&date, &list, &hbv.Hits, &hbv.Bytes, &hbv.Visits); err != nil {
+			return err
+		}
+		if stats.Flagged[date] == nil {
+			stats.Flagged[date] = make(map[string]*logstats.HitsBytesVisits)
+		}
+		stats.Flagged[date][list] = hbv
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, user_agent, hits, bytes, visits FROM user_agents WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, userAgent string
+		hbv := &logstats.HitsBytesVisits{}
+		if err := rows.Scan(&date, &userAgent, &hbv.Hits, &hbv.Bytes, &hbv.Visits); err != nil {
+			return err
+		}
+		if stats.UserAgents[date] == nil {
+			stats.UserAgents[date] = make(map[string]*logstats.HitsBytesVisits)
+		}
+		stats.UserAgents[date][userAgent] = hbv
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, url_path, method, hits, bytes FROM url_paths WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, urlPath, method string
+		hb := &logstats.HitsBytes{}
+		if err := rows.Scan(&date, &urlPath, &method, &hb.Hits, &hb.Bytes); err != nil {
+			return err
+		}
+		if stats.URLPaths[date] == nil {
+			stats.URLPaths[date] = make(map[string]map[string]*logstats.HitsBytes)
+		}
+		if stats.URLPaths[date][urlPath] == nil {
+			stats.URLPaths[date][urlPath] = make(map[string]*logstats.HitsBytes)
+		}
+		stats.URLPaths[date][urlPath][method] = hb
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, referrer, hits, bytes FROM referrers WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, referrer string
+		hb := &logstats.HitsBytes{}
+		if err := rows.Scan(&date, &referrer, &hb.Hits, &hb.Bytes); err != nil {
+			return err
+		}
+		if stats.Referrers[date] == nil {
+			stats.Referrers[date] = make(map[string]*logstats.HitsBytes)
+		}
+		stats.Referrers[date][referrer] = hb
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, url_path, referrer, hits FROM not_found WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, urlPath, referrer string
+		var hits uint64
+		if err := rows.Scan(&date, &urlPath, &referrer, &hits); err != nil {
+			return err
+		}
+		if stats.NotFound[date] == nil {
+			stats.NotFound[date] = make(map[string]map[string]uint64)
+		}
+		if stats.NotFound[date][urlPath] == nil {
+			stats.NotFound[date][urlPath] = make(map[string]uint64)
+		}
+		stats.NotFound[date][urlPath][referrer] = hits
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, url_path, code, hits FROM errors WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, urlPath string
+		var code uint16
+		var hits uint64
+		if err := rows.Scan(&date, &urlPath, &code, &hits); err != nil {
+			return err
+		}
+		if stats.Errors[date] == nil {
+			stats.Errors[date] = make(map[string]map[uint16]uint64)
+		}
+		if stats.Errors[date][urlPath] == nil {
+			stats.Errors[date][urlPath] = make(map[uint16]uint64)
+		}
+		stats.Errors[date][urlPath][code] = hits
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, domain, hits, bytes FROM referrer_domains WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, domain string
+		hb := &logstats.HitsBytes{}
+		if err := rows.Scan(&date, &domain, &hb.Hits, &hb.Bytes); err != nil {
+			return err
+		}
+		if stats.ReferrerDomains[date] == nil {
+			stats.ReferrerDomains[date] = make(map[string]*logstats.HitsBytes)
+		}
+		stats.ReferrerDomains[date][domain] = hb
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT date, url_path, hits, bytes FROM partial_content WHERE date >= ?`, func(rows *sql.Rows) error {
+		var date, urlPath string
+		hb := &logstats.HitsBytes{}
+		if err := rows.Scan(&date, &urlPath, &hb.Hits, &hb.Bytes); err != nil {
+			return err
+		}
+		if stats.Partial[date] == nil {
+			stats.Partial[date] = make(map[string]*logstats.HitsBytes)
+		}
+		stats.Partial[date][urlPath] = hb
+		return nil
+	}, first)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loadLocations reads the locations of the cities and the ISO codes of the countries into stats.
+func (s *SQLite) loadLocations(stats *logstats.LogStats) error {
+	err := s.query(`SELECT city, lat, lon FROM city_locations`, func(rows *sql.Rows) error {
+		var city string
+		location := &logstats.GeoPoint{}
+		if err := rows.Scan(&city, &location.Lat, &location.Lon); err != nil {
+			return err
+		}
+		stats.CityLocations[city] = location
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.query(`SELECT country, code FROM country_codes`, func(rows *sql.Rows) error {
+		var country, code string
+		if err := rows.Scan(&country, &code); err != nil {
+			return err
+		}
+		stats.CountryCodes[country] = code
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WritePositions stores where the logs were read up to, replacing the positions of the same logs.
+func (s *SQLite) WritePositions(positions parser.Positions) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for fingerprint, pos := range positions {
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO log_positions (fingerprint, file, position) VALUES (?, ?, ?)`,
+				fingerprint, pos.File, pos.Offset); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Positions returns where the logs were read up to by the runs that stored them with WritePositions.
+func (s *SQLite) Positions() (parser.Positions, error) {
+	positions := make(parser.Positions)
+	err := s.query(`SELECT fingerprint, file, position FROM log_positions`, func(rows *sql.Rows) error {
+		var fingerprint string
+		var pos parser.Position
+		if err := rows.Scan(&fingerprint, &pos.File, &pos.Offset); err != nil {
+			return err
+		}
+		positions[fingerprint] = pos
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// deleteDays deletes the rows of the days held in stats from a table derived from the statistics of those days.
+func deleteDays(tx *sql.Tx, table string, stats *logstats.LogStats) error {
+	for _, date := range stats.Dates() {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE date = ?`, date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing on success and rolling back on error. Within the transaction
+// begun by Begin, fn runs in that transaction instead.
+func (s *SQLite) inTx(fn func(tx *sql.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// query runs a query with the arguments and calls scan for every row.
+func (s *SQLite) query(query string, scan func(rows *sql.Rows) error, args ...any) error {
+	var rows *sql.Rows
+	var err error
+	if s.tx != nil {
+		rows, err = s.tx.Query(query, args...)
+	} else {
+		rows, err = s.db.Query(query, args...)
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// queryRow runs a query that returns a single row.
+func (s *SQLite) queryRow(query string, args ...any) *sql.Row {
+	if s.tx != nil {
+		return s.tx.QueryRow(query, args...)
+	}
+	return s.db.QueryRow(query, args...)
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rbscholtus/go-webalizer/internal/logstats"
+	"github.com/rbscholtus/go-webalizer/internal/parser"
+)
+
+// openTemp opens a new SQLite database in a temporary directory, closed when the test ends.
+func openTemp(t *testing.T) *SQLite {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// addHits adds hits of 100 bytes each, in a single visit, by each of the sites on date to stats.
+func addHits(stats *logstats.LogStats, date string, hits uint64, sites ...string) {
+	for _, site := range sites {
+		stats.Hits[date] += hits
+		stats.Files[date] += hits
+		stats.Pages[date] += hits
+		stats.Bytes[date] += 100 * hits
+		if stats.Sites[date] == nil {
+			stats.Sites[date] = make(map[string]uint64)
+			stats.Visits[date] = make(map[string]uint64)
+		}
+		stats.Sites[date][site] += hits
+		stats.Visits[date][site]++
+		for i := uint64(0); i < hits; i++ {
+			stats.UpdateIPStats(date, site, 100, i == 0)
+		}
+	}
+}
+
+func TestWriteDays(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes int
+		want   uint64
+	}{
+		{"once", 1, 3},
+		{"twice", 2, 6},
+		{"three times", 3, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTemp(t)
+			stats := logstats.NewLogStats()
+			addHits(stats, "2024-03-01", 3, "192.0.2.1")
+			for range tt.writes {
+				if err := db.WriteDays(stats, stats.Dates()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			loaded, err := db.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := loaded.Hits["2024-03-01"]; got != tt.want {
+				t.Errorf("Hits = %d, want %d", got, tt.want)
+			}
+			if got := loaded.Bytes["2024-03-01"]; got != 100*tt.want {
+				t.Errorf("Bytes = %d, want %d", got, 100*tt.want)
+			}
+			if got := loaded.Sites["2024-03-01"]["192.0.2.1"]; got != tt.want {
+				t.Errorf("Sites = %d, want %d", got, tt.want)
+			}
+			if got := loaded.IPs["2024-03-01"]["192.0.2.1"]; got == nil || got.Hits != tt.want || got.Visits != uint64(tt.writes) {
+				t.Errorf("IPs = %+v, want %d hits in %d visits", got, tt.want, tt.writes)
+			}
+		})
+	}
+}
+
+func TestPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []parser.Positions
+		want   parser.Positions
+	}{
+		{"none", nil, parser.Positions{}},
+		{
+			"single write",
+			[]parser.Positions{{"a": {File: "access.log", Offset: 120}, "b": {File: "access.log.1", Offset: 4096}}},
+			parser.Positions{"a": {File: "access.log", Offset: 120}, "b": {File: "access.log.1", Offset: 4096}},
+		},
+		{
+			"replaced by a later write",
+			[]parser.Positions{
+				{"a": {File: "access.log", Offset: 120}, "b": {File: "access.log.1", Offset: 4096}},
+				{"a": {File: "access.log.1", Offset: 360}},
+			},
+			parser.Positions{"a": {File: "access.log.1", Offset: 360}, "b": {File: "access.log.1", Offset: 4096}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTemp(t)
+			for _, positions := range tt.writes {
+				if err := db.WritePositions(positions); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := db.Positions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("Positions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRecent(t *testing.T) {
+	db := openTemp(t)
+	stats := logstats.NewLogStats()
+	addHits(stats, "2024-01-10", 2, "192.0.2.1", "192.0.2.2")
+	addHits(stats, "2024-01-11", 1, "192.0.2.1")
+	addHits(stats, "2024-02-05", 4, "192.0.2.3")
+	addHits(stats, "2024-03-01", 5, "192.0.2.1")
+	if err := db.WriteDays(stats, stats.Dates()); err != nil {
+		t.Fatal(err)
+	}
+
+	january := logstats.HFPBVSData{Category: "Jan", Hits: 5, Files: 5, Pages: 5, Bytes: 500, Visits: 3, Sites: 3}
+	february := logstats.HFPBVSData{Category: "Feb", Hits: 4, Files: 4, Pages: 4, Bytes: 400, Visits: 1, Sites: 1}
+	tests := []struct {
+		name        string
+		months      int
+		wantDates   []string
+		wantHistory map[string]logstats.HFPBVSData
+	}{
+		{"last month", 1, []string{"2024-03-01"}, map[string]logstats.HFPBVSData{"2024-01": january, "2024-02": february}},
+		{"last two months", 2, []string{"2024-02-05", "2024-03-01"}, map[string]logstats.HFPBVSData{"2024-01": january}},
+		{"all months", 3, []string{"2024-01-10", "2024-01-11", "2024-02-05", "2024-03-01"}, map[string]logstats.HFPBVSData{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recent, err := db.LoadRecent(tt.months)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Sorted(slices.Values(recent.Dates())); !slices.Equal(got, tt.wantDates) {
+				t.Errorf("Dates() = %v, want %v", got, tt.wantDates)
+			}
+			history := make(map[string]logstats.HFPBVSData)
+			for month, data := range recent.History {
+				history[month] = *data
+			}
+			if !maps.Equal(history, tt.wantHistory) {
+				t.Errorf("History = %+v, want %+v", history, tt.wantHistory)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package topk implements the Space-Saving algorithm for tracking the most frequent keys in bounded memory.
+package topk
+
+import "container/heap"
+
+// entry is a tracked key and its estimated count.
+type entry struct {
+	// key is the tracked key.
+	key string
+	// count is the estimated number of occurrences, which may overestimate the true count.
+	count uint64
+	// index is the position of the entry in the heap.
+	index int
+}
+
+// minHeap orders entries by ascending count.
+type minHeap []*entry
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h minHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *minHeap) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *minHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// SpaceSaving tracks at most capacity keys. Once full, a new key replaces the least frequent one
+// and inherits its count, so every key with a true count above total/capacity is guaranteed to be tracked.
+type SpaceSaving struct {
+	// capacity is the maximum number of tracked keys.
+	capacity int
+	// entries maps tracked keys to their heap entries.
+	entries map[string]*entry
+	// heap holds the tracked entries with the least frequent one at the root.
+	heap minHeap
+}
+
+// New returns a new SpaceSaving summary that tracks at most capacity keys.
+func New(capacity int) *SpaceSaving {
+	return &SpaceSaving{
+		capacity: capacity,
+		entries:  make(map[string]*entry, capacity),
+		heap:     make(minHeap, 0, capacity),
+	}
+}
+
+// Offer counts one occurrence of key.
+// If key was not tracked and the summary is full, the least frequent key is evicted:
+// Offer then returns the evicted key, the count the new key inherited from it, and true.
+func (s *SpaceSaving) Offer(key string) (evicted string, inherited uint64, ok bool) {
+	if e, found := s.entries[key]; found {
+		e.count++
+		heap.Fix(&s.heap, e.index)
+		return "", 0, false
+	}
+
+	if len(s.heap) < s.capacity {
+		e := &entry{key: key, count: 1}
+		heap.Push(&s.heap, e)
+		s.entries[key] = e
+		return "", 0, false
+	}
+
+	// Replace the least frequent key, reusing its entry.
+	e := s.heap[0]
+	evicted, inherited = e.key, e.count
+	delete(s.entries, e.key)
+	e.key = key
+	e.count = inherited + 1
+	s.entries[key] = e
+	heap.Fix(&s.heap, 0)
+
+	return evicted, inherited, true
+}
+
+// Count returns the estimated count of key and whether it is tracked.
+func (s *SpaceSaving) Count(key string) (uint64, bool) {
+	if e, found := s.entries[key]; found {
+		return e.count, true
+	}
+	return 0, false
+}
+
+// Len returns the number of tracked keys.
+func (s *SpaceSaving) Len() int {
+	return len(s.heap)
+}
@@ -0,0 +1,81 @@
+package topk
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSpaceSaving(t *testing.T) {
+	// stream returns the keys hits times each of heavy, interleaved with one hit of each of n other keys.
+	stream := func(heavy []string, hits int, n int) []string {
+		var keys []string
+		for i := range max(hits, n) {
+			if i < hits {
+				keys = append(keys, heavy...)
+			}
+			if i < n {
+				keys = append(keys, fmt.Sprintf("/page/%d", i))
+			}
+		}
+		return keys
+	}
+
+	// Every key with a true count above len(keys)/capacity is a heavy hitter that must stay tracked.
+	tests := []struct {
+		name     string
+		capacity int
+		keys     []string
+		heavy    []string
+	}{
+		{"under capacity", 10, stream([]string{"/"}, 5, 5), []string{"/"}},
+		{"at capacity", 3, []string{"a", "b", "c", "a", "b", "c"}, []string{"a", "b", "c"}},
+		{"one heavy hitter", 5, stream([]string{"/"}, 300, 1000), []string{"/"}},
+		{"several heavy hitters", 10, stream([]string{"/", "/about", "/blog"}, 200, 1000), []string{"/", "/about", "/blog"}},
+		{"capacity of one", 1, []string{"a", "b", "a", "a", "c", "a", "a"}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.capacity)
+			counts := make(map[string]uint64)
+			for _, key := range tt.keys {
+				counts[key]++
+				wasTracked, full := s.entries[key] != nil, s.Len() == tt.capacity
+				lowest := uint64(0)
+				if len(s.heap) > 0 {
+					lowest = s.heap[0].count
+				}
+				evicted, inherited, ok := s.Offer(key)
+				if s.Len() > tt.capacity {
+					t.Fatalf("Len() = %d after %q, exceeding the capacity of %d", s.Len(), key, tt.capacity)
+				}
+				if ok != (full && !wasTracked) {
+					t.Fatalf("Offer(%q) = %q, %v, want an eviction only for a new key in a full summary", key, evicted, ok)
+				}
+				if ok {
+					if _, tracked := s.Count(evicted); tracked {
+						t.Fatalf("Offer(%q) evicted %q, which is still tracked", key, evicted)
+					}
+					if inherited != lowest {
+						t.Fatalf("Offer(%q) inherited %d, want the lowest count %d", key, inherited, lowest)
+					}
+				}
+			}
+
+			var total uint64
+			for _, e := range s.heap {
+				total += e.count
+				if e.count < counts[e.key] {
+					t.Errorf("Count(%q) = %d, below its true count %d", e.key, e.count, counts[e.key])
+				}
+			}
+			if total != uint64(len(tt.keys)) {
+				t.Errorf("counts add up to %d, want the %d keys offered", total, len(tt.keys))
+			}
+			for _, key := range tt.heavy {
+				if count, ok := s.Count(key); !ok || count < counts[key] {
+					t.Errorf("Count(%q) = %d, %v, want at least %d", key, count, ok, counts[key])
+				}
+			}
+		})
+	}
+}
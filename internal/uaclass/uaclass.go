@@ -0,0 +1,105 @@
+// Package uaclass classifies user agent strings into browser, operating system, and device type.
+package uaclass
+
+import (
+	"strings"
+
+	"github.com/mssola/useragent"
+)
+
+// Device types returned by Classify.
+const (
+	Desktop = "Desktop"
+	Mobile  = "Mobile"
+	Tablet  = "Tablet"
+	Bot     = "Bot"
+	Other   = "Other"
+)
+
+// Unknown is used for a browser or operating system that could not be determined.
+const Unknown = "Unknown"
+
+// Client is the classification of a user agent.
+type Client struct {
+	// Browser is the browser family, such as "Chrome" or "Firefox".
+	Browser string
+	// Version is the major version of the browser, or empty if unknown.
+	Version string
+	// OS is the operating system name, such as "Windows" or "Android".
+	OS string
+	// Device is the device type: Desktop, Mobile, Tablet, Bot, or Other for non-browser clients.
+	Device string
+}
+
+// osNames maps operating system names reported by the parser to their common names.
+var osNames = map[string]string{
+	"iPhone OS": "iOS",
+	"OS":        "iOS", // iPads report "CPU OS"
+	"Mac OS X":  "macOS",
+}
+
+// Classifier classifies user agent strings, caching the result per user agent
+// as logs usually hold few distinct user agents. A Classifier is not safe for concurrent use.
+type Classifier struct {
+	// clients is a map of classifications, keyed by user agent.
+	clients map[string]Client
+}
+
+// NewClassifier returns a new Classifier.
+func NewClassifier() *Classifier {
+	return &Classifier{clients: make(map[string]Client)}
+}
+
+// Classify returns the classification of a user agent string.
+func (c *Classifier) Classify(userAgent string) Client {
+	if client, ok := c.clients[userAgent]; ok {
+		return client
+	}
+	client := Classify(userAgent)
+	c.clients[userAgent] = client
+	return client
+}
+
+// Classify returns the classification of a user agent string without caching.
+func Classify(userAgent string) Client {
+	ua := useragent.New(userAgent)
+
+	client := Client{Browser: Unknown, OS: Unknown}
+	if name, version := ua.Browser(); name != "" && name != "-" {
+		client.Browser = name
+		client.Version, _, _ = strings.Cut(version, ".")
+	}
+	if os := ua.OSInfo().Name; os != "" {
+		if name, ok := osNames[os]; ok {
+			os = name
+		}
+		client.OS = os
+	}
+
+	switch {
+	case ua.Bot():
+		client.Device = Bot
+	case client.OS == Unknown:
+		client.Device = Other
+	case isTablet(userAgent):
+		client.Device = Tablet
+	case ua.Mobile():
+		client.Device = Mobile
+	default:
+		client.Device = Desktop
+	}
+
+	return client
+}
+
+// isTablet reports whether a user agent string belongs to a tablet.
+// Android tablets omit the "Mobile" token that Android phones include.
+func isTablet(userAgent string) bool {
+	switch {
+	case strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "Tablet"):
+		return true
+	case strings.Contains(userAgent, "Android"):
+		return !strings.Contains(userAgent, "Mobile")
+	}
+	return false
+}